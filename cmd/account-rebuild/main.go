@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"txsystem/internal/account/models"
+	"txsystem/internal/account/service"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/logging"
+)
+
+const batchSize = 500
+
+var (
+	dryRun    = flag.Bool("dry-run", false, "report differences without changing anything")
+	accountID = flag.Uint("account", 0, "rebuild only this account")
+)
+
+var logger = logging.For("main")
+
+// run replays the event store of every account, or just -account, and
+// compares it with the accounts table and the snapshots. Differences are
+// printed and, unless -dry-run is set, fixed.
+func run() error {
+	var cfg config.AccountRebuild
+	config.MustLoad(&cfg)
+	if err := logging.Setup("account-rebuild", cfg.Logging); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := database.Open(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+	accounts := service.NewAccountService(db, nil)
+
+	checked, inconsistent := 0, 0
+	var last uint
+	for {
+		var ids []uint
+		query := db.WithContext(ctx).Model(&models.Account{}).Where("id > ?", last).Order("id").Limit(batchSize)
+		if *accountID != 0 {
+			query = query.Where("id = ?", *accountID)
+		}
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, id := range ids {
+			result, err := accounts.Rebuild(ctx, id, !*dryRun)
+			if err != nil {
+				return fmt.Errorf("failed to rebuild account %d: %w", id, err)
+			}
+			checked++
+			if !result.Consistent() {
+				inconsistent++
+				report(result)
+			}
+		}
+		if len(ids) < batchSize {
+			break
+		}
+		last = ids[len(ids)-1]
+	}
+
+	logger.Info("accounts rebuilt", "checked", checked, "inconsistent", inconsistent, "dry_run", *dryRun)
+	if *dryRun && inconsistent > 0 {
+		return fmt.Errorf("%d of %d accounts differ from their events", inconsistent, checked)
+	}
+	return nil
+}
+
+func report(r *service.RebuildResult) {
+	p, e := r.Projection, r.Replayed
+	fmt.Printf("account %d: projection balance=%v held=%v version=%d, events balance=%v held=%v version=%d",
+		r.AccountID, p.Balance, p.HeldBalance, p.Version, e.Balance, e.HeldBalance, e.Version)
+	if len(r.BadSnapshots) > 0 {
+		fmt.Printf(", bad snapshots %v", r.BadSnapshots)
+	}
+	fmt.Println()
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,134 +1,134 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"strings"
-	"time"
+	accountv1 "txsystem/api/account/v1"
 	"txsystem/internal/account/handler"
-	"txsystem/internal/account/models"
+	accountrpc "txsystem/internal/account/rpc"
+	"txsystem/internal/account/rules"
+	"txsystem/internal/account/service"
+	"txsystem/internal/transaction/fees"
+	txrepository "txsystem/internal/transaction/repository"
+	txservice "txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/rpc"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	echoSwagger "github.com/swaggo/echo-swagger"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// LoadEnv loads env vars; already done in init()
-func init() {
-	if err := godotenv.Load(); err != nil {
-		panic("Error loading .env file")
-	}
-}
-
-func setupDatabase() (*gorm.DB, error) {
-	port := os.Getenv("POSTGRES_PORT")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
-	host := os.Getenv("POSTGRES_HOST")
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	maxRetries := 10
-	retryDelay := 3 * time.Second
-
-	var db *gorm.DB
-	var err error
-
-	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		if err == nil {
-			// Test the connection
-			sqlDB, pingErr := db.DB()
-			if pingErr == nil {
-				pingErr = sqlDB.Ping()
-			}
-			if pingErr == nil {
-				log.Info("Connected to database")
-				break
-			} else {
-				err = pingErr
-			}
-		}
-
-		log.Errorf("Failed to connect to DB (attempt %d/%d): %v", i+1, maxRetries, err)
-		time.Sleep(retryDelay)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("could not connect to database after %d attempts: %w", maxRetries, err)
-	}
+var logger = logging.For("main")
 
-	log.Info("Migrating database...")
-	if err := db.AutoMigrate(&models.Account{}); err != nil {
-		return nil, err
-	}
-
-	return db, nil
-}
-
-func setupProducer() types.ProducerConnection {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	topic := os.Getenv("KAFKA_TOPIC_TRANSCATIONS")
-
-	if brokers == "" || topic == "" {
-		log.Fatal("KAFKA_BROKERS or KAFKA_TOPIC_TRANSCATIONS env var not set")
-	}
-
-	conn := messaging.GetProducerConnection(strings.Split(brokers, ","), topic)
+func setupProducer(cfg config.Kafka) types.ProducerConnection {
+	conn := messaging.GetProducerConnection(cfg.Brokers, cfg.TransactionsTopic)
 	if conn == nil || !conn.IsConnected() {
-		log.Fatal("Failed to connect Kafka producer")
+		logging.Fatal(logger, "failed to connect Kafka producer")
 	}
 
 	return conn
 }
 
-func setupEchoServer(kafkaProducer types.ProducerConnection, db *gorm.DB) *echo.Echo {
+func setupHealth(cfg config.Health, kafkaProducer types.ProducerConnection, db *gorm.DB) *health.Checker {
+	checker := health.NewChecker(cfg.Timeout)
+	checker.Add("postgres", health.Postgres(db))
+	checker.Add("kafka", health.Kafka(kafkaProducer))
+	return checker
+}
+
+func setupEchoServer(kafkaProducer types.ProducerConnection, db *gorm.DB, checker *health.Checker, verifier *auth.Verifier, holds *service.HoldService, interest *service.InterestService) *echo.Echo {
 	e := echo.New()
-	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-		StackSize: 1 << 10,
-		LogLevel:  log.ERROR,
-	}))
+	e.Use(logging.Recover())
+	e.Use(tracing.Middleware())
+	logging.Register(e, auth.RequireAdmin())
+	metrics.Register(e)
+	e.Use(auth.Middleware(verifier))
+	metrics.RegisterDBStats(db, "postgres")
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	handler.InitRoutes(e, kafkaProducer, db)
-
-	e.GET("/", func(c echo.Context) error {
-		kafkaProducer.Produce("server started")
-		return c.String(200, "OK")
-	})
+	handler.InitRoutes(e, kafkaProducer, db, holds, interest)
+	checker.Register(e)
 
 	return e
 }
 
 func run() {
-	db, err := setupDatabase()
+	var cfg config.AccountService
+	config.MustLoad(&cfg)
+	if err := logging.Setup("account-service", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "account-service", cfg.Tracing)
 	if err != nil {
-		log.Fatal("Database setup failed:", err)
+		logging.Fatal(logger, "tracing setup failed", "error", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	producer := setupProducer()
+	db, err := database.Open(context.Background(), cfg.Postgres)
+	if err != nil {
+		logging.Fatal(logger, "database setup failed", "error", err)
+	}
+
+	producer := setupProducer(cfg.Kafka)
 	defer producer.Close()
 
-	echoServer := setupEchoServer(producer, db)
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	feeEngine, err := fees.NewEngine(ctx, cfg.Fees.File, db)
+	if err != nil {
+		logging.Fatal(logger, "fee schedules setup failed", "error", err)
+	}
+
+	engine, err := rules.NewEngine(cfg.Rules.File, rules.NewUsage(db))
+	if err != nil {
+		logging.Fatal(logger, "failed to load transfer rules", "error", err)
+	}
+	if err := engine.Watch(ctx); err != nil {
+		logging.Fatal(logger, "failed to watch transfer rules", "error", err)
+	}
+
+	holds := service.NewHoldService(db, producer, engine, feeEngine, cfg.Holds)
+	go holds.RunExpiry(ctx)
+	go service.NewOutbox(db, producer).RunRepublish(ctx, cfg.Outbox)
 
-	port := os.Getenv("ACCOUNT_SERVICE_PORT")
-	if port == "" {
-		port = "9001"
+	interest := service.NewInterestService(db, producer, cfg.Interest)
+	if cfg.Interest.Enabled {
+		go interest.Run(ctx)
 	}
-	log.Infof("Starting server on port %s", port)
-	if err := echoServer.Start(fmt.Sprintf(":%s", port)); err != nil {
-		log.Fatal("Echo server failed:", err)
+
+	grpcServer := rpc.NewServer(verifier)
+	accountv1.RegisterAccountServiceServer(grpcServer, accountrpc.NewServer(
+		service.NewAccountService(db, producer),
+		holds,
+		txservice.NewTransactionService(producer, txrepository.NewTransactionRepository(db), feeEngine),
+		auth.NewOwnership(db),
+	))
+	if err := rpc.Serve(ctx, grpcServer, fmt.Sprintf(":%s", cfg.GRPCPort)); err != nil {
+		logging.Fatal(logger, "gRPC server setup failed", "error", err)
+	}
+
+	echoServer := setupEchoServer(producer, db, setupHealth(cfg.Health, producer, db), verifier, holds, interest)
+
+	logger.Info("starting server", "port", cfg.Port)
+	if err := echoServer.Start(fmt.Sprintf(":%s", cfg.Port)); err != nil {
+		logging.Fatal(logger, "server failed", "error", err)
 	}
 }
 
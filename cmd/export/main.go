@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	ledgerservice "txsystem/internal/ledger/service"
+	"txsystem/internal/transaction/repository"
+	txservice "txsystem/internal/transaction/service"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/export"
+	"txsystem/pkg/common/logging"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	data      = flag.String("data", "transactions", "what to export: transactions or ledger")
+	format    = flag.String("format", "csv", "csv, ndjson or parquet")
+	from      = flag.String("from", "", "first date, YYYY-MM-DD")
+	to        = flag.String("to", "", "last date, YYYY-MM-DD")
+	accountID = flag.String("account", "", "export only this account")
+	out       = flag.String("out", "", "output file; defaults to a name built from the filters")
+)
+
+var logger = logging.For("main")
+
+// exporter writes the filtered records to w and returns how many it wrote.
+type exporter func(ctx context.Context, filter export.Filter, format export.Format, w io.Writer) (int, error)
+
+// run exports transactions or ledger entries to a file, streaming them so
+// exports of any size run in constant memory.
+func run() error {
+	var cfg config.Export
+	config.MustLoad(&cfg)
+	if err := logging.Setup("export", cfg.Logging); err != nil {
+		return err
+	}
+
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	filter, err := export.ParseFilter(*from, *to, *accountID)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var write exporter
+	switch *data {
+	case "transactions":
+		db, err := database.Open(ctx, cfg.Postgres)
+		if err != nil {
+			return err
+		}
+		defer database.Close(db)
+		// Exports neither publish nor price, so they need no producer or
+		// fee engine.
+		write = txservice.NewTransactionService(nil, repository.NewTransactionRepository(db), nil).Export
+	case "ledger":
+		connectCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		client, err := mongo.Connect(connectCtx, options.Client().ApplyURI(cfg.Mongo.URI))
+		if err != nil {
+			return fmt.Errorf("failed to connect to MongoDB: %w", err)
+		}
+		defer client.Disconnect(ctx)
+		calendar, err := ledgerservice.NewCalendar(cfg.EOD)
+		if err != nil {
+			return err
+		}
+		write = ledgerservice.NewLedgerService(client.Database(cfg.Mongo.Database), calendar).Export
+	default:
+		return errors.New("-data must be transactions or ledger")
+	}
+
+	path := *out
+	if path == "" {
+		path = filter.FileName(*data, f)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	n, err := write(ctx, filter, f, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	logger.Info("export written", "data", *data, "file", path, "records", n)
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
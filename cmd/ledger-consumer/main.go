@@ -5,35 +5,30 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 	"txsystem/internal/ledger/processor"
+	"txsystem/internal/ledger/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
 
-	"github.com/joho/godotenv"
-	"github.com/labstack/gommon/log"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Warn("Error loading .env file")
-	}
-}
+var logger = logging.For("main")
 
-func setupMongoDB() (*mongo.Database, error) {
+func setupMongoDB(cfg config.Mongo) (*mongo.Database, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		uri = "mongodb://localhost:27017"
-	}
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI).SetMonitor(tracing.MongoMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -42,25 +37,13 @@ func setupMongoDB() (*mongo.Database, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	dbName := os.Getenv("MONGODB_DATABASE")
-	if dbName == "" {
-		dbName = "ledger_db"
-	}
-
-	return client.Database(dbName), nil
+	return client.Database(cfg.Database), nil
 }
 
-func setupKafkaConsumer() types.ConsumerConnection {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	topic := os.Getenv("KAFKA_TOPIC_TRANSCATIONS")
-
-	if brokers == "" || topic == "" {
-		log.Fatal("KAFKA_BROKERS or KAFKA_TOPIC_TRANSCATIONS env var not set")
-	}
-
-	consumer := messaging.NewKafkaConsumer(strings.Split(brokers, ","), topic)
+func setupKafkaConsumer(cfg config.Kafka) types.ConsumerConnection {
+	consumer := messaging.NewKafkaConsumer(cfg.Brokers, cfg.SagaLedgerTopic, "ledger-consumer-group")
 	if consumer == nil || !consumer.IsConnected() {
-		log.Fatal("Failed to connect to Kafka")
+		logging.Fatal(logger, "failed to connect to Kafka")
 	}
 
 	return consumer
@@ -71,30 +54,55 @@ func waitForShutdown(cancel context.CancelFunc, db *mongo.Database) {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	log.Info("Shutdown signal received")
+	logger.Info("shutdown signal received")
 	cancel()
 
 	ctx, cancelTimeout := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelTimeout()
 
 	if err := db.Client().Disconnect(ctx); err != nil {
-		log.Errorf("Error disconnecting from MongoDB: %v", err)
+		logger.Error("error disconnecting from MongoDB", "error", err)
 	}
 
-	log.Info("Gracefully shut down")
-	os.Exit(0)
+	logger.Info("gracefully shut down")
 }
 
 func run() {
-	db, err := setupMongoDB()
+	var cfg config.LedgerConsumer
+	config.MustLoad(&cfg)
+	if err := logging.Setup("ledger-consumer", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "ledger-consumer", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := setupMongoDB(cfg.Mongo)
+	if err != nil {
+		logging.Fatal(logger, "failed to set up MongoDB", "error", err)
+	}
+
+	calendar, err := service.NewCalendar(cfg.EOD)
 	if err != nil {
-		log.Fatalf("Failed to setup MongoDB: %v", err)
+		logging.Fatal(logger, "failed to set up business calendar", "error", err)
+	}
+	if err := service.NewLedgerService(db, calendar).EnsureIndexes(context.Background()); err != nil {
+		logging.Fatal(logger, "failed to set up ledger indexes", "error", err)
 	}
 
-	msgProcessor := processor.NewMessageProcessor(db)
+	replyProducer := messaging.GetProducerConnection(cfg.Kafka.Brokers, cfg.Kafka.SagaReplyTopic)
+	if replyProducer == nil || !replyProducer.IsConnected() {
+		logging.Fatal(logger, "failed to connect saga reply producer to Kafka")
+	}
+	defer replyProducer.Close()
+
+	msgProcessor := processor.NewMessageProcessor(db, calendar, replyProducer)
 
 	// Set up Kafka consumer
-	consumer := setupKafkaConsumer()
+	consumer := setupKafkaConsumer(cfg.Kafka)
 	defer consumer.Close()
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -102,7 +110,21 @@ func run() {
 
 	// Start consuming messages
 	consumer.StartConsumer(ctx, msgProcessor)
-	log.Info("Ledger service started and consuming messages...")
+	logger.Info("ledger consumer started")
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
+	}
+
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Add("mongodb", health.Mongo(db.Client()))
+	checker.Add("kafka", health.Kafka(consumer))
+	checker.Add("kafka-replies", health.Kafka(replyProducer))
+	checker.Add("consumer", health.Consumer(consumer, cfg.Health.MaxPollAge, cfg.Health.MaxLag))
+	checker.Handle("/metrics", metrics.Handler())
+	checker.Handle("/admin/log-level", auth.AdminHandler(verifier, logging.LevelHandler()))
+	checker.Serve(ctx, ":"+cfg.HealthPort)
 
 	// Wait for shutdown signal
 	waitForShutdown(cancel, db)
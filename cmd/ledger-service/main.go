@@ -8,32 +8,30 @@ import (
 	"syscall"
 	"time"
 	"txsystem/internal/ledger/handlers"
+	"txsystem/internal/ledger/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
 )
 
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Warn("Error loading .env file")
-	}
-}
+var logger = logging.For("main")
 
-func setupMongoDB() (*mongo.Database, error) {
+func setupMongoDB(cfg config.Mongo) (*mongo.Database, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		uri = "mongodb://localhost:27017"
-	}
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.URI).SetMonitor(tracing.MongoMonitor()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
@@ -43,27 +41,34 @@ func setupMongoDB() (*mongo.Database, error) {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
-	dbName := os.Getenv("MONGODB_DATABASE")
-	if dbName == "" {
-		dbName = "ledger_db"
-	}
+	return client.Database(cfg.Database), nil
+}
 
-	return client.Database(dbName), nil
+func setupHealth(cfg config.Health, db *mongo.Database, pg *gorm.DB) *health.Checker {
+	checker := health.NewChecker(cfg.Timeout)
+	checker.Add("mongodb", health.Mongo(db.Client()))
+	checker.Add("postgres", health.Postgres(pg))
+	return checker
 }
 
-func setupEchoServer(db *mongo.Database) *echo.Echo {
+func setupEchoServer(db *mongo.Database, pg *gorm.DB, calendar *service.Calendar, checker *health.Checker, verifier *auth.Verifier) *echo.Echo {
 	e := echo.New()
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+	e.Use(logging.Recover())
 	e.Use(middleware.CORS())
 
+	e.Use(tracing.Middleware())
+	logging.Register(e, auth.RequireAdmin())
+	metrics.Register(e)
+	metrics.RegisterDBStats(pg, "postgres")
+	e.Use(auth.Middleware(verifier))
+
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	handlers.InitRoutes(e, db)
+	handlers.InitRoutes(e, db, calendar, auth.NewOwnership(pg))
 
-	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(200, map[string]string{"status": "ok"})
-	})
+	checker.Register(e)
+	// Kept for probes configured before /healthz existed.
+	e.GET("/health", echo.WrapHandler(checker.LivenessHandler()))
 
 	return e
 }
@@ -73,48 +78,79 @@ func waitForShutdown(e *echo.Echo, db *mongo.Client) {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 
-	log.Info("Shutting down server...")
+	logger.Info("shutting down server")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := db.Disconnect(ctx); err != nil {
-		log.Errorf("Error disconnecting from MongoDB: %v", err)
+		logger.Error("error disconnecting from MongoDB", "error", err)
 	}
 
 	if err := e.Shutdown(ctx); err != nil {
-		log.Fatal(err)
+		logging.Fatal(logger, "server shutdown failed", "error", err)
 	}
 }
 
 func run() {
+	var cfg config.LedgerService
+	config.MustLoad(&cfg)
+	if err := logging.Setup("ledger-service", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "ledger-service", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Connect to MongoDB
-	db, err := setupMongoDB()
+	db, err := setupMongoDB(cfg.Mongo)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		logging.Fatal(logger, "failed to connect to MongoDB", "error", err)
 	}
-	log.Info("Connected to MongoDB")
+	logger.Info("connected to MongoDB")
 
-	// Setup Echo server
-	e := setupEchoServer(db)
+	// Account ownership lives with the accounts in Postgres.
+	pg, err := database.Open(context.Background(), cfg.Postgres)
+	if err != nil {
+		logging.Fatal(logger, "database setup failed", "error", err)
+	}
+	defer database.Close(pg)
 
-	// Start server
-	port := os.Getenv("LEDGER_SERVICE_PORT")
-	if port == "" {
-		port = "9003"
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
 	}
 
+	calendar, err := service.NewCalendar(cfg.EOD)
+	if err != nil {
+		logging.Fatal(logger, "failed to set up business calendar", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cfg.EOD.Enabled {
+		go service.NewLedgerService(db, calendar).RunEOD(ctx, cfg.EOD)
+	}
+
+	// Setup Echo server
+	e := setupEchoServer(db, pg, calendar, setupHealth(cfg.Health, db, pg), verifier)
+
+	// Start server
 	go func() {
-		if err := e.Start(fmt.Sprintf(":%s", port)); err != nil {
-			log.Infof("Shutting down: %v", err)
+		if err := e.Start(fmt.Sprintf(":%s", cfg.Port)); err != nil {
+			logger.Info("server stopped", "reason", err)
 		}
 	}()
 
-	log.Infof("Ledger service started on port %s", port)
+	logger.Info("ledger service started", "port", cfg.Port)
 
 	// Wait for shutdown signal
 	waitForShutdown(e, db.Client())
-	log.Info("Server gracefully stopped")
+	logger.Info("server gracefully stopped")
 }
 
 func main() {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"txsystem/migrations"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/logging"
+)
+
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up         apply all pending migrations
+  down [n]   revert the last n migrations (default 1)
+  status     list migrations and when they were applied`
+
+var logger = logging.For("main")
+
+func run() error {
+	var cfg config.Migrate
+	config.MustLoad(&cfg)
+	if err := logging.Setup("migrate", cfg.Logging); err != nil {
+		return err
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usage)
+	}
+
+	ctx := context.Background()
+	db, err := database.Open(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	migrator, err := database.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+		logger.Info("migrations applied")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+		}
+		if err := migrator.Down(ctx, steps); err != nil {
+			return err
+		}
+		logger.Info("migrations reverted", "steps", steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02T15:04:05Z07:00")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"txsystem/internal/saga/processor"
+	"txsystem/internal/saga/repository"
+	"txsystem/internal/saga/service"
+	txrepository "txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
+	"txsystem/pkg/common/types"
+)
+
+var logger = logging.For("main")
+
+func run() {
+	var cfg config.SagaCoordinator
+	config.MustLoad(&cfg)
+	if err := logging.Setup("saga-coordinator", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "saga-coordinator", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := database.Open(context.Background(), cfg.Postgres)
+	if err != nil {
+		logging.Fatal(logger, "database setup failed", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	accountProducer := setupProducer(cfg.Kafka.Brokers, cfg.Kafka.SagaAccountTopic)
+	ledgerProducer := setupProducer(cfg.Kafka.Brokers, cfg.Kafka.SagaLedgerTopic)
+	statusProducer := setupProducer(cfg.Kafka.Brokers, cfg.Kafka.StatusTopic)
+
+	coordinator := service.NewCoordinator(
+		repository.NewSagaRepository(db),
+		txrepository.NewTransactionRepository(db),
+		accountProducer, ledgerProducer, statusProducer,
+		cfg.Saga,
+	)
+	go coordinator.Run(ctx)
+
+	transactions := setupKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.TransactionsTopic, "saga-coordinator-group")
+	transactions.StartConsumer(ctx, processor.NewTransactionProcessor(coordinator))
+	replies := setupKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.SagaReplyTopic, "saga-replies-group")
+	replies.StartConsumer(ctx, processor.NewReplyProcessor(coordinator))
+	logger.Info("saga coordinator started")
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
+	}
+
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Add("postgres", health.Postgres(db))
+	checker.Add("kafka", health.Kafka(transactions))
+	checker.Add("kafka-account", health.Kafka(accountProducer))
+	checker.Add("kafka-ledger", health.Kafka(ledgerProducer))
+	checker.Add("kafka-status", health.Kafka(statusProducer))
+	checker.Add("consumer-transactions", health.Consumer(transactions, cfg.Health.MaxPollAge, cfg.Health.MaxLag))
+	checker.Add("consumer-replies", health.Consumer(replies, cfg.Health.MaxPollAge, cfg.Health.MaxLag))
+	checker.Handle("/metrics", metrics.Handler())
+	checker.Handle("/admin/log-level", auth.AdminHandler(verifier, logging.LevelHandler()))
+	metrics.RegisterDBStats(db, "postgres")
+	checker.Serve(ctx, ":"+cfg.HealthPort)
+
+	waitForShutdown(cancel)
+
+	logger.Info("shutting down coordinator")
+	transactions.Close()
+	replies.Close()
+	accountProducer.Close()
+	ledgerProducer.Close()
+	statusProducer.Close()
+	logger.Info("shutdown complete")
+}
+
+func main() {
+	run()
+}
+
+func setupProducer(brokers []string, topic string) types.ProducerConnection {
+	producer := messaging.GetProducerConnection(brokers, topic)
+	if producer == nil || !producer.IsConnected() {
+		logging.Fatal(logger, "failed to connect producer to Kafka", "topic", topic)
+	}
+	return producer
+}
+
+func setupKafkaConsumer(brokers []string, topic, groupID string) types.ConsumerConnection {
+	consumer := messaging.NewKafkaConsumer(brokers, topic, groupID)
+	if consumer == nil || !consumer.IsConnected() {
+		logging.Fatal(logger, "failed to connect to Kafka", "topic", topic)
+	}
+	return consumer
+}
+
+func waitForShutdown(cancelFunc context.CancelFunc) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+	logger.Info("shutdown signal received")
+	cancelFunc()
+}
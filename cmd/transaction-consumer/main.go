@@ -2,116 +2,98 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
-	"time"
 
 	"txsystem/internal/account/processor"
+	"txsystem/internal/account/rules"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
-
-	"github.com/joho/godotenv"
-	"github.com/labstack/gommon/log"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 )
 
-// LoadEnv loads env vars; already done in init()
-func init() {
-	if err := godotenv.Load(); err != nil {
-		panic("Error loading .env file")
-	}
-}
+var logger = logging.For("main")
 
 func run() {
-	db, err := setupDatabase()
-	if err != nil {
-		log.Fatalf("database setup failed: %v", err)
+	var cfg config.TransactionConsumer
+	config.MustLoad(&cfg)
+	if err := logging.Setup("transaction-consumer", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
 	}
 
-	msgProcessor := processor.NewMessageProcessor(db)
+	shutdownTracing, err := tracing.Setup(context.Background(), "transaction-consumer", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
 
-	consumer := setupKafkaConsumer()
+	db, err := database.Open(context.Background(), cfg.Postgres)
 	if err != nil {
-		log.Fatalf("kafka consumer setup failed: %v", err)
+		logging.Fatal(logger, "database setup failed", "error", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	consumer.StartConsumer(ctx, msgProcessor)
-	log.Info("Kafka consumer started...")
+	engine, err := rules.NewEngine(cfg.Rules.File, rules.NewUsage(db))
+	if err != nil {
+		logging.Fatal(logger, "failed to load transfer rules", "error", err)
+	}
+	if err := engine.Watch(ctx); err != nil {
+		logging.Fatal(logger, "failed to watch transfer rules", "error", err)
+	}
 
-	waitForShutdown(cancel)
+	replyProducer := messaging.GetProducerConnection(cfg.Kafka.Brokers, cfg.Kafka.SagaReplyTopic)
+	if replyProducer == nil || !replyProducer.IsConnected() {
+		logging.Fatal(logger, "failed to connect saga reply producer to Kafka")
+	}
 
-	log.Info("Shutting down consumer...")
-	consumer.Close()
-	log.Info("Shutdown complete")
-}
+	msgProcessor := processor.NewMessageProcessor(db, engine, replyProducer)
 
-func main() {
-	run()
-}
+	consumer := setupKafkaConsumer(cfg.Kafka)
 
-func setupDatabase() (*gorm.DB, error) {
-	port := os.Getenv("POSTGRES_PORT")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
-	host := os.Getenv("POSTGRES_HOST")
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	maxRetries := 10
-	retryDelay := 3 * time.Second
-
-	var db *gorm.DB
-	var err error
-
-	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		if err == nil {
-			// Test the connection
-			sqlDB, pingErr := db.DB()
-			if pingErr == nil {
-				pingErr = sqlDB.Ping()
-			}
-			if pingErr == nil {
-				log.Info("Connected to database")
-				break
-			} else {
-				err = pingErr
-			}
-		}
-
-		log.Errorf("Failed to connect to DB (attempt %d/%d): %v", i+1, maxRetries, err)
-		time.Sleep(retryDelay)
-	}
+	consumer.StartConsumer(ctx, msgProcessor)
+	logger.Info("Kafka consumer started")
 
+	verifier, err := auth.NewVerifier(cfg.Auth)
 	if err != nil {
-		return nil, fmt.Errorf("could not connect to database after %d attempts: %w", maxRetries, err)
+		logging.Fatal(logger, "auth setup failed", "error", err)
 	}
 
-	log.Info("Migrating database...")
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Add("postgres", health.Postgres(db))
+	checker.Add("kafka", health.Kafka(consumer))
+	checker.Add("kafka-replies", health.Kafka(replyProducer))
+	checker.Add("consumer", health.Consumer(consumer, cfg.Health.MaxPollAge, cfg.Health.MaxLag))
+	checker.Handle("/metrics", metrics.Handler())
+	checker.Handle("/admin/log-level", auth.AdminHandler(verifier, logging.LevelHandler()))
+	metrics.RegisterDBStats(db, "postgres")
+	checker.Serve(ctx, ":"+cfg.HealthPort)
 
-	return db, nil
+	waitForShutdown(cancel)
+
+	logger.Info("shutting down consumer")
+	consumer.Close()
+	replyProducer.Close()
+	logger.Info("shutdown complete")
 }
 
-func setupKafkaConsumer() types.ConsumerConnection {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	topic := os.Getenv("KAFKA_TOPIC_TRANSCATIONS")
+func main() {
+	run()
+}
 
-	if brokers == "" || topic == "" {
-		log.Fatal("KAFKA_BROKERS or KAFKA_TOPIC_TRANSCATIONS env var not set")
+func setupKafkaConsumer(cfg config.Kafka) types.ConsumerConnection {
+	consumer := messaging.NewKafkaConsumer(cfg.Brokers, cfg.SagaAccountTopic, "transaction-consumer-group")
+	if consumer == nil || !consumer.IsConnected() {
+		logging.Fatal(logger, "failed to connect to Kafka")
 	}
-
-	consumer := messaging.NewKafkaConsumer(strings.Split(brokers, ","), topic)
 	return consumer
 }
 
@@ -119,8 +101,6 @@ func waitForShutdown(cancelFunc context.CancelFunc) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
-	log.Info("Shutdown signal received")
+	logger.Info("shutdown signal received")
 	cancelFunc()
-	os.Exit(0)
-	time.Sleep(2 * time.Second)
 }
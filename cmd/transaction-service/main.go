@@ -1,138 +1,142 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"strings"
-	"time"
+	accountv1 "txsystem/api/account/v1"
+	transactionv1 "txsystem/api/transaction/v1"
 	_ "txsystem/docs"
+	"txsystem/internal/transaction/fees"
 	"txsystem/internal/transaction/handler"
-	"txsystem/internal/transaction/models"
+	"txsystem/internal/transaction/repository"
+	transactionrpc "txsystem/internal/transaction/rpc"
+	"txsystem/internal/transaction/service"
+	webhookhandler "txsystem/internal/webhook/handler"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/rpc"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
 
-	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	echoSwagger "github.com/swaggo/echo-swagger"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-// LoadEnv loads env vars; already done in init()
-func init() {
-	if err := godotenv.Load(); err != nil {
-		panic("Error loading .env file")
-	}
-}
-
-func setupDatabase() (*gorm.DB, error) {
-	port := os.Getenv("POSTGRES_PORT")
-	user := os.Getenv("POSTGRES_USER")
-	password := os.Getenv("POSTGRES_PASSWORD")
-	dbname := os.Getenv("POSTGRES_DB")
-	host := os.Getenv("POSTGRES_HOST")
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	maxRetries := 10
-	retryDelay := 3 * time.Second
-
-	var db *gorm.DB
-	var err error
-
-	for i := 0; i < maxRetries; i++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		if err == nil {
-			// Test the connection
-			sqlDB, pingErr := db.DB()
-			if pingErr == nil {
-				pingErr = sqlDB.Ping()
-			}
-			if pingErr == nil {
-				log.Info("Connected to database")
-				break
-			} else {
-				err = pingErr
-			}
-		}
-
-		log.Errorf("Failed to connect to DB (attempt %d/%d): %v", i+1, maxRetries, err)
-		time.Sleep(retryDelay)
-	}
+var logger = logging.For("main")
 
-	if err != nil {
-		return nil, fmt.Errorf("could not connect to database after %d attempts: %w", maxRetries, err)
-	}
-
-	log.Info("Migrating database...")
-	if err := db.AutoMigrate(&models.Transaction{}); err != nil {
-		return nil, err
+func setupProducer(cfg config.Kafka) types.ProducerConnection {
+	conn := messaging.GetProducerConnection(cfg.Brokers, cfg.TransactionsTopic)
+	if conn == nil || !conn.IsConnected() {
+		logging.Fatal(logger, "failed to connect Kafka producer")
 	}
 
-	return db, nil
+	return conn
 }
 
-func setupProducer() types.ProducerConnection {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	topic := os.Getenv("KAFKA_TOPIC_TRANSCATIONS")
+func setupHealth(cfg config.Health, kafkaProducer types.ProducerConnection, db *gorm.DB) *health.Checker {
+	checker := health.NewChecker(cfg.Timeout)
+	checker.Add("postgres", health.Postgres(db))
+	checker.Add("kafka", health.Kafka(kafkaProducer))
+	return checker
+}
 
-	if brokers == "" || topic == "" {
-		log.Fatal("KAFKA_BROKERS or KAFKA_TOPIC_TRANSCATIONS env var not set")
+// setupAccountCheck returns nil when the account check is disabled.
+func setupAccountCheck(cfg config.AccountCheck, fe *fees.Engine) *service.AccountChecker {
+	if !cfg.Enabled {
+		return nil
 	}
-
-	conn := messaging.GetProducerConnection(strings.Split(brokers, ","), topic)
-	if conn == nil || !conn.IsConnected() {
-		log.Fatal("Failed to connect Kafka producer")
+	conn, err := rpc.Dial(cfg.Addr, cfg.Token)
+	if err != nil {
+		logging.Fatal(logger, "account client setup failed", "error", err)
 	}
-
-	return conn
+	return service.NewAccountChecker(accountv1.NewAccountServiceClient(conn), fe, cfg)
 }
 
-func setupEchoServer(kafkaProducer types.ProducerConnection, db *gorm.DB) *echo.Echo {
+func setupEchoServer(kafkaProducer types.ProducerConnection, db *gorm.DB, checker *health.Checker, verifier *auth.Verifier, cfg *config.TransactionService, accounts *service.AccountChecker, fe *fees.Engine) *echo.Echo {
 	e := echo.New()
-	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
-		StackSize: 1 << 10,
-		LogLevel:  log.ERROR,
-	}))
+	e.Use(logging.Recover())
+	e.Use(tracing.Middleware())
+	logging.Register(e, auth.RequireAdmin())
+	metrics.Register(e)
+	e.Use(auth.Middleware(verifier))
+	metrics.RegisterDBStats(db, "postgres")
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	handler.InitRoutes(e, kafkaProducer, db)
-
-	e.GET("/", func(c echo.Context) error {
-		kafkaProducer.Produce("server started")
-		return c.String(200, "OK")
-	})
+	handler.InitRoutes(e, kafkaProducer, db, cfg, accounts, fe)
+	webhookhandler.InitRoutes(e, db, cfg.WebhookTargets)
+	checker.Register(e)
 
 	return e
 }
 
 func run() {
-	db, err := setupDatabase()
+	var cfg config.TransactionService
+	config.MustLoad(&cfg)
+	if err := logging.Setup("transaction-service", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "transaction-service", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := database.Open(context.Background(), cfg.Postgres)
 	if err != nil {
-		log.Fatal("Database setup failed:", err)
+		logging.Fatal(logger, "database setup failed", "error", err)
 	}
 
-	producer := setupProducer()
+	producer := setupProducer(cfg.Kafka)
 	defer producer.Close()
 
-	echoServer := setupEchoServer(producer, db)
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	port := os.Getenv("ACCOUNT_SERVICE_PORT")
-	if port == "" {
-		port = "9001"
+	feeEngine, err := fees.NewEngine(ctx, cfg.Fees.File, db)
+	if err != nil {
+		logging.Fatal(logger, "fee schedules setup failed", "error", err)
+	}
+
+	transactions := service.NewTransactionService(producer, repository.NewTransactionRepository(db), feeEngine)
+	if cfg.Scheduler.Enabled {
+		scheduler := service.NewScheduler(repository.NewScheduleRepository(db), transactions, cfg.Scheduler)
+		go scheduler.Run(ctx)
+	}
+
+	batches := service.NewBatchService(producer, repository.NewBatchRepository(db), feeEngine)
+	go batches.RunRepublish(ctx, cfg.Batch)
+
+	grpcServer := rpc.NewServer(verifier)
+	transactionv1.RegisterTransactionServiceServer(grpcServer, transactionrpc.NewServer(transactions, auth.NewOwnership(db)))
+	if err := rpc.Serve(ctx, grpcServer, fmt.Sprintf(":%s", cfg.GRPCPort)); err != nil {
+		logging.Fatal(logger, "gRPC server setup failed", "error", err)
 	}
-	log.Infof("Starting server on port %s", port)
-	if err := echoServer.Start(fmt.Sprintf(":%s", port)); err != nil {
-		log.Fatal("Echo server failed:", err)
+
+	echoServer := setupEchoServer(producer, db, setupHealth(cfg.Health, producer, db), verifier, &cfg, setupAccountCheck(cfg.AccountCheck, feeEngine), feeEngine)
+
+	logger.Info("starting server", "port", cfg.Port)
+	if err := echoServer.Start(fmt.Sprintf(":%s", cfg.Port)); err != nil {
+		logging.Fatal(logger, "server failed", "error", err)
 	}
 }
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer JWT, e.g. "Bearer eyJhbGciOi..."
 func main() {
 	run()
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"txsystem/internal/webhook/processor"
+	"txsystem/internal/webhook/repository"
+	"txsystem/internal/webhook/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/database"
+	"txsystem/pkg/common/health"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
+	"txsystem/pkg/common/types"
+)
+
+var logger = logging.For("main")
+
+func run() {
+	var cfg config.WebhookDispatcher
+	config.MustLoad(&cfg)
+	if err := logging.Setup("webhook-dispatcher", cfg.Logging); err != nil {
+		logging.Fatal(logger, "logging setup failed", "error", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "webhook-dispatcher", cfg.Tracing)
+	if err != nil {
+		logging.Fatal(logger, "tracing setup failed", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	db, err := database.Open(context.Background(), cfg.Postgres)
+	if err != nil {
+		logging.Fatal(logger, "database setup failed", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dispatcher := service.NewDispatcher(
+		repository.NewWebhookRepository(db),
+		repository.NewDeliveryRepository(db),
+		cfg.Webhooks,
+		cfg.WebhookTargets,
+	)
+	go dispatcher.Run(ctx)
+
+	consumer := setupKafkaConsumer(cfg.Kafka)
+	consumer.StartConsumer(ctx, processor.NewMessageProcessor(dispatcher))
+	logger.Info("webhook dispatcher started")
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		logging.Fatal(logger, "auth setup failed", "error", err)
+	}
+
+	checker := health.NewChecker(cfg.Health.Timeout)
+	checker.Add("postgres", health.Postgres(db))
+	checker.Add("kafka", health.Kafka(consumer))
+	checker.Add("consumer", health.Consumer(consumer, cfg.Health.MaxPollAge, cfg.Health.MaxLag))
+	checker.Handle("/metrics", metrics.Handler())
+	checker.Handle("/admin/log-level", auth.AdminHandler(verifier, logging.LevelHandler()))
+	metrics.RegisterDBStats(db, "postgres")
+	checker.Serve(ctx, ":"+cfg.HealthPort)
+
+	waitForShutdown(cancel)
+
+	logger.Info("shutting down dispatcher")
+	consumer.Close()
+	logger.Info("shutdown complete")
+}
+
+func main() {
+	run()
+}
+
+func setupKafkaConsumer(cfg config.Kafka) types.ConsumerConnection {
+	consumer := messaging.NewKafkaConsumer(cfg.Brokers, cfg.StatusTopic, "webhook-dispatcher-group")
+	if consumer == nil || !consumer.IsConnected() {
+		logging.Fatal(logger, "failed to connect to Kafka")
+	}
+	return consumer
+}
+
+func waitForShutdown(cancelFunc context.CancelFunc) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+	logger.Info("shutdown signal received")
+	cancelFunc()
+}
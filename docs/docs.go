@@ -15,28 +15,39 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/transactions": {
+        "/api/v1/fees/schedules/{version}": {
             "get": {
-                "description": "GetTransactions handles fetching list of last 10 transactions",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetFeeSchedules returns the fee schedules recorded under a version, as charged to the transactions carrying that fee_schedule_version.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "fees"
+                ],
+                "summary": "Get a fee schedules version",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fee schedules version",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get transactions",
                 "responses": {
                     "200": {
-                        "description": "List of transactions",
+                        "description": "Fee schedules",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/types.TransactionResponse"
-                            }
+                            "$ref": "#/definitions/fees.Schedules"
                         }
                     },
                     "400": {
-                        "description": "error:bad request",
+                        "description": "error:invalid version",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -45,7 +56,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error:transactions not found",
+                        "description": "error:fee schedules version not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -54,7 +65,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error:failed to fetch transactions",
+                        "description": "error:failed to get fee schedules",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -63,33 +74,137 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "CreateTransaction handles creating a transaction.",
-                "consumes": [
+            }
+        },
+        "/api/v1/schedules": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetSchedules lists the schedules debiting the caller's accounts, or every schedule for admins.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "List scheduled transfers",
+                "responses": {
+                    "200": {
+                        "description": "List of schedules",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.ScheduleResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error:failed to fetch schedules",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "schedules"
                 ],
-                "summary": "Create a new transaction",
+                "summary": "Get a scheduled transfer",
                 "parameters": [
                     {
-                        "description": "Transaction request",
-                        "name": "transaction",
-                        "in": "body",
-                        "required": true,
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Schedule details",
                         "schema": {
-                            "$ref": "#/definitions/types.TransactionRequest"
+                            "$ref": "#/definitions/types.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid schedule ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:schedule not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Cancel a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "message:transaction created",
+                    "200": {
+                        "description": "Cancelled schedule",
+                        "schema": {
+                            "$ref": "#/definitions/types.ScheduleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "error:schedule not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -97,8 +212,50 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "error:invalid request",
+                    "409": {
+                        "description": "error:schedule cannot change to the requested state",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/schedules/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedules"
+                ],
+                "summary": "Pause a scheduled transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Schedule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paused schedule",
+                        "schema": {
+                            "$ref": "#/definitions/types.ScheduleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "error:schedule not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -106,8 +263,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "error:failed to create transaction",
+                    "409": {
+                        "description": "error:schedule cannot change to the requested state",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -118,20 +275,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/transactions/{id}": {
-            "get": {
-                "description": "GetTransaction handles fetching a transaction by ID.",
+        "/api/v1/schedules/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Occurrences that fell due while paused are skipped.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "transactions"
+                    "schedules"
                 ],
-                "summary": "Get a transaction by ID",
+                "summary": "Resume a paused scheduled transfer",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Transaction ID",
+                        "description": "Schedule ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -139,13 +301,68 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Transaction details",
+                        "description": "Resumed schedule",
                         "schema": {
-                            "$ref": "#/definitions/types.TransactionResponse"
+                            "$ref": "#/definitions/types.ScheduleResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "error:schedule not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error:schedule cannot change to the requested state",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetTransactions handles fetching list of last 10 transactions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get transactions",
+                "responses": {
+                    "200": {
+                        "description": "List of transactions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TransactionResponse"
+                            }
                         }
                     },
                     "400": {
-                        "description": "error:invalid transaction ID",
+                        "description": "error:bad request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -154,7 +371,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error:transaction not found",
+                        "description": "error:transactions not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -163,7 +380,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error:failed to fetch transaction",
+                        "description": "error:failed to fetch transactions",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -172,45 +389,1028 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "types.TransactionRequest": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "description": {
-                    "type": "string"
-                },
-                "destination_account": {
-                    "type": "string"
-                },
-                "source_account": {
-                    "type": "string"
-                },
-                "transaction_type": {
-                    "type": "string"
-                }
-            }
-        },
-        "types.TransactionResponse": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "number"
-                },
-                "created_at": {
-                    "type": "string"
-                },
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "CreateTransaction handles creating a transaction. With execute_at or recurrence the transfer is scheduled instead and the schedule is returned. When the account check is enabled, transfers that cannot settle are rejected with 422 and a reason code (account_not_found, currency_mismatch, insufficient_funds).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Create a new transaction",
+                "parameters": [
+                    {
+                        "description": "Transaction request",
+                        "name": "transaction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.TransactionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created transaction, with its fee",
+                        "schema": {
+                            "$ref": "#/definitions/types.CreateTransactionResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Scheduled transfer",
+                        "schema": {
+                            "$ref": "#/definitions/types.ScheduleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error:forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "error:insufficient available balance in source account, reason:insufficient_funds",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error:failed to create transaction",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/batch": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "CreateBatch accepts a JSON array, NDJSON (application/x-ndjson) or CSV (text/csv) body, or the same as a multipart upload in the \"file\" field. Every item is validated first; the batch is stored in one database transaction, then its events are published in one Kafka transaction.",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Submit a batch of transactions",
+                "parameters": [
+                    {
+                        "description": "Transactions",
+                        "name": "transactions",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TransactionRequest"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Batch accepted",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid batch",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchRejectedResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "error:batch too large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error:failed to submit batch",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/batch/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetBatch summarises the outcome of every item in a batch.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get batch status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Batch status",
+                        "schema": {
+                            "$ref": "#/definitions/types.BatchStatusResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:batch not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error:failed to fetch batch",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "ExportTransactions streams transactions in ID order as CSV, NDJSON or Parquet, for spreadsheets and the data warehouse. Without account only admins may export. The status is sent before the first row, so a complete export ends with an X-Export-Rows trailer holding the row count and a failed one with X-Export-Error instead.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.apache.parquet"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Export transactions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default), ndjson or parquet",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day of creation, YYYY-MM-DD (UTC)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day of creation, YYYY-MM-DD (UTC)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transfers touching this account",
+                        "name": "account",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "error:format must be csv, ndjson or parquet",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error:forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "StreamTransactions sends server-sent events as transactions are created (transaction.created) and settled (transaction.completed, transaction.failed). Each event ID is a Kafka cursor; reconnecting with it in Last-Event-ID resumes after that event. Without one the stream starts at the current end.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Stream live transaction updates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only transfers touching this account",
+                        "name": "account",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events with this status (pending, completed, failed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume after this event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume after this event, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "$ref": "#/definitions/types.TransactionStatusEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid Last-Event-ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:account not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "error:too many open streams",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transactions/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetTransaction handles fetching a transaction by ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transactions"
+                ],
+                "summary": "Get a transaction by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transaction ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transaction details",
+                        "schema": {
+                            "$ref": "#/definitions/types.TransactionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid transaction ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error:missing bearer token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:transaction not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error:failed to fetch transaction",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetWebhooks lists the webhooks registered by the caller.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "List of webhooks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.WebhookResponse"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "CreateWebhook registers an endpoint for transaction status events on the caller's accounts. The response carries the signing secret, which is not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook details",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Registered webhook",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "error:account filter includes an account you do not own",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook details",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "error:webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "DeleteWebhook removes the webhook and its delivery log.",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted"
+                    },
+                    "404": {
+                        "description": "error:webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetDeliveries returns the latest 100 deliveries of a webhook, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (pending, succeeded, failed)",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery log",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.WebhookDeliveryResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error:invalid status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error:webhook not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}/deliveries/{deliveryId}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Redeliver queues a finished delivery again with a fresh retry budget.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a webhook delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Queued delivery",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookDeliveryResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "error:delivery not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error:delivery is still pending",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "fees.Band": {
+            "type": "object",
+            "properties": {
+                "flat": {
+                    "type": "number"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "up_to": {
+                    "type": "number"
+                }
+            }
+        },
+        "fees.Schedule": {
+            "type": "object",
+            "properties": {
+                "bands": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fees.Band"
+                    }
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "flat": {
+                    "type": "number"
+                },
+                "max": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "tier": {
+                    "type": "string"
+                },
+                "transaction_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "fees.Schedules": {
+            "type": "object",
+            "properties": {
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/fees.Schedule"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.BatchItemError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.BatchItemStatus": {
+            "type": "object",
+            "properties": {
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.BatchRejectedResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BatchItemError"
+                    }
+                }
+            }
+        },
+        "types.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "item_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.BatchStatusResponse": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BatchItemStatus"
+                    }
+                },
+                "pending": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "submitted_by": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.CreateTransactionResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/types.TransactionResponse"
+                }
+            }
+        },
+        "types.Frequency": {
+            "type": "string",
+            "enum": [
+                "daily",
+                "weekly",
+                "monthly"
+            ],
+            "x-enum-varnames": [
+                "FrequencyDaily",
+                "FrequencyWeekly",
+                "FrequencyMonthly"
+            ]
+        },
+        "types.Recurrence": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "frequency": {
+                    "$ref": "#/definitions/types.Frequency"
+                },
+                "until": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ScheduleResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "destination_account": {
+                    "type": "string"
+                },
+                "execute_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_run_at": {
+                    "type": "string"
+                },
+                "occurrences": {
+                    "type": "integer"
+                },
+                "recurrence": {
+                    "$ref": "#/definitions/types.Recurrence"
+                },
+                "source_account": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.TransactionRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "destination_account": {
+                    "type": "string"
+                },
+                "execute_at": {
+                    "description": "ExecuteAt defers the transfer to a future time. With Recurrence it is\nthe first occurrence and defaults to now.",
+                    "type": "string"
+                },
+                "recurrence": {
+                    "$ref": "#/definitions/types.Recurrence"
+                },
+                "source_account": {
+                    "type": "string"
+                },
+                "transaction_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.TransactionResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
                 "destination_account": {
                     "type": "string"
                 },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "fee": {
+                    "type": "number"
+                },
+                "fee_schedule_version": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -230,6 +1430,145 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "types.TransactionStatusEvent": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "destination_account": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                },
+                "source_account": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "integer"
+                },
+                "transaction_type": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.WebhookDeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "description": "Accounts filters by source or destination account. Empty means all of\nthe caller's accounts.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "events": {
+                    "description": "Events filters by event type, e.g. \"transaction.failed\". Empty means all.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret signs deliveries. It is only returned when the webhook is created.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "Bearer JWT, e.g. \"Bearer eyJhbGciOi...\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -1,2 +1,3 @@
 //go:generate swag init --generalInfo ./cmd/transaction-service/main.go --dir . --output ./docs
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative api/account/v1/account.proto api/transaction/v1/transaction.proto
 package main
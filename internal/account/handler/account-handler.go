@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 	"txsystem/internal/account/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/types"
 
 	"github.com/labstack/echo/v4"
@@ -10,12 +13,14 @@ import (
 )
 
 type Handler struct {
-	service *service.AccountService
+	service   *service.AccountService
+	ownership *auth.Ownership
 }
 
-func NewHandler(s *service.AccountService) *Handler {
+func NewHandler(s *service.AccountService, o *auth.Ownership) *Handler {
 	return &Handler{
-		service: s,
+		service:   s,
+		ownership: o,
 	}
 }
 
@@ -25,17 +30,136 @@ func (h *Handler) GetAccount(c echo.Context) error {
 	if err != nil {
 		return c.JSON(400, map[string]string{"error": "Invalid account ID"})
 	}
-	account, err := h.service.GetAccount(c.Request().Context(), accountID)
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, id)
 	if err != nil {
 		return c.JSON(500, map[string]string{"error": "Failed to get account"})
 	}
+	if !allowed {
+		return c.JSON(403, map[string]string{"error": "forbidden"})
+	}
+	account, err := h.service.GetAccount(ctx, accountID)
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to get account"})
+	}
+	return c.JSON(200, account)
+}
+
+// GetBalance returns the account's balance, the overdraft used and the
+// credit still available.
+func (h *Handler) GetBalance(c echo.Context) error {
+	id := c.Param("id")
+	accountID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid account ID"})
+	}
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, id)
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to get balance"})
+	}
+	if !allowed {
+		return c.JSON(403, map[string]string{"error": "forbidden"})
+	}
+	account, err := h.service.GetAccount(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(404, map[string]string{"error": service.ErrAccountNotFound.Error()})
+	}
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to get balance"})
+	}
+	return c.JSON(200, types.BalanceResponse{
+		AccountID:        account.ID,
+		Currency:         account.Currency,
+		Balance:          account.Balance,
+		HeldBalance:      account.HeldBalance,
+		AvailableBalance: account.AvailableBalance,
+		OverdraftLimit:   account.OverdraftLimit,
+		OverdraftUsed:    account.OverdraftUsed,
+		AvailableCredit:  account.AvailableCredit,
+	})
+}
+
+// SetOverdraft changes the account's overdraft limit and fee. Admin only.
+func (h *Handler) SetOverdraft(c echo.Context) error {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid account ID"})
+	}
+	var req types.OverdraftRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "invalid request"})
+	}
+	account, err := h.service.SetOverdraft(c.Request().Context(), uint(accountID), req.Limit, req.Fee)
+	switch {
+	case errors.Is(err, service.ErrInvalidOverdraft):
+		return c.JSON(400, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountNotFound):
+		return c.JSON(404, map[string]string{"error": err.Error()})
+	case err != nil:
+		return c.JSON(500, map[string]string{"error": "Failed to set overdraft"})
+	}
 	return c.JSON(200, account)
 }
 
-func InitRoutes(e *echo.Echo, kc types.ProducerConnection, db *gorm.DB) {
-	transactionService := service.NewAccountService(db)
-	h := NewHandler(transactionService)
-	e.Logger.Info("Initializing transaction routes")
+// maxEventsPage bounds how many events one request returns.
+const maxEventsPage = 1000
+
+// ListEvents returns the account's events after the version given by
+// after, oldest first, up to limit of them.
+func (h *Handler) ListEvents(c echo.Context) error {
+	id := c.Param("id")
+	accountID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return c.JSON(400, map[string]string{"error": "Invalid account ID"})
+	}
+	var after int64
+	if v := c.QueryParam("after"); v != "" {
+		if after, err = strconv.ParseInt(v, 10, 64); err != nil || after < 0 {
+			return c.JSON(400, map[string]string{"error": "Invalid after"})
+		}
+	}
+	limit := 100
+	if v := c.QueryParam("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxEventsPage {
+			return c.JSON(400, map[string]string{"error": "Invalid limit"})
+		}
+	}
+
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, id)
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list account events"})
+	}
+	if !allowed {
+		return c.JSON(403, map[string]string{"error": "forbidden"})
+	}
+	events, err := h.service.Events(ctx, uint(accountID), after, limit)
+	if err != nil {
+		return c.JSON(500, map[string]string{"error": "Failed to list account events"})
+	}
+	return c.JSON(200, events)
+}
+
+func InitRoutes(e *echo.Echo, kc types.ProducerConnection, db *gorm.DB, holds *service.HoldService, interest *service.InterestService) {
+	transactionService := service.NewAccountService(db, kc)
+	ownership := auth.NewOwnership(db)
+	h := NewHandler(transactionService, ownership)
+	logging.For("http").Info("initializing transaction routes")
 	g := e.Group("/api/v1/transactions")
 	g.GET("/:id", h.GetAccount)
+	e.GET("/api/v1/accounts/:id/events", h.ListEvents)
+	e.GET("/api/v1/accounts/:id/balance", h.GetBalance)
+	e.PUT("/api/v1/accounts/:id/overdraft", h.SetOverdraft, auth.RequireAdmin())
+
+	hh := NewHoldHandler(holds, ownership)
+	hg := e.Group("/api/v1/holds")
+	hg.POST("", hh.Authorize)
+	hg.GET("/:id", hh.GetHold)
+	hg.POST("/:id/capture", hh.Capture)
+	hg.POST("/:id/void", hh.Void)
+
+	ih := NewInterestHandler(interest, ownership)
+	e.GET("/api/v1/accounts/:id/interest", ih.GetInterest)
+	e.PUT("/api/v1/accounts/:id/interest", ih.SetInterest, auth.RequireAdmin())
 }
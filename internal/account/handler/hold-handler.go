@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"txsystem/internal/account/rules"
+	"txsystem/internal/account/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HoldHandler struct {
+	service   *service.HoldService
+	ownership *auth.Ownership
+}
+
+func NewHoldHandler(s *service.HoldService, o *auth.Ownership) *HoldHandler {
+	return &HoldHandler{
+		service:   s,
+		ownership: o,
+	}
+}
+
+// Authorize places a hold on the caller's source account.
+func (h *HoldHandler) Authorize(c echo.Context) error {
+	var req types.HoldRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid ttl"})
+		}
+	}
+
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, strconv.FormatUint(uint64(req.AccountID), 10))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create hold"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+	}
+
+	hold, err := h.service.Authorize(ctx, req.AccountID, req.DestinationAccount, req.Amount, ttl, req.Description)
+	if err != nil {
+		return holdError(c, err, "failed to create hold")
+	}
+	return c.JSON(http.StatusCreated, hold)
+}
+
+func (h *HoldHandler) GetHold(c echo.Context) error {
+	id, ok := h.authorizedHold(c)
+	if !ok {
+		return nil
+	}
+	hold, err := h.service.GetHold(c.Request().Context(), id)
+	if err != nil {
+		return holdError(c, err, "failed to get hold")
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+// Capture settles the hold, fully or partially.
+func (h *HoldHandler) Capture(c echo.Context) error {
+	var req types.CaptureRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	id, ok := h.authorizedHold(c)
+	if !ok {
+		return nil
+	}
+	hold, err := h.service.Capture(c.Request().Context(), id, req.Amount)
+	if err != nil {
+		return holdError(c, err, "failed to capture hold")
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+func (h *HoldHandler) Void(c echo.Context) error {
+	id, ok := h.authorizedHold(c)
+	if !ok {
+		return nil
+	}
+	hold, err := h.service.Void(c.Request().Context(), id)
+	if err != nil {
+		return holdError(c, err, "failed to void hold")
+	}
+	return c.JSON(http.StatusOK, hold)
+}
+
+// authorizedHold parses the hold ID and checks the caller owns the held
+// account. It writes the error response itself and reports false on failure.
+func (h *HoldHandler) authorizedHold(c echo.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid hold ID"})
+		return 0, false
+	}
+
+	ctx := c.Request().Context()
+	hold, err := h.service.GetHold(ctx, uint(id))
+	if err != nil {
+		holdError(c, err, "failed to get hold")
+		return 0, false
+	}
+	allowed, err := h.ownership.CanAccess(ctx, strconv.FormatUint(uint64(hold.AccountID), 10))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get hold"})
+		return 0, false
+	}
+	if !allowed {
+		// Reported as missing so hold IDs cannot be probed.
+		c.JSON(http.StatusNotFound, map[string]string{"error": service.ErrHoldNotFound.Error()})
+		return 0, false
+	}
+	return hold.ID, true
+}
+
+func holdError(c echo.Context, err error, fallback string) error {
+	var rejection *rules.Rejection
+	switch {
+	case errors.As(err, &rejection):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": rejection.Message, "reason": rejection.Reason})
+	case errors.Is(err, service.ErrHoldNotFound), errors.Is(err, service.ErrAccountNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInsufficientFunds), errors.Is(err, service.ErrCurrencyMismatch):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrHoldNotActive), errors.Is(err, service.ErrHoldExpired):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrSameAccount),
+		errors.Is(err, service.ErrCaptureTooHigh), errors.Is(err, service.ErrInvalidTTL):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": fallback})
+}
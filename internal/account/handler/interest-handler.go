@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"txsystem/internal/account/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterestHandler struct {
+	service   *service.InterestService
+	ownership *auth.Ownership
+}
+
+func NewInterestHandler(s *service.InterestService, o *auth.Ownership) *InterestHandler {
+	return &InterestHandler{
+		service:   s,
+		ownership: o,
+	}
+}
+
+// GetInterest returns the account's interest terms, the interest accrued
+// since the last posting and the latest postings.
+func (h *InterestHandler) GetInterest(c echo.Context) error {
+	id := c.Param("id")
+	accountID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid account ID"})
+	}
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get interest"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+	}
+	summary, err := h.service.Summary(ctx, uint(accountID))
+	if err != nil {
+		return interestError(c, err, "failed to get interest")
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// SetInterest replaces the account's interest terms. Admin only.
+func (h *InterestHandler) SetInterest(c echo.Context) error {
+	accountID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid account ID"})
+	}
+	var req types.InterestRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+	terms, err := h.service.SetTerms(c.Request().Context(), uint(accountID), &req)
+	if err != nil {
+		return interestError(c, err, "failed to set interest terms")
+	}
+	return c.JSON(http.StatusOK, terms)
+}
+
+func interestError(c echo.Context, err error, fallback string) error {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound), errors.Is(err, service.ErrNoInterest):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidInterest):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": fallback})
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type AccountEventType string
+
+const (
+	AccountOpened   AccountEventType = "account_opened"
+	AccountDebited  AccountEventType = "debited"
+	AccountCredited AccountEventType = "credited"
+	HoldPlaced      AccountEventType = "hold_placed"
+	HoldReleased    AccountEventType = "hold_released"
+	FeeCharged      AccountEventType = "fee_charged"
+	FeeRefunded     AccountEventType = "fee_refunded"
+	InterestPosted  AccountEventType = "interest_posted"
+	// OverdraftFeeCharged is the fee for a transfer that drew on the
+	// overdraft. Refunds use FeeRefunded.
+	OverdraftFeeCharged AccountEventType = "overdraft_fee_charged"
+)
+
+// AccountEvent is one change to an account, numbered by Version from 1.
+// Owner and Currency are only set on AccountOpened. TransactionID and HoldID
+// point at what caused the change, when anything did.
+type AccountEvent struct {
+	ID            uint             `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountID     uint             `json:"account_id"`
+	Version       int64            `json:"version"`
+	Type          AccountEventType `json:"type"`
+	Amount        float64          `json:"amount"`
+	Owner         string           `json:"owner,omitempty"`
+	Currency      string           `json:"currency,omitempty"`
+	TransactionID *uint            `json:"transaction_id,omitempty"`
+	HoldID        *uint            `json:"hold_id,omitempty"`
+	CreatedAt     time.Time        `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AccountSnapshot is the account state after Version, so loading it only
+// replays the events that came later.
+type AccountSnapshot struct {
+	AccountID   uint  `gorm:"primaryKey;autoIncrement:false"`
+	Version     int64 `gorm:"primaryKey;autoIncrement:false"`
+	Owner       string
+	Currency    string
+	Balance     float64
+	HeldBalance float64
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
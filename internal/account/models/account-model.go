@@ -2,13 +2,88 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// Account.Balance is the ledger balance. HeldBalance is the part of it
+// reserved by active holds. The balance may go below zero down to
+// -OverdraftLimit, so AvailableBalance, what can be spent, is the balance
+// not held plus the limit. The row is a projection of the account events up
+// to Version; the overdraft terms are set directly.
 type Account struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id,omitempty"`
-	Owner     string    `json:"owner"`
-	Balance   float64   `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID               uint    `gorm:"primaryKey;autoIncrement" json:"id,omitempty"`
+	Owner            string  `json:"owner"`
+	Balance          float64 `json:"balance"`
+	HeldBalance      float64 `json:"held_balance"`
+	AvailableBalance float64 `gorm:"-" json:"available_balance"`
+	Currency         string  `json:"currency"`
+	// Tier selects the fee schedule the account is charged under.
+	Tier           string  `gorm:"default:standard" json:"tier"`
+	OverdraftLimit float64 `json:"overdraft_limit"`
+	// OverdraftFee is charged on each transfer that draws on the overdraft.
+	OverdraftFee    float64   `json:"overdraft_fee"`
+	OverdraftUsed   float64   `gorm:"-" json:"overdraft_used"`
+	AvailableCredit float64   `gorm:"-" json:"available_credit"`
+	Version         int64     `json:"version"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (a *Account) Available() float64 {
+	return a.Balance - a.HeldBalance + a.OverdraftLimit
+}
+
+// Overdrawn is how far the balance is below zero.
+func (a *Account) Overdrawn() float64 {
+	return max(0, -a.Balance)
+}
+
+// Credit is the part of the overdraft limit still available, after the
+// overdraft used and the holds that reach into it.
+func (a *Account) Credit() float64 {
+	return max(0, min(a.OverdraftLimit, a.Available()))
+}
+
+func (a *Account) derive() {
+	a.AvailableBalance = a.Available()
+	a.OverdraftUsed = a.Overdrawn()
+	a.AvailableCredit = a.Credit()
+}
+
+// Apply folds the next event into the account.
+func (a *Account) Apply(e *AccountEvent) {
+	switch e.Type {
+	case AccountOpened:
+		a.Owner = e.Owner
+		a.Currency = e.Currency
+		a.Balance = e.Amount
+	case AccountDebited, FeeCharged, OverdraftFeeCharged:
+		a.Balance -= e.Amount
+	case AccountCredited, FeeRefunded, InterestPosted:
+		a.Balance += e.Amount
+	case HoldPlaced:
+		a.HeldBalance += e.Amount
+	case HoldReleased:
+		a.HeldBalance -= e.Amount
+	}
+	a.Version = e.Version
+	a.derive()
+}
+
+// Snapshot captures the account state at its current version.
+func (a *Account) Snapshot() *AccountSnapshot {
+	return &AccountSnapshot{
+		AccountID:   a.ID,
+		Version:     a.Version,
+		Owner:       a.Owner,
+		Currency:    a.Currency,
+		Balance:     a.Balance,
+		HeldBalance: a.HeldBalance,
+	}
+}
+
+func (a *Account) AfterFind(tx *gorm.DB) error {
+	a.derive()
+	return nil
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldVoided   HoldStatus = "voided"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold reserves Amount on AccountID until it is captured, voided or expires.
+type Hold struct {
+	ID                 uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AccountID          uint       `json:"account_id"`
+	DestinationAccount uint       `json:"destination_account"`
+	Amount             float64    `json:"amount"`
+	CapturedAmount     float64    `json:"captured_amount"`
+	Currency           string     `json:"currency"`
+	Description        string     `json:"description"`
+	Status             HoldStatus `json:"status"`
+	ExpiresAt          time.Time  `json:"expires_at"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
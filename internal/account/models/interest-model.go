@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// DayCount is the convention that turns an annual rate into a daily one.
+type DayCount string
+
+const (
+	DayCountActual365    DayCount = "actual/365"
+	DayCountActual360    DayCount = "actual/360"
+	DayCountActualActual DayCount = "actual/actual"
+	DayCount30360        DayCount = "30/360"
+)
+
+// Compounding is how often accrued interest is paid into the balance, after
+// which it earns interest itself. Daily interest is paid out monthly but
+// earns interest from the day after it accrues.
+type Compounding string
+
+const (
+	CompoundDaily     Compounding = "daily"
+	CompoundMonthly   Compounding = "monthly"
+	CompoundQuarterly Compounding = "quarterly"
+	CompoundAnnually  Compounding = "annually"
+)
+
+// InterestConfig holds the interest terms of an account. AnnualRate is a
+// percentage; an account without a config, or with a zero rate, earns
+// nothing.
+type InterestConfig struct {
+	AccountID   uint        `gorm:"primaryKey;autoIncrement:false" json:"account_id"`
+	AnnualRate  float64     `json:"annual_rate"`
+	DayCount    DayCount    `json:"day_count"`
+	Compounding Compounding `json:"compounding"`
+	CreatedAt   time.Time   `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time   `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// InterestAccrual is the interest an account earned on AccrualDate, on
+// Balance at the end of that day. PostedOn is set once it is paid out.
+type InterestAccrual struct {
+	AccountID   uint       `gorm:"primaryKey;autoIncrement:false" json:"account_id"`
+	AccrualDate time.Time  `gorm:"primaryKey;type:date" json:"accrual_date"`
+	Balance     float64    `json:"balance"`
+	AnnualRate  float64    `json:"annual_rate"`
+	DayCount    DayCount   `json:"day_count"`
+	Amount      float64    `json:"amount"`
+	PostedOn    *time.Time `gorm:"type:date" json:"posted_on,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// InterestPosting is the accrued interest credited to an account on
+// PostingDate by the transaction TransactionID.
+type InterestPosting struct {
+	AccountID     uint      `gorm:"primaryKey;autoIncrement:false" json:"account_id"`
+	PostingDate   time.Time `gorm:"primaryKey;type:date" json:"posting_date"`
+	Amount        float64   `json:"amount"`
+	TransactionID uint      `json:"transaction_id"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// InterestRun records that Job completed for RunDate.
+type InterestRun struct {
+	Job       string    `gorm:"primaryKey"`
+	RunDate   time.Time `gorm:"primaryKey;type:date"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OutboxEntry is a transaction the account service settled itself and must
+// publish on the transactions topic. PublishedAt is set once it is.
+type OutboxEntry struct {
+	TransactionID uint `gorm:"primaryKey;autoIncrement:false"`
+	PublishedAt   *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+func (OutboxEntry) TableName() string {
+	return "transaction_outbox"
+}
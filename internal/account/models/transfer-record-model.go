@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type TransferRecordStatus string
+
+const (
+	TransferApplied  TransferRecordStatus = "applied"
+	TransferRejected TransferRecordStatus = "rejected"
+	TransferReversed TransferRecordStatus = "reversed"
+)
+
+// TransferRecord remembers what became of the balance move for a
+// transaction. A reversed record with no amount is a tombstone left by a
+// reversal that arrived before the move, so the move is never made.
+type TransferRecord struct {
+	TransactionID      uint `gorm:"primaryKey;autoIncrement:false"`
+	SourceAccount      uint
+	DestinationAccount uint
+	Amount             float64
+	Fee                float64
+	// OverdraftDrawn is how much further below zero the transfer took the
+	// source balance, and OverdraftFee what it was charged for that.
+	OverdraftDrawn float64
+	OverdraftFee   float64
+	Status         TransferRecordStatus
+	Reason         string
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
@@ -1,22 +1,175 @@
 package processor
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"txsystem/internal/account/rules"
 	"txsystem/internal/account/service"
+	"txsystem/pkg/common/inbox"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
 	"txsystem/pkg/common/types"
 
 	"gorm.io/gorm"
 )
 
+// ReasonReversed answers a transfer command that arrives after the saga
+// already reversed the transfer.
+const ReasonReversed = "reversed"
+
+var logger = logging.For("settlement")
+
 type messageProcessor struct {
-	acs *service.AccountService
+	acs     *service.AccountService
+	rules   *rules.Engine
+	inbox   *inbox.Inbox
+	replies types.ProducerConnection
 }
 
-func NewMessageProcessor(db *gorm.DB) types.MessageProcessor {
+// NewMessageProcessor is the account participant of the transfer saga: it
+// moves and reverses balances on command and answers on replies.
+func NewMessageProcessor(db *gorm.DB, engine *rules.Engine, replies types.ProducerConnection) types.MessageProcessor {
 	return &messageProcessor{
-		acs: service.NewAccountService(db),
+		acs:     service.NewAccountService(db, nil),
+		rules:   engine,
+		inbox:   inbox.New(db, "account-settlement"),
+		replies: replies,
+	}
+}
+
+// ProcessMessage handles a transfer or reverse command. Rejections are
+// final and answered; only infrastructure errors are returned so the
+// consumer retries them. A command handled before is answered again from
+// the inbox without evaluating the rules or touching balances.
+func (mp *messageProcessor) ProcessMessage(ctx context.Context, message string) error {
+	var cmd types.SagaCommand
+	if err := json.Unmarshal([]byte(message), &cmd); err != nil {
+		return fmt.Errorf("failed to decode saga command: %w", err)
+	}
+
+	key := fmt.Sprintf("%d:%s", cmd.TransactionID, cmd.Action)
+	reason, handled, err := mp.inbox.Lookup(ctx, key)
+	if err != nil {
+		return err
+	}
+	if handled {
+		logger.DebugContext(ctx, "saga command handled before, answering again", "transaction_id", cmd.TransactionID, "action", cmd.Action)
+		return mp.reply(ctx, &cmd, reason)
 	}
+
+	switch cmd.Action {
+	case types.SagaTransfer:
+		reason, err = mp.transfer(ctx, &cmd)
+	case types.SagaReverse:
+		err = mp.acs.ReverseTransfer(ctx, uint(cmd.TransactionID))
+		if err == nil {
+			logger.InfoContext(ctx, "transfer reversed", "transaction_id", cmd.TransactionID)
+		}
+	default:
+		logger.WarnContext(ctx, "unknown saga command, skipping", "action", cmd.Action, "saga_id", cmd.SagaID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Stored after the balances commit. A crash in between handles the
+	// command again, which the account service turns into a replay.
+	if err := mp.inbox.Store(ctx, key, reason); err != nil {
+		return err
+	}
+	return mp.reply(ctx, &cmd, reason)
 }
 
-func (mp *messageProcessor) ProcessMessage(message string) error {
+// transfer checks the transfer rules and moves the balances. It returns the
+// rejection reason, or "" when the transfer was applied.
+func (mp *messageProcessor) transfer(ctx context.Context, cmd *types.SagaCommand) (string, error) {
+	fromID, fromErr := strconv.ParseUint(cmd.SourceAccount, 10, 64)
+	toID, toErr := strconv.ParseUint(cmd.DestinationAccount, 10, 64)
+	if fromErr != nil || toErr != nil {
+		return mp.reject(ctx, cmd, service.ReasonAccountNotFound, service.ErrAccountNotFound)
+	}
+
+	err := mp.rules.Evaluate(ctx, rules.Transfer{
+		ID:                 uint(cmd.TransactionID),
+		Amount:             cmd.Amount,
+		SourceAccount:      cmd.SourceAccount,
+		DestinationAccount: cmd.DestinationAccount,
+	})
+	var rejection *rules.Rejection
+	if errors.As(err, &rejection) {
+		metrics.TransferRejections.WithLabelValues(rejection.Reason).Inc()
+		return mp.reject(ctx, cmd, rejection.Reason, rejection)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = mp.acs.ApplyTransfer(ctx, uint(cmd.TransactionID), uint(fromID), uint(toID), cmd.Amount, cmd.Fee)
+	if errors.Is(err, service.ErrTransferReversed) {
+		return ReasonReversed, nil
+	}
+	if reason := service.RejectionReason(err); reason != "" {
+		logRejection(ctx, cmd, reason, err)
+		return reason, nil
+	}
+	return "", err
+}
+
+// reject records the rejection so repeated commands get the same answer,
+// even if the rules would pass by then.
+func (mp *messageProcessor) reject(ctx context.Context, cmd *types.SagaCommand, reason string, cause error) (string, error) {
+	err := mp.acs.RejectTransfer(ctx, uint(cmd.TransactionID), reason)
+	switch {
+	case err == nil:
+		// Applied by an earlier attempt.
+		return "", nil
+	case errors.Is(err, service.ErrTransferReversed):
+		return ReasonReversed, nil
+	}
+	if recorded := service.RejectionReason(err); recorded != "" {
+		logRejection(ctx, cmd, recorded, cause)
+		return recorded, nil
+	}
+	return "", err
+}
+
+func logRejection(ctx context.Context, cmd *types.SagaCommand, reason string, cause error) {
+	logger.InfoContext(ctx, "transfer rejected",
+		"transaction_id", cmd.TransactionID,
+		"source_account", cmd.SourceAccount,
+		"destination_account", cmd.DestinationAccount,
+		"reason", reason,
+		"error", cause,
+	)
+}
+
+// reply answers the command. An applied transfer is answered with its
+// overdraft fee, read from the transfer record so a repeated answer carries
+// it too.
+func (mp *messageProcessor) reply(ctx context.Context, cmd *types.SagaCommand, reason string) error {
+	r := types.SagaReply{
+		SagaID:        cmd.SagaID,
+		TransactionID: cmd.TransactionID,
+		Action:        cmd.Action,
+		OK:            reason == "",
+		Reason:        reason,
+	}
+	if r.OK && cmd.Action == types.SagaTransfer {
+		fee, err := mp.acs.OverdraftFee(ctx, uint(cmd.TransactionID))
+		if err != nil {
+			return err
+		}
+		r.OverdraftFee = fee
+	}
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga reply: %w", err)
+	}
+	if err := mp.replies.Produce(ctx, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish saga reply: %w", err)
+	}
 	return nil
 }
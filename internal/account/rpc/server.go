@@ -0,0 +1,229 @@
+// Package rpc serves the account gRPC API from the same service as the REST
+// handlers.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	accountv1 "txsystem/api/account/v1"
+	"txsystem/internal/account/models"
+	"txsystem/internal/account/rules"
+	"txsystem/internal/account/service"
+	txservice "txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+type Server struct {
+	accountv1.UnimplementedAccountServiceServer
+	accounts     *service.AccountService
+	holds        *service.HoldService
+	transactions *txservice.TransactionService
+	ownership    *auth.Ownership
+}
+
+func NewServer(as *service.AccountService, hs *service.HoldService, ts *txservice.TransactionService, o *auth.Ownership) *Server {
+	return &Server{accounts: as, holds: hs, transactions: ts, ownership: o}
+}
+
+func (s *Server) GetAccount(ctx context.Context, req *accountv1.GetAccountRequest) (*accountv1.Account, error) {
+	if err := s.authorize(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	account, err := s.accounts.GetAccount(ctx, int(req.GetId()))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Error(codes.NotFound, service.ErrAccountNotFound.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get account")
+	}
+	return toAccount(account), nil
+}
+
+func (s *Server) CreateAccount(ctx context.Context, req *accountv1.CreateAccountRequest) (*accountv1.Account, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, auth.ErrMissingToken.Error())
+	}
+	if req.GetCurrency() == "" {
+		return nil, status.Error(codes.InvalidArgument, "currency is required")
+	}
+	if req.GetInitialBalance() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "initial balance cannot be negative")
+	}
+	owner := req.GetOwner()
+	if owner == "" {
+		owner = p.Subject
+	}
+	if !p.IsAdmin() && (owner != p.Subject || req.GetInitialBalance() != 0) {
+		return nil, status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+	}
+
+	account, err := s.accounts.CreateAccount(ctx, owner, req.GetCurrency(), req.GetInitialBalance())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create account")
+	}
+	return toAccount(account), nil
+}
+
+// Transfer goes through the transaction pipeline like a REST transfer, so
+// rules, settlement and the ledger treat both alike. The idempotency key is
+// scoped to the source account, so it cannot reach another caller's
+// transaction or a scheduler key.
+func (s *Server) Transfer(ctx context.Context, req *accountv1.TransferRequest) (*accountv1.TransferResponse, error) {
+	switch {
+	case req.GetIdempotencyKey() == "":
+		return nil, status.Error(codes.InvalidArgument, "idempotency key is required")
+	case req.GetAmount() <= 0:
+		return nil, status.Error(codes.InvalidArgument, service.ErrInvalidAmount.Error())
+	case req.GetSourceAccount() == req.GetDestinationAccount():
+		return nil, status.Error(codes.InvalidArgument, service.ErrSameAccount.Error())
+	}
+	if err := s.authorize(ctx, req.GetSourceAccount()); err != nil {
+		return nil, err
+	}
+
+	source := strconv.FormatUint(req.GetSourceAccount(), 10)
+	tx, err := s.transactions.CreateTransactionOnce(ctx, &types.TransactionRequest{
+		Amount:             req.GetAmount(),
+		Description:        req.GetDescription(),
+		SourceAccount:      source,
+		DestinationAccount: strconv.FormatUint(req.GetDestinationAccount(), 10),
+		TransactionType:    req.GetTransactionType(),
+	}, "grpc-"+source+"-"+req.GetIdempotencyKey())
+	if errors.Is(err, txservice.ErrIdempotencyKeyReused) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, txservice.ErrIdempotencyKeyConflict) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to create transfer")
+	}
+	return &accountv1.TransferResponse{TransactionId: tx.ID, Status: tx.Status}, nil
+}
+
+func (s *Server) AuthorizeHold(ctx context.Context, req *accountv1.AuthorizeHoldRequest) (*accountv1.Hold, error) {
+	if err := s.authorize(ctx, req.GetAccountId()); err != nil {
+		return nil, err
+	}
+	hold, err := s.holds.Authorize(ctx, uint(req.GetAccountId()), uint(req.GetDestinationAccount()),
+		req.GetAmount(), req.GetTtl().AsDuration(), req.GetDescription())
+	if err != nil {
+		return nil, holdError(err, "failed to create hold")
+	}
+	return toHold(hold), nil
+}
+
+func (s *Server) GetHold(ctx context.Context, req *accountv1.GetHoldRequest) (*accountv1.Hold, error) {
+	hold, err := s.authorizedHold(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return toHold(hold), nil
+}
+
+func (s *Server) CaptureHold(ctx context.Context, req *accountv1.CaptureHoldRequest) (*accountv1.Hold, error) {
+	if _, err := s.authorizedHold(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	hold, err := s.holds.Capture(ctx, uint(req.GetId()), req.GetAmount())
+	if err != nil {
+		return nil, holdError(err, "failed to capture hold")
+	}
+	return toHold(hold), nil
+}
+
+func (s *Server) VoidHold(ctx context.Context, req *accountv1.VoidHoldRequest) (*accountv1.Hold, error) {
+	if _, err := s.authorizedHold(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	hold, err := s.holds.Void(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, holdError(err, "failed to void hold")
+	}
+	return toHold(hold), nil
+}
+
+// authorize checks the caller may act on the account.
+func (s *Server) authorize(ctx context.Context, accountID uint64) error {
+	allowed, err := s.ownership.CanAccess(ctx, strconv.FormatUint(accountID, 10))
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check account access")
+	}
+	if !allowed {
+		return status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+	}
+	return nil
+}
+
+// authorizedHold loads the hold and checks the caller owns the held account.
+// Other callers' holds are reported as missing.
+func (s *Server) authorizedHold(ctx context.Context, id uint64) (*models.Hold, error) {
+	hold, err := s.holds.GetHold(ctx, uint(id))
+	if err != nil {
+		return nil, holdError(err, "failed to get hold")
+	}
+	allowed, err := s.ownership.CanAccess(ctx, strconv.FormatUint(uint64(hold.AccountID), 10))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get hold")
+	}
+	if !allowed {
+		return nil, status.Error(codes.NotFound, service.ErrHoldNotFound.Error())
+	}
+	return hold, nil
+}
+
+func holdError(err error, fallback string) error {
+	var rejection *rules.Rejection
+	switch {
+	case errors.As(err, &rejection):
+		return status.Error(codes.FailedPrecondition, rejection.Error())
+	case errors.Is(err, service.ErrHoldNotFound), errors.Is(err, service.ErrAccountNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrInsufficientFunds), errors.Is(err, service.ErrCurrencyMismatch),
+		errors.Is(err, service.ErrHoldNotActive), errors.Is(err, service.ErrHoldExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrInvalidAmount), errors.Is(err, service.ErrSameAccount),
+		errors.Is(err, service.ErrCaptureTooHigh), errors.Is(err, service.ErrInvalidTTL):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, fallback)
+}
+
+func toAccount(a *models.Account) *accountv1.Account {
+	return &accountv1.Account{
+		Id:               uint64(a.ID),
+		Owner:            a.Owner,
+		Balance:          a.Balance,
+		HeldBalance:      a.HeldBalance,
+		AvailableBalance: a.Available(),
+		Currency:         a.Currency,
+		OverdraftLimit:   a.OverdraftLimit,
+		OverdraftUsed:    a.Overdrawn(),
+		AvailableCredit:  a.Credit(),
+		CreatedAt:        timestamppb.New(a.CreatedAt),
+		UpdatedAt:        timestamppb.New(a.UpdatedAt),
+	}
+}
+
+func toHold(h *models.Hold) *accountv1.Hold {
+	return &accountv1.Hold{
+		Id:                 uint64(h.ID),
+		AccountId:          uint64(h.AccountID),
+		DestinationAccount: uint64(h.DestinationAccount),
+		Amount:             h.Amount,
+		CapturedAmount:     h.CapturedAmount,
+		Currency:           h.Currency,
+		Description:        h.Description,
+		Status:             string(h.Status),
+		ExpiresAt:          timestamppb.New(h.ExpiresAt),
+		CreatedAt:          timestamppb.New(h.CreatedAt),
+	}
+}
@@ -0,0 +1,191 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+	"txsystem/pkg/common/logging"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var logger = logging.For("rules")
+
+// reloadDelay lets a burst of change events settle before the file is read.
+const reloadDelay = 250 * time.Millisecond
+
+// Transfer is the part of a transaction the rules look at.
+type Transfer struct {
+	ID                 uint
+	Amount             float64
+	SourceAccount      string
+	DestinationAccount string
+}
+
+// Engine evaluates transfers against the current rules. The rules are
+// swapped atomically on reload, so evaluation never blocks on the file.
+type Engine struct {
+	path  string
+	usage Usage
+	rules atomic.Pointer[Rules]
+	now   func() time.Time
+}
+
+// NewEngine loads the rules at path. An empty path allows every transfer.
+func NewEngine(path string, usage Usage) (*Engine, error) {
+	e := &Engine{path: path, usage: usage, now: time.Now}
+	e.rules.Store(&Rules{})
+	if path == "" {
+		return e, nil
+	}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Rules returns the rules currently in force.
+func (e *Engine) Rules() *Rules {
+	return e.rules.Load()
+}
+
+// Reload re-reads the rules file. The previous rules stay in force when the
+// new file is invalid.
+func (e *Engine) Reload() error {
+	r, err := Load(e.path)
+	if err != nil {
+		return err
+	}
+	e.rules.Store(r)
+	logger.Info("transfer rules loaded", "path", e.path)
+	return nil
+}
+
+// Watch reloads the rules whenever the file changes, until ctx is cancelled.
+// The directory is watched rather than the file so editors that replace the
+// file and mounted ConfigMaps, which swap a symlink, are both picked up.
+func (e *Engine) Watch(ctx context.Context) error {
+	if e.path == "" {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch rules file: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(e.path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch rules file: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+					timer.Reset(reloadDelay)
+				}
+			case <-timer.C:
+				if err := e.Reload(); err != nil {
+					logger.Error("failed to reload transfer rules, keeping previous rules", "error", err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("rules watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Evaluate returns a *Rejection when t breaks a rule, or another error when
+// the usage needed to decide could not be read.
+func (e *Engine) Evaluate(ctx context.Context, t Transfer) error {
+	r := e.rules.Load()
+	now := e.now().UTC()
+
+	if slices.Contains(r.Blocked.Accounts, t.SourceAccount) || slices.Contains(r.Blocked.Accounts, t.DestinationAccount) {
+		return reject(ReasonBlockedCounterparty, "account is blocked")
+	}
+
+	owner, err := e.usage.Owner(ctx, t.SourceAccount)
+	if err != nil {
+		return err
+	}
+	if len(r.Blocked.Owners) > 0 {
+		counterparty, err := e.usage.Owner(ctx, t.DestinationAccount)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(r.Blocked.Owners, owner) || slices.Contains(r.Blocked.Owners, counterparty) {
+			return reject(ReasonBlockedCounterparty, "account owner is blocked")
+		}
+	}
+
+	if r.MaxAmount > 0 && t.Amount > r.MaxAmount {
+		return reject(ReasonAmountLimit, fmt.Sprintf("amount %.2f exceeds the limit of %.2f", t.Amount, r.MaxAmount))
+	}
+
+	if r.MaxTransfersPerMinute > 0 {
+		count, err := e.usage.RecentTransfers(ctx, t.SourceAccount, now.Add(-time.Minute), t.ID)
+		if err != nil {
+			return err
+		}
+		if count >= int64(r.MaxTransfersPerMinute) {
+			return reject(ReasonVelocityLimit, fmt.Sprintf("%d transfers already sent in the last minute, limit is %d", count, r.MaxTransfersPerMinute))
+		}
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	checks := []struct {
+		limit  float64
+		since  time.Time
+		reason string
+		spent  func(since time.Time) (float64, error)
+	}{
+		{r.Account.Daily, day, ReasonAccountDailyLimit, func(since time.Time) (float64, error) {
+			return e.usage.AccountOutflow(ctx, t.SourceAccount, since)
+		}},
+		{r.Account.Monthly, month, ReasonAccountMonthlyLimit, func(since time.Time) (float64, error) {
+			return e.usage.AccountOutflow(ctx, t.SourceAccount, since)
+		}},
+		{r.Owner.Daily, day, ReasonOwnerDailyLimit, func(since time.Time) (float64, error) {
+			return e.usage.OwnerOutflow(ctx, owner, since)
+		}},
+		{r.Owner.Monthly, month, ReasonOwnerMonthlyLimit, func(since time.Time) (float64, error) {
+			return e.usage.OwnerOutflow(ctx, owner, since)
+		}},
+	}
+	for _, c := range checks {
+		if c.limit <= 0 {
+			continue
+		}
+		spent, err := c.spent(c.since)
+		if err != nil {
+			return err
+		}
+		if spent+t.Amount > c.limit {
+			return reject(c.reason, fmt.Sprintf("%.2f already sent, limit is %.2f", spent, c.limit))
+		}
+	}
+	return nil
+}
+
+func reject(reason, message string) *Rejection {
+	return &Rejection{Reason: reason, Message: message}
+}
@@ -0,0 +1,182 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeUsage answers from fixed figures and records the windows asked for.
+type fakeUsage struct {
+	owners        map[string]string
+	accountSpent  map[time.Time]float64
+	ownerSpent    map[time.Time]float64
+	recent        int64
+	recentBefore  uint
+	err           error
+	accountWindow []time.Time
+}
+
+func (u *fakeUsage) Owner(_ context.Context, accountID string) (string, error) {
+	return u.owners[accountID], u.err
+}
+
+func (u *fakeUsage) AccountOutflow(_ context.Context, _ string, since time.Time) (float64, error) {
+	u.accountWindow = append(u.accountWindow, since)
+	return u.accountSpent[since], u.err
+}
+
+func (u *fakeUsage) OwnerOutflow(_ context.Context, _ string, since time.Time) (float64, error) {
+	return u.ownerSpent[since], u.err
+}
+
+func (u *fakeUsage) RecentTransfers(_ context.Context, _ string, _ time.Time, before uint) (int64, error) {
+	u.recentBefore = before
+	return u.recent, u.err
+}
+
+var (
+	now        = time.Date(2024, 3, 15, 13, 45, 0, 0, time.UTC)
+	startOfDay = time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	startOfMon = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestEngine(r *Rules, u Usage) *Engine {
+	e := &Engine{usage: u, now: func() time.Time { return now }}
+	e.rules.Store(r)
+	return e
+}
+
+func TestEvaluate(t *testing.T) {
+	owners := map[string]string{"1": "alice", "2": "bob", "3": "mallory"}
+	transfer := Transfer{ID: 7, Amount: 100, SourceAccount: "1", DestinationAccount: "2"}
+	tests := []struct {
+		name       string
+		rules      Rules
+		usage      fakeUsage
+		transfer   Transfer
+		wantReason string
+	}{
+		{name: "no rules", transfer: transfer},
+		{name: "blocked source", rules: Rules{Blocked: Blocked{Accounts: []string{"1"}}}, transfer: transfer, wantReason: ReasonBlockedCounterparty},
+		{name: "blocked destination", rules: Rules{Blocked: Blocked{Accounts: []string{"2"}}}, transfer: transfer, wantReason: ReasonBlockedCounterparty},
+		{name: "blocked receiving owner", rules: Rules{Blocked: Blocked{Owners: []string{"mallory"}}}, transfer: Transfer{Amount: 1, SourceAccount: "1", DestinationAccount: "3"}, wantReason: ReasonBlockedCounterparty},
+		{name: "blocked owner elsewhere", rules: Rules{Blocked: Blocked{Owners: []string{"mallory"}}}, transfer: transfer},
+		{name: "at max amount", rules: Rules{MaxAmount: 100}, transfer: transfer},
+		{name: "above max amount", rules: Rules{MaxAmount: 99.99}, transfer: transfer, wantReason: ReasonAmountLimit},
+		{name: "below velocity limit", rules: Rules{MaxTransfersPerMinute: 3}, usage: fakeUsage{recent: 2}, transfer: transfer},
+		{name: "at velocity limit", rules: Rules{MaxTransfersPerMinute: 3}, usage: fakeUsage{recent: 3}, transfer: transfer, wantReason: ReasonVelocityLimit},
+		{
+			name:     "daily limit reached exactly",
+			rules:    Rules{Account: Limits{Daily: 500}},
+			usage:    fakeUsage{accountSpent: map[time.Time]float64{startOfDay: 400}},
+			transfer: transfer,
+		},
+		{
+			name:       "daily limit exceeded",
+			rules:      Rules{Account: Limits{Daily: 500}},
+			usage:      fakeUsage{accountSpent: map[time.Time]float64{startOfDay: 400.01}},
+			transfer:   transfer,
+			wantReason: ReasonAccountDailyLimit,
+		},
+		{
+			name:       "monthly limit counts from the first",
+			rules:      Rules{Account: Limits{Daily: 1000, Monthly: 2000}},
+			usage:      fakeUsage{accountSpent: map[time.Time]float64{startOfDay: 0, startOfMon: 1950}},
+			transfer:   transfer,
+			wantReason: ReasonAccountMonthlyLimit,
+		},
+		{
+			name:       "owner daily limit",
+			rules:      Rules{Owner: Limits{Daily: 150}},
+			usage:      fakeUsage{ownerSpent: map[time.Time]float64{startOfDay: 60}},
+			transfer:   transfer,
+			wantReason: ReasonOwnerDailyLimit,
+		},
+		{
+			name:       "owner monthly limit",
+			rules:      Rules{Owner: Limits{Monthly: 150}},
+			usage:      fakeUsage{ownerSpent: map[time.Time]float64{startOfMon: 60}},
+			transfer:   transfer,
+			wantReason: ReasonOwnerMonthlyLimit,
+		},
+		{
+			name:       "account limit is checked before owner limit",
+			rules:      Rules{Account: Limits{Daily: 100}, Owner: Limits{Daily: 100}},
+			usage:      fakeUsage{accountSpent: map[time.Time]float64{startOfDay: 1}, ownerSpent: map[time.Time]float64{startOfDay: 1}},
+			transfer:   transfer,
+			wantReason: ReasonAccountDailyLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.usage
+			u.owners = owners
+			err := newTestEngine(&tt.rules, &u).Evaluate(context.Background(), tt.transfer)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Evaluate = %v, want nil", err)
+				}
+				return
+			}
+			var rejection *Rejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("Evaluate = %v, want a rejection", err)
+			}
+			if rejection.Reason != tt.wantReason {
+				t.Errorf("reason = %s, want %s", rejection.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestEvaluateUsage(t *testing.T) {
+	u := &fakeUsage{owners: map[string]string{"1": "alice"}}
+	r := &Rules{Account: Limits{Daily: 1000, Monthly: 5000}, MaxTransfersPerMinute: 10}
+	if err := newTestEngine(r, u).Evaluate(context.Background(), Transfer{ID: 42, Amount: 1, SourceAccount: "1", DestinationAccount: "2"}); err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if u.recentBefore != 42 {
+		t.Errorf("RecentTransfers counted before %d, want the transfer's own ID 42", u.recentBefore)
+	}
+	if len(u.accountWindow) != 2 || !u.accountWindow[0].Equal(startOfDay) || !u.accountWindow[1].Equal(startOfMon) {
+		t.Errorf("outflow windows = %v, want %v and %v", u.accountWindow, startOfDay, startOfMon)
+	}
+
+	u = &fakeUsage{err: errors.New("db down")}
+	err := newTestEngine(r, u).Evaluate(context.Background(), Transfer{Amount: 1, SourceAccount: "1"})
+	var rejection *Rejection
+	if err == nil || errors.As(err, &rejection) {
+		t.Errorf("Evaluate = %v, want the usage error, not a rejection", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "valid", yaml: "max_amount: 10000\naccount: {daily: 5000, monthly: 20000}\nmax_transfers_per_minute: 5\nblocked: {owners: [mallory]}\n"},
+		{name: "empty", yaml: "", wantErr: "empty"},
+		{name: "unknown key", yaml: "max_ammount: 10\n", wantErr: "max_ammount"},
+		{name: "negative limit", yaml: "owner: {monthly: -1}\n", wantErr: "owner.monthly must not be negative"},
+		{name: "negative velocity", yaml: "max_transfers_per_minute: -1\n", wantErr: "max_transfers_per_minute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
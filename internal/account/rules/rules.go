@@ -0,0 +1,96 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reason codes recorded on transactions rejected before settlement.
+const (
+	ReasonAmountLimit         = "amount_limit_exceeded"
+	ReasonAccountDailyLimit   = "account_daily_limit_exceeded"
+	ReasonAccountMonthlyLimit = "account_monthly_limit_exceeded"
+	ReasonOwnerDailyLimit     = "owner_daily_limit_exceeded"
+	ReasonOwnerMonthlyLimit   = "owner_monthly_limit_exceeded"
+	ReasonVelocityLimit       = "velocity_limit_exceeded"
+	ReasonBlockedCounterparty = "counterparty_blocked"
+)
+
+// Rules is the content of the rules file. A zero limit is disabled.
+type Rules struct {
+	MaxAmount             float64 `yaml:"max_amount"`
+	Account               Limits  `yaml:"account"`
+	Owner                 Limits  `yaml:"owner"`
+	MaxTransfersPerMinute int     `yaml:"max_transfers_per_minute"`
+	Blocked               Blocked `yaml:"blocked"`
+}
+
+// Limits caps the outgoing volume over a calendar day and month, in UTC.
+type Limits struct {
+	Daily   float64 `yaml:"daily"`
+	Monthly float64 `yaml:"monthly"`
+}
+
+// Blocked lists counterparties that may neither send nor receive transfers.
+type Blocked struct {
+	Accounts []string `yaml:"accounts"`
+	Owners   []string `yaml:"owners"`
+}
+
+// Rejection is returned by Engine.Evaluate when a transfer breaks a rule.
+type Rejection struct {
+	Reason  string
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return r.Reason + ": " + r.Message
+}
+
+// Load reads and validates a rules file.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Parse decodes rules from YAML. Unknown keys and empty documents are
+// rejected so a typo or a half-written file cannot silently disable limits.
+func Parse(data []byte) (*Rules, error) {
+	var r Rules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&r); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("rules file is empty")
+		}
+		return nil, err
+	}
+
+	for name, v := range map[string]float64{
+		"max_amount":      r.MaxAmount,
+		"account.daily":   r.Account.Daily,
+		"account.monthly": r.Account.Monthly,
+		"owner.daily":     r.Owner.Daily,
+		"owner.monthly":   r.Owner.Monthly,
+	} {
+		if v < 0 {
+			return nil, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if r.MaxTransfersPerMinute < 0 {
+		return nil, fmt.Errorf("max_transfers_per_minute must not be negative")
+	}
+	return &r, nil
+}
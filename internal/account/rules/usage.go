@@ -0,0 +1,129 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"txsystem/internal/account/models"
+	"txsystem/pkg/common/types"
+
+	"gorm.io/gorm"
+)
+
+// Usage answers the questions the rules ask about past transfers.
+type Usage interface {
+	// Owner returns the owner of accountID, or "" when it does not exist.
+	Owner(ctx context.Context, accountID string) (string, error)
+	// AccountOutflow sums transfers out of accountID whose balance move was
+	// applied since the given time and not reversed, settled or not, and the
+	// holds placed on it since then that are still active, which will move
+	// money when captured.
+	AccountOutflow(ctx context.Context, accountID string, since time.Time) (float64, error)
+	// OwnerOutflow sums the same transfers out of every account of owner.
+	OwnerOutflow(ctx context.Context, owner string, since time.Time) (float64, error)
+	// RecentTransfers counts transfers out of accountID created since the
+	// given time and queued ahead of transaction before.
+	RecentTransfers(ctx context.Context, accountID string, since time.Time, before uint) (int64, error)
+}
+
+type dbUsage struct {
+	db *gorm.DB
+}
+
+// NewUsage reads usage from the shared accounts, transactions,
+// transfer_records and holds tables.
+func NewUsage(db *gorm.DB) Usage {
+	return &dbUsage{db: db}
+}
+
+// accountKey parses an account ID for the integer key columns. Ledger
+// accounts, such as gl-1000, are not in the accounts table.
+func accountKey(accountID string) (uint64, bool) {
+	id, err := strconv.ParseUint(accountID, 10, 63)
+	return id, err == nil
+}
+
+func (u *dbUsage) Owner(ctx context.Context, accountID string) (string, error) {
+	id, ok := accountKey(accountID)
+	if !ok {
+		return "", nil
+	}
+	var owners []string
+	err := u.db.WithContext(ctx).
+		Table("accounts").
+		Where("id = ?", id).
+		Pluck("owner", &owners).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to look up account owner: %w", err)
+	}
+	if len(owners) == 0 {
+		return "", nil
+	}
+	return owners[0], nil
+}
+
+func (u *dbUsage) AccountOutflow(ctx context.Context, accountID string, since time.Time) (float64, error) {
+	id, ok := accountKey(accountID)
+	if !ok {
+		return 0, nil
+	}
+	var total float64
+	err := u.db.WithContext(ctx).
+		Table("transfer_records").
+		Where("source_account = ? AND status = ? AND created_at >= ?", id, models.TransferApplied, since).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum account outflow: %w", err)
+	}
+	var held float64
+	err = u.db.WithContext(ctx).
+		Model(&models.Hold{}).
+		Where("account_id = ? AND status = ? AND created_at >= ?", id, models.HoldActive, since).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&held).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum account holds: %w", err)
+	}
+	return total + held, nil
+}
+
+func (u *dbUsage) OwnerOutflow(ctx context.Context, owner string, since time.Time) (float64, error) {
+	if owner == "" {
+		return 0, nil
+	}
+	var total float64
+	err := u.db.WithContext(ctx).
+		Table("transfer_records").
+		Joins("JOIN accounts ON accounts.id = transfer_records.source_account").
+		Where("accounts.owner = ? AND transfer_records.status = ? AND transfer_records.created_at >= ?", owner, models.TransferApplied, since).
+		Select("COALESCE(SUM(transfer_records.amount), 0)").
+		Scan(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum owner outflow: %w", err)
+	}
+	var held float64
+	err = u.db.WithContext(ctx).
+		Model(&models.Hold{}).
+		Joins("JOIN accounts ON accounts.id = holds.account_id").
+		Where("accounts.owner = ? AND holds.status = ? AND holds.created_at >= ?", owner, models.HoldActive, since).
+		Select("COALESCE(SUM(holds.amount), 0)").
+		Scan(&held).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum owner holds: %w", err)
+	}
+	return total + held, nil
+}
+
+func (u *dbUsage) RecentTransfers(ctx context.Context, accountID string, since time.Time, before uint) (int64, error) {
+	var count int64
+	err := u.db.WithContext(ctx).
+		Table("transactions").
+		Where("source_account = ? AND created_at >= ? AND id < ? AND status <> ?", accountID, since, before, types.StatusFailed).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recent transfers: %w", err)
+	}
+	return count, nil
+}
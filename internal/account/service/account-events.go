@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"txsystem/internal/account/models"
+
+	"gorm.io/gorm"
+)
+
+// snapshotInterval is how many events an account gets between snapshots.
+const snapshotInterval = 100
+
+// appendEvents applies events to the locked account, then stores them with
+// the updated projection, and a snapshot when the account crosses a
+// multiple of snapshotInterval. The unique (account_id, version) index
+// rejects a writer that skipped the lock.
+func appendEvents(tx *gorm.DB, account *models.Account, events ...*models.AccountEvent) error {
+	before := account.Version
+	for _, e := range events {
+		e.AccountID = account.ID
+		e.Version = account.Version + 1
+		account.Apply(e)
+	}
+	if err := tx.Create(events).Error; err != nil {
+		return fmt.Errorf("failed to store account events: %w", err)
+	}
+	if err := tx.Save(account).Error; err != nil {
+		return fmt.Errorf("failed to update account: %w", err)
+	}
+	if account.Version/snapshotInterval > before/snapshotInterval {
+		if err := tx.Create(account.Snapshot()).Error; err != nil {
+			return fmt.Errorf("failed to store account snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+func event(t models.AccountEventType, amount float64) *models.AccountEvent {
+	return &models.AccountEvent{Type: t, Amount: amount}
+}
+
+func ref(id uint) *uint {
+	return &id
+}
+
+// LoadAccount derives the account from its latest snapshot and the events
+// after it, without reading the accounts projection.
+func (as *AccountService) LoadAccount(ctx context.Context, id uint) (*models.Account, error) {
+	db := as.db.WithContext(ctx)
+	account := &models.Account{ID: id}
+
+	var snapshot models.AccountSnapshot
+	found := db.Where("account_id = ?", id).Order("version DESC").Limit(1).Find(&snapshot)
+	if found.Error != nil {
+		return nil, fmt.Errorf("failed to load account snapshot: %w", found.Error)
+	}
+	if found.RowsAffected == 1 {
+		account.Owner = snapshot.Owner
+		account.Currency = snapshot.Currency
+		account.Balance = snapshot.Balance
+		account.HeldBalance = snapshot.HeldBalance
+		account.Version = snapshot.Version
+	}
+
+	var events []models.AccountEvent
+	if err := db.Where("account_id = ? AND version > ?", id, account.Version).Order("version").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to load account events: %w", err)
+	}
+	if account.Version == 0 && len(events) == 0 {
+		return nil, ErrAccountNotFound
+	}
+	for i := range events {
+		if err := replay(account, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return account, nil
+}
+
+// Events returns up to limit events of the account after version, oldest
+// first.
+func (as *AccountService) Events(ctx context.Context, id uint, after int64, limit int) ([]models.AccountEvent, error) {
+	var events []models.AccountEvent
+	err := as.db.WithContext(ctx).
+		Where("account_id = ? AND version > ?", id, after).
+		Order("version").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account events: %w", err)
+	}
+	return events, nil
+}
+
+// RebuildResult compares an account projection and its snapshots with a
+// replay of every event.
+type RebuildResult struct {
+	AccountID  uint
+	Projection models.Account
+	Replayed   models.Account
+	// BadSnapshots are the versions whose snapshot differs from the replay.
+	BadSnapshots []int64
+}
+
+func (r *RebuildResult) Consistent() bool {
+	return sameState(&r.Projection, &r.Replayed) && len(r.BadSnapshots) == 0
+}
+
+// Rebuild replays every event of the account from the start and compares
+// the result with the projection and each snapshot. With write set it
+// overwrites a differing projection and deletes differing snapshots. The
+// account stays locked meanwhile, so no events are appended.
+func (as *AccountService) Rebuild(ctx context.Context, id uint, write bool) (*RebuildResult, error) {
+	result := &RebuildResult{AccountID: id}
+	err := as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockAccount(tx, id, &result.Projection); err != nil {
+			return err
+		}
+
+		var snapshots []models.AccountSnapshot
+		if err := tx.Where("account_id = ?", id).Order("version").Find(&snapshots).Error; err != nil {
+			return fmt.Errorf("failed to load account snapshots: %w", err)
+		}
+		var events []models.AccountEvent
+		if err := tx.Where("account_id = ?", id).Order("version").Find(&events).Error; err != nil {
+			return fmt.Errorf("failed to load account events: %w", err)
+		}
+
+		replayed := &result.Replayed
+		replayed.ID = id
+		for i := range events {
+			if err := replay(replayed, &events[i]); err != nil {
+				return err
+			}
+			for len(snapshots) > 0 && snapshots[0].Version <= replayed.Version {
+				if s := snapshots[0]; s.Version < replayed.Version || !sameState(snapshotAccount(&s), replayed) {
+					result.BadSnapshots = append(result.BadSnapshots, s.Version)
+				}
+				snapshots = snapshots[1:]
+			}
+		}
+		// Snapshots past the last event describe changes that never happened.
+		for _, s := range snapshots {
+			result.BadSnapshots = append(result.BadSnapshots, s.Version)
+		}
+
+		if !write {
+			return nil
+		}
+		if len(result.BadSnapshots) > 0 {
+			if err := tx.Where("account_id = ? AND version IN ?", id, result.BadSnapshots).Delete(&models.AccountSnapshot{}).Error; err != nil {
+				return fmt.Errorf("failed to delete account snapshots: %w", err)
+			}
+		}
+		if sameState(&result.Projection, replayed) {
+			return nil
+		}
+		err := tx.Model(&models.Account{}).Where("id = ?", id).Updates(map[string]any{
+			"owner":        replayed.Owner,
+			"currency":     replayed.Currency,
+			"balance":      replayed.Balance,
+			"held_balance": replayed.HeldBalance,
+			"version":      replayed.Version,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// replay applies an event read from the store, refusing gaps so a missing
+// event is not mistaken for a balance.
+func replay(account *models.Account, e *models.AccountEvent) error {
+	if e.Version != account.Version+1 {
+		return fmt.Errorf("account %d: event version %d follows version %d", account.ID, e.Version, account.Version)
+	}
+	account.Apply(e)
+	return nil
+}
+
+func snapshotAccount(s *models.AccountSnapshot) *models.Account {
+	return &models.Account{
+		ID:          s.AccountID,
+		Owner:       s.Owner,
+		Currency:    s.Currency,
+		Balance:     s.Balance,
+		HeldBalance: s.HeldBalance,
+		Version:     s.Version,
+	}
+}
+
+func sameState(a, b *models.Account) bool {
+	return a.Owner == b.Owner &&
+		a.Currency == b.Currency &&
+		a.Balance == b.Balance &&
+		a.HeldBalance == b.HeldBalance &&
+		a.Version == b.Version
+}
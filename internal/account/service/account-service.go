@@ -2,29 +2,109 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"txsystem/internal/account/models"
+	ledgermodels "txsystem/internal/ledger/models"
+	txmodels "txsystem/internal/transaction/models"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Errors returned by ApplyTransfer for transfers that can never succeed, as
+// opposed to failures worth retrying.
+var (
+	ErrInvalidAmount     = errors.New("transfer amount must be positive")
+	ErrSameAccount       = errors.New("source and destination accounts cannot be the same")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrInsufficientFunds = errors.New("insufficient balance in source account")
+	ErrTransferReversed  = errors.New("transfer was reversed")
+	ErrInvalidOverdraft  = errors.New("overdraft limit and fee must not be negative")
+	ErrCurrencyMismatch  = errors.New("source and destination accounts use different currencies")
+)
+
+// Reason codes for transfers that fail during settlement. Rule rejections
+// use the codes from the rules package.
+const (
+	ReasonInvalidAmount     = "invalid_amount"
+	ReasonSameAccount       = "same_account"
+	ReasonAccountNotFound   = "account_not_found"
+	ReasonInsufficientFunds = "insufficient_funds"
+	ReasonCurrencyMismatch  = "currency_mismatch"
 )
 
 type AccountService struct {
 	db *gorm.DB
+	// kc publishes opening balances; it may be nil where no accounts are
+	// opened.
+	kc types.ProducerConnection
 }
 
-func NewAccountService(db *gorm.DB) *AccountService {
-	return &AccountService{db: db}
+func NewAccountService(db *gorm.DB, kc types.ProducerConnection) *AccountService {
+	return &AccountService{db: db, kc: kc}
 }
 
+// CreateAccount opens an account. An initial balance is recorded as a
+// completed opening_balance transaction from external clearing and published
+// so the ledger posts it like a capture.
 func (as *AccountService) CreateAccount(ctx context.Context, owner, currency string, initialBalance float64) (*models.Account, error) {
-	account := &models.Account{
-		Owner:    owner,
-		Currency: currency,
-		Balance:  initialBalance,
-	}
-	if err := as.db.WithContext(ctx).Create(account).Error; err != nil {
+	account := &models.Account{}
+	var record *txmodels.Transaction
+	err := as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		opened := event(models.AccountOpened, initialBalance)
+		opened.Owner = owner
+		opened.Currency = currency
+		if initialBalance <= 0 {
+			return appendEvents(tx, account, opened)
+		}
+
+		destination := strconv.FormatUint(uint64(account.ID), 10)
+		record = &txmodels.Transaction{
+			Amount:             initialBalance,
+			Currency:           currency,
+			Description:        "Opening balance",
+			SourceAccount:      ledgermodels.SystemAccountID(ledgermodels.AccountExternalClearing),
+			DestinationAccount: destination,
+			TransactionType:    "opening_balance",
+			Status:             types.StatusCompleted,
+			TransactionID:      "opening-" + destination,
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to record opening balance: %w", err)
+		}
+		if err := enqueue(tx, record); err != nil {
+			return err
+		}
+		opened.TransactionID = ref(record.ID)
+		if err := appendEvents(tx, account, opened); err != nil {
+			return err
+		}
+		// As with interest, the source is outside the accounts table, so a
+		// compensating saga only takes the balance back from the account.
+		applied := models.TransferRecord{
+			TransactionID:      record.ID,
+			DestinationAccount: account.ID,
+			Amount:             initialBalance,
+			Status:             models.TransferApplied,
+		}
+		if err := tx.Create(&applied).Error; err != nil {
+			return fmt.Errorf("failed to record opening balance transfer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
+	if record != nil {
+		publishTransaction(ctx, as.db, as.kc, record)
+	}
 	return account, nil
 }
 
@@ -36,60 +116,302 @@ func (as *AccountService) GetAccount(ctx context.Context, id int) (*models.Accou
 	return &account, nil
 }
 
-func (as *AccountService) TransferBalance(ctx context.Context, fromID, toID uint, amount float64) error {
-	if amount <= 0 {
-		return fmt.Errorf("transfer amount must be positive")
+// ApplyTransfer moves amount for the transaction once and charges the fee to
+// the source account, plus its overdraft fee when the transfer draws on the
+// overdraft. Repeating it returns the first outcome without moving anything
+// again: nil once applied, a *RejectedError once rejected, and
+// ErrTransferReversed when a reversal got there first. Rejections are
+// recorded; other errors leave no trace so the call can be retried.
+func (as *AccountService) ApplyTransfer(ctx context.Context, transactionID, fromID, toID uint, amount, fee float64) error {
+	var outcome error
+	replayed := false
+	currency := "unknown"
+	drawn := 0.0
+	err := as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := models.TransferRecord{
+			TransactionID:      transactionID,
+			SourceAccount:      fromID,
+			DestinationAccount: toID,
+			Amount:             amount,
+			Fee:                fee,
+			Status:             models.TransferApplied,
+		}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if created.Error != nil {
+			return fmt.Errorf("failed to record transfer: %w", created.Error)
+		}
+		if created.RowsAffected == 0 {
+			replayed = true
+			outcome = recordedOutcome(tx, transactionID)
+			return nil
+		}
+
+		var err error
+		currency, err = transferBalance(tx, &record)
+		if reason := RejectionReason(err); reason != "" {
+			outcome = err
+			return tx.Model(&record).Updates(map[string]any{"status": models.TransferRejected, "reason": reason}).Error
+		}
+		if err != nil || record.OverdraftDrawn == 0 {
+			return err
+		}
+		drawn = record.OverdraftDrawn
+		return tx.Model(&record).Updates(map[string]any{
+			"overdraft_drawn": record.OverdraftDrawn,
+			"overdraft_fee":   record.OverdraftFee,
+		}).Error
+	})
+	if replayed {
+		return outcome
+	}
+	if err != nil || outcome != nil {
+		metrics.TransfersFailed.WithLabelValues(currency).Inc()
+		if err != nil {
+			return err
+		}
+		return outcome
 	}
+	metrics.TransfersSettled.WithLabelValues(currency).Inc()
+	metrics.TransferVolume.WithLabelValues(currency).Add(amount)
+	if drawn > 0 {
+		metrics.OverdraftDrawn.WithLabelValues(currency).Add(drawn)
+	}
+	return nil
+}
 
-	if fromID == toID {
-		return fmt.Errorf("source and destination accounts cannot be the same")
+// OverdraftFee returns the overdraft fee charged for the transaction's
+// transfer, 0 when there was none.
+func (as *AccountService) OverdraftFee(ctx context.Context, transactionID uint) (float64, error) {
+	var record models.TransferRecord
+	found := as.db.WithContext(ctx).Limit(1).Find(&record, transactionID)
+	if found.Error != nil {
+		return 0, fmt.Errorf("failed to load transfer record: %w", found.Error)
 	}
+	return record.OverdraftFee, nil
+}
 
-	tx := as.db.WithContext(ctx).Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
+// SetOverdraft changes the overdraft limit and fee of the account. Lowering
+// the limit below what is already used only stops further drawing.
+func (as *AccountService) SetOverdraft(ctx context.Context, id uint, limit, fee float64) (*models.Account, error) {
+	if limit < 0 || fee < 0 {
+		return nil, ErrInvalidOverdraft
+	}
+	var account models.Account
+	err := as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockAccount(tx, id, &account); err != nil {
+			return err
+		}
+		if err := tx.Model(&account).Updates(map[string]any{"overdraft_limit": limit, "overdraft_fee": fee}).Error; err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		return tx.First(&account, id).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &account, nil
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// RejectTransfer records that the transaction must never move money, unless
+// an outcome was recorded already, and returns the recorded outcome like
+// ApplyTransfer.
+func (as *AccountService) RejectTransfer(ctx context.Context, transactionID uint, reason string) error {
+	var outcome error
+	err := as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := models.TransferRecord{TransactionID: transactionID, Status: models.TransferRejected, Reason: reason}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
 		}
-	}()
+		outcome = recordedOutcome(tx, transactionID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return outcome
+}
 
-	var fromAccount models.Account
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&fromAccount, fromID).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to get source account: %w", err)
+// ReverseTransfer moves the applied amount back and refunds the fees. It
+// never fails for lack of funds: a compensation has to go through. Without
+// an applied move it leaves a tombstone so a late ApplyTransfer does
+// nothing.
+func (as *AccountService) ReverseTransfer(ctx context.Context, transactionID uint) error {
+	return as.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tombstone := models.TransferRecord{TransactionID: transactionID, Status: models.TransferReversed}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tombstone)
+		if created.Error != nil {
+			return fmt.Errorf("failed to record reversal: %w", created.Error)
+		}
+		if created.RowsAffected == 1 {
+			return nil
+		}
+
+		var record models.TransferRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, transactionID).Error; err != nil {
+			return fmt.Errorf("failed to load transfer record: %w", err)
+		}
+		if record.Status != models.TransferApplied {
+			return nil
+		}
+		if err := moveBalance(tx, transactionID, record.DestinationAccount, record.SourceAccount, record.Amount); err != nil {
+			return fmt.Errorf("failed to reverse transfer: %w", err)
+		}
+		if err := adjustFee(tx, transactionID, record.SourceAccount, models.FeeRefunded, record.Fee+record.OverdraftFee); err != nil {
+			return fmt.Errorf("failed to refund fee: %w", err)
+		}
+		return tx.Model(&record).Update("status", models.TransferReversed).Error
+	})
+}
+
+// recordedOutcome maps an existing transfer record to ApplyTransfer's result.
+func recordedOutcome(tx *gorm.DB, transactionID uint) error {
+	var record models.TransferRecord
+	if err := tx.First(&record, transactionID).Error; err != nil {
+		return fmt.Errorf("failed to load transfer record: %w", err)
+	}
+	switch record.Status {
+	case models.TransferRejected:
+		return &RejectedError{Reason: record.Reason}
+	case models.TransferReversed:
+		return ErrTransferReversed
+	}
+	return nil
+}
+
+// RejectedError is a rejection recorded by an earlier attempt.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "transfer was rejected: " + e.Reason
+}
+
+// RejectionReason returns the reason code for errors that mean the transfer
+// can never succeed, and "" for any other error.
+func RejectionReason(err error) string {
+	var rejected *RejectedError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &rejected):
+		return rejected.Reason
+	case errors.Is(err, ErrInvalidAmount):
+		return ReasonInvalidAmount
+	case errors.Is(err, ErrSameAccount):
+		return ReasonSameAccount
+	case errors.Is(err, ErrAccountNotFound):
+		return ReasonAccountNotFound
+	case errors.Is(err, ErrInsufficientFunds):
+		return ReasonInsufficientFunds
+	case errors.Is(err, ErrCurrencyMismatch):
+		return ReasonCurrencyMismatch
 	}
+	return ""
+}
 
-	// Check for sufficient balance
-	if fromAccount.Balance < amount {
-		tx.Rollback()
-		return fmt.Errorf("insufficient balance in source account")
+// transferBalance moves the record's amount between its accounts within
+// tx, charges its fee to the source and returns the source account
+// currency, or "unknown" when it could not be loaded. A transfer that takes
+// the source further below zero is charged the account's overdraft fee too;
+// both are set on the record. Accounts in different currencies are refused,
+// whatever the transaction service checked before.
+func transferBalance(tx *gorm.DB, record *models.TransferRecord) (string, error) {
+	currency := "unknown"
+	transactionID, fromID, toID := record.TransactionID, record.SourceAccount, record.DestinationAccount
+	if record.Amount <= 0 || record.Fee < 0 {
+		return currency, ErrInvalidAmount
+	}
+	if fromID == toID {
+		return currency, ErrSameAccount
 	}
 
+	var fromAccount models.Account
+	if err := lockAccount(tx, fromID, &fromAccount); err != nil {
+		return currency, fmt.Errorf("failed to get source account: %w", err)
+	}
+	currency = fromAccount.Currency
 	var toAccount models.Account
-	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&toAccount, toID).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to get destination account: %w", err)
+	if err := lockAccount(tx, toID, &toAccount); err != nil {
+		return currency, fmt.Errorf("failed to get destination account: %w", err)
+	}
+	if toAccount.Currency != fromAccount.Currency {
+		return currency, ErrCurrencyMismatch
 	}
 
-	fromAccount.Balance -= amount
-	if err := tx.Save(&fromAccount).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update source account: %w", err)
+	total := record.Amount + record.Fee
+	if drawn := max(0, total-fromAccount.Balance) - fromAccount.Overdrawn(); drawn > 0 {
+		record.OverdraftDrawn = drawn
+		record.OverdraftFee = fromAccount.OverdraftFee
+		total += fromAccount.OverdraftFee
+	}
+	// Funds reserved by holds cannot be spent; the overdraft limit can.
+	if fromAccount.Available() < total {
+		return currency, ErrInsufficientFunds
+	}
+	if err := moveBalance(tx, transactionID, fromID, toID, record.Amount); err != nil {
+		return currency, err
+	}
+	if err := adjustFee(tx, transactionID, fromID, models.FeeCharged, record.Fee); err != nil {
+		return currency, fmt.Errorf("failed to charge fee: %w", err)
 	}
+	if err := adjustFee(tx, transactionID, fromID, models.OverdraftFeeCharged, record.OverdraftFee); err != nil {
+		return currency, fmt.Errorf("failed to charge overdraft fee: %w", err)
+	}
+	return currency, nil
+}
 
-	toAccount.Balance += amount
-	if err := tx.Save(&toAccount).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update destination account: %w", err)
+// adjustFee charges or refunds a fee on the account, doing nothing for a
+// transfer without one.
+func adjustFee(tx *gorm.DB, transactionID, accountID uint, t models.AccountEventType, fee float64) error {
+	if fee == 0 {
+		return nil
 	}
+	var account models.Account
+	if err := lockAccount(tx, accountID, &account); err != nil {
+		return err
+	}
+	e := event(t, fee)
+	e.TransactionID = ref(transactionID)
+	return appendEvents(tx, &account, e)
+}
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// moveBalance moves amount for the transaction without checking funds. An
+// account ID of 0 stands for a ledger account outside the accounts table,
+// such as interest expense, and only the other side moves.
+func moveBalance(tx *gorm.DB, transactionID, fromID, toID uint, amount float64) error {
+	var fromAccount, toAccount models.Account
+	if fromID != 0 {
+		if err := lockAccount(tx, fromID, &fromAccount); err != nil {
+			return fmt.Errorf("failed to get source account: %w", err)
+		}
+	}
+	if toID != 0 {
+		if err := lockAccount(tx, toID, &toAccount); err != nil {
+			return fmt.Errorf("failed to get destination account: %w", err)
+		}
 	}
 
+	if fromID != 0 {
+		debit := event(models.AccountDebited, amount)
+		debit.TransactionID = ref(transactionID)
+		if err := appendEvents(tx, &fromAccount, debit); err != nil {
+			return fmt.Errorf("failed to update source account: %w", err)
+		}
+	}
+	if toID != 0 {
+		credit := event(models.AccountCredited, amount)
+		credit.TransactionID = ref(transactionID)
+		if err := appendEvents(tx, &toAccount, credit); err != nil {
+			return fmt.Errorf("failed to update destination account: %w", err)
+		}
+	}
 	return nil
 }
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"txsystem/internal/account/models"
+)
+
+func TestReplay(t *testing.T) {
+	opened := &models.AccountEvent{Type: models.AccountOpened, Owner: "alice", Currency: "EUR", Amount: 100}
+	tests := []struct {
+		name          string
+		overdraft     float64
+		events        []*models.AccountEvent
+		wantBalance   float64
+		wantHeld      float64
+		wantAvailable float64
+		wantUsed      float64
+		wantCredit    float64
+	}{
+		{
+			name:        "opening balance",
+			events:      []*models.AccountEvent{opened},
+			wantBalance: 100, wantAvailable: 100,
+		},
+		{
+			name: "transfer with fee and refund",
+			events: []*models.AccountEvent{
+				opened,
+				event(models.AccountDebited, 30),
+				event(models.FeeCharged, 1),
+				event(models.AccountCredited, 5),
+				event(models.FeeRefunded, 1),
+				event(models.InterestPosted, 0.5),
+			},
+			wantBalance: 75.5, wantAvailable: 75.5,
+		},
+		{
+			name: "hold placed and partly released",
+			events: []*models.AccountEvent{
+				opened,
+				event(models.HoldPlaced, 40),
+				event(models.HoldPlaced, 10),
+				event(models.HoldReleased, 40),
+			},
+			wantBalance: 100, wantHeld: 10, wantAvailable: 90,
+		},
+		{
+			name:      "into the overdraft",
+			overdraft: 50,
+			events: []*models.AccountEvent{
+				opened,
+				event(models.AccountDebited, 120),
+				event(models.OverdraftFeeCharged, 2),
+				event(models.HoldPlaced, 10),
+			},
+			wantBalance: -22, wantHeld: 10, wantAvailable: 18, wantUsed: 22, wantCredit: 18,
+		},
+		{
+			name:      "holds reaching into the overdraft",
+			overdraft: 50,
+			events: []*models.AccountEvent{
+				opened,
+				event(models.HoldPlaced, 120),
+			},
+			wantBalance: 100, wantHeld: 120, wantAvailable: 30, wantCredit: 30,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &models.Account{ID: 7, OverdraftLimit: tt.overdraft}
+			for i, e := range tt.events {
+				e := *e
+				e.Version = int64(i + 1)
+				if err := replay(account, &e); err != nil {
+					t.Fatalf("replay event %d: %v", i+1, err)
+				}
+			}
+			if account.Owner != "alice" || account.Currency != "EUR" || account.Version != int64(len(tt.events)) {
+				t.Errorf("account = %s/%s at version %d, want alice/EUR at %d", account.Owner, account.Currency, account.Version, len(tt.events))
+			}
+			if account.Balance != tt.wantBalance || account.HeldBalance != tt.wantHeld {
+				t.Errorf("balance = %v held %v, want %v held %v", account.Balance, account.HeldBalance, tt.wantBalance, tt.wantHeld)
+			}
+			if account.AvailableBalance != tt.wantAvailable || account.OverdraftUsed != tt.wantUsed || account.AvailableCredit != tt.wantCredit {
+				t.Errorf("available %v, overdraft used %v, credit %v; want %v, %v, %v",
+					account.AvailableBalance, account.OverdraftUsed, account.AvailableCredit, tt.wantAvailable, tt.wantUsed, tt.wantCredit)
+			}
+
+			// A snapshot taken now and the account it restores describe the
+			// same state.
+			if restored := snapshotAccount(account.Snapshot()); !sameState(restored, account) {
+				t.Errorf("snapshot restores %+v, want %+v", restored, account)
+			}
+		})
+	}
+}
+
+func TestReplayRefusesGaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int64
+		wantErr bool
+	}{
+		{name: "next version", version: 4},
+		{name: "gap", version: 5, wantErr: true},
+		{name: "repeated", version: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &models.Account{ID: 7, Balance: 10, Version: 3}
+			e := event(models.AccountCredited, 5)
+			e.Version = tt.version
+			err := replay(account, e)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replay version %d = %v, want error %v", tt.version, err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "follows version 3") {
+					t.Errorf("error = %v, want it to name the versions", err)
+				}
+				if account.Balance != 10 || account.Version != 3 {
+					t.Errorf("refused event changed the account to %v at version %d", account.Balance, account.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestRebuildResultConsistent(t *testing.T) {
+	state := models.Account{ID: 7, Owner: "alice", Currency: "EUR", Balance: 10, HeldBalance: 2, Version: 4}
+	tests := []struct {
+		name   string
+		change func(r *RebuildResult)
+		want   bool
+	}{
+		{name: "same", change: func(*RebuildResult) {}, want: true},
+		{name: "derived fields are ignored", change: func(r *RebuildResult) { r.Projection.AvailableBalance = 99 }, want: true},
+		{name: "overdraft terms are ignored", change: func(r *RebuildResult) { r.Projection.OverdraftLimit = 50 }, want: true},
+		{name: "balance drifted", change: func(r *RebuildResult) { r.Projection.Balance = 11 }},
+		{name: "held balance drifted", change: func(r *RebuildResult) { r.Projection.HeldBalance = 0 }},
+		{name: "projection behind", change: func(r *RebuildResult) { r.Projection.Version = 3 }},
+		{name: "bad snapshot", change: func(r *RebuildResult) { r.BadSnapshots = []int64{100} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &RebuildResult{AccountID: 7, Projection: state, Replayed: state}
+			tt.change(r)
+			if got := r.Consistent(); got != tt.want {
+				t.Errorf("Consistent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"txsystem/internal/account/models"
+	"txsystem/internal/account/rules"
+	"txsystem/internal/transaction/fees"
+	txmodels "txsystem/internal/transaction/models"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrHoldNotFound   = errors.New("hold not found")
+	ErrHoldNotActive  = errors.New("hold is no longer active")
+	ErrHoldExpired    = errors.New("hold has expired")
+	ErrCaptureTooHigh = errors.New("capture amount exceeds the held amount")
+	ErrInvalidTTL     = errors.New("hold TTL exceeds the maximum")
+)
+
+var holdLogger = logging.For("holds")
+
+// HoldService reserves funds with authorization holds and settles them with
+// a single capture, full or partial, or releases them with a void. Holds are
+// checked against the transfer rules when placed and charged the capture fee
+// when captured, like any other transfer.
+type HoldService struct {
+	db    *gorm.DB
+	kc    types.ProducerConnection
+	rules *rules.Engine
+	fees  *fees.Engine
+	cfg   config.Holds
+	now   func() time.Time
+}
+
+func NewHoldService(db *gorm.DB, kc types.ProducerConnection, engine *rules.Engine, fe *fees.Engine, cfg config.Holds) *HoldService {
+	return &HoldService{db: db, kc: kc, rules: engine, fees: fe, cfg: cfg, now: time.Now}
+}
+
+// Authorize reserves amount on the source account. A zero ttl uses the
+// configured default. A hold the transfer rules refuse fails with their
+// *rules.Rejection, and one between accounts of different currencies with
+// ErrCurrencyMismatch.
+func (hs *HoldService) Authorize(ctx context.Context, fromID, toID uint, amount float64, ttl time.Duration, description string) (*models.Hold, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if fromID == toID {
+		return nil, ErrSameAccount
+	}
+	if ttl == 0 {
+		ttl = hs.cfg.DefaultTTL
+	}
+	if ttl < 0 || ttl > hs.cfg.MaxTTL {
+		return nil, ErrInvalidTTL
+	}
+	// A hold is not a transaction yet, so every earlier transfer counts
+	// towards the velocity limit.
+	err := hs.rules.Evaluate(ctx, rules.Transfer{
+		ID:                 math.MaxInt64,
+		Amount:             amount,
+		SourceAccount:      strconv.FormatUint(uint64(fromID), 10),
+		DestinationAccount: strconv.FormatUint(uint64(toID), 10),
+	})
+	var rejection *rules.Rejection
+	if errors.As(err, &rejection) {
+		return nil, rejection
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate transfer rules: %w", err)
+	}
+
+	hold := &models.Hold{
+		AccountID:          fromID,
+		DestinationAccount: toID,
+		Amount:             amount,
+		Description:        description,
+		Status:             models.HoldActive,
+		ExpiresAt:          hs.now().Add(ttl),
+	}
+	err = hs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var from models.Account
+		if err := lockAccount(tx, fromID, &from); err != nil {
+			return fmt.Errorf("failed to get source account: %w", err)
+		}
+		var to models.Account
+		if err := tx.First(&to, toID).Error; err != nil {
+			return fmt.Errorf("failed to get destination account: %w", notFound(err))
+		}
+		if from.Currency != to.Currency {
+			return ErrCurrencyMismatch
+		}
+		if from.Available() < amount {
+			return ErrInsufficientFunds
+		}
+
+		hold.Currency = from.Currency
+		if err := tx.Create(hold).Error; err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+		placed := event(models.HoldPlaced, amount)
+		placed.HoldID = ref(hold.ID)
+		if err := appendEvents(tx, &from, placed); err != nil {
+			return fmt.Errorf("failed to update source account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// Capture settles the hold for amount, or the full held amount when amount
+// is zero, and releases whatever was not captured. The capture fee is
+// charged to the source account on top of the captured amount.
+func (hs *HoldService) Capture(ctx context.Context, id uint, amount float64) (*models.Hold, error) {
+	if amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	var hold models.Hold
+	var record *txmodels.Transaction
+	err := hs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := hs.lockActiveHold(tx, id, &hold); err != nil {
+			return err
+		}
+		var err error
+		if amount, err = captureAmount(&hold, amount); err != nil {
+			return err
+		}
+
+		var from, to models.Account
+		if err := lockAccount(tx, hold.AccountID, &from); err != nil {
+			return fmt.Errorf("failed to get source account: %w", err)
+		}
+		if err := lockAccount(tx, hold.DestinationAccount, &to); err != nil {
+			return fmt.Errorf("failed to get destination account: %w", err)
+		}
+		source := strconv.FormatUint(uint64(hold.AccountID), 10)
+		quote, err := hs.fees.Quote(ctx, "capture", source, amount)
+		if err != nil {
+			return fmt.Errorf("failed to price capture: %w", err)
+		}
+		if !coversCapture(&from, &hold, amount, quote.Fee) {
+			return ErrInsufficientFunds
+		}
+
+		hold.Status = models.HoldCaptured
+		hold.CapturedAmount = amount
+		if err := tx.Save(&hold).Error; err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+
+		// The capture is recorded as a completed transfer so it shows up in
+		// the transaction history and counts towards transfer limits.
+		record = &txmodels.Transaction{
+			Amount:             amount,
+			Currency:           hold.Currency,
+			Fee:                quote.Fee,
+			FeeScheduleVersion: quote.ScheduleVersion,
+			Description:        hold.Description,
+			SourceAccount:      source,
+			DestinationAccount: strconv.FormatUint(uint64(hold.DestinationAccount), 10),
+			TransactionType:    "capture",
+			Status:             types.StatusCompleted,
+			TransactionID:      fmt.Sprintf("hold-%d", hold.ID),
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to record capture: %w", err)
+		}
+		if err := enqueue(tx, record); err != nil {
+			return err
+		}
+
+		released := event(models.HoldReleased, hold.Amount)
+		released.HoldID = ref(hold.ID)
+		debit := event(models.AccountDebited, amount)
+		debit.HoldID = ref(hold.ID)
+		debit.TransactionID = ref(record.ID)
+		if err := appendEvents(tx, &from, released, debit); err != nil {
+			return fmt.Errorf("failed to update source account: %w", err)
+		}
+		credit := event(models.AccountCredited, amount)
+		credit.HoldID = ref(hold.ID)
+		credit.TransactionID = ref(record.ID)
+		if err := appendEvents(tx, &to, credit); err != nil {
+			return fmt.Errorf("failed to update destination account: %w", err)
+		}
+		if err := adjustFee(tx, record.ID, hold.AccountID, models.FeeCharged, quote.Fee); err != nil {
+			return fmt.Errorf("failed to charge fee: %w", err)
+		}
+
+		// The money has moved, so a saga compensating the capture reverses
+		// it and refunds the fee.
+		applied := models.TransferRecord{
+			TransactionID:      record.ID,
+			SourceAccount:      hold.AccountID,
+			DestinationAccount: hold.DestinationAccount,
+			Amount:             amount,
+			Fee:                quote.Fee,
+			Status:             models.TransferApplied,
+		}
+		if err := tx.Create(&applied).Error; err != nil {
+			return fmt.Errorf("failed to record capture: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishTransaction(ctx, hs.db, hs.kc, record)
+	return &hold, nil
+}
+
+// Void releases the hold without moving any money.
+func (hs *HoldService) Void(ctx context.Context, id uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := hs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := hs.lockActiveHold(tx, id, &hold); err != nil {
+			return err
+		}
+		return release(tx, &hold, models.HoldVoided)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (hs *HoldService) GetHold(ctx context.Context, id uint) (*models.Hold, error) {
+	var hold models.Hold
+	if err := hs.db.WithContext(ctx).First(&hold, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ExpireHolds releases every active hold past its expiry and returns how
+// many were expired.
+func (hs *HoldService) ExpireHolds(ctx context.Context) (int, error) {
+	var ids []uint
+	err := hs.db.WithContext(ctx).
+		Model(&models.Hold{}).
+		Where("status = ? AND expires_at <= ?", models.HoldActive, hs.now()).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		released := false
+		err := hs.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var hold models.Hold
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, id).Error; err != nil {
+				return err
+			}
+			// Captured or voided since it was listed.
+			if hold.Status != models.HoldActive {
+				return nil
+			}
+			released = true
+			return release(tx, &hold, models.HoldExpired)
+		})
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire hold %d: %w", id, err)
+		}
+		if released {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// RunExpiry expires holds every sweep interval until ctx is cancelled.
+func (hs *HoldService) RunExpiry(ctx context.Context) {
+	ticker := time.NewTicker(hs.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := hs.ExpireHolds(ctx)
+			if err != nil {
+				holdLogger.Error("failed to expire holds", "error", err)
+			}
+			if n > 0 {
+				holdLogger.Info("expired holds", "count", n)
+			}
+		}
+	}
+}
+
+func (hs *HoldService) lockActiveHold(tx *gorm.DB, id uint, hold *models.Hold) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(hold, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrHoldNotFound
+		}
+		return fmt.Errorf("failed to get hold: %w", err)
+	}
+	return checkActive(hold, hs.now())
+}
+
+// checkActive reports why the hold can no longer be captured or voided at
+// now, if it cannot.
+func checkActive(hold *models.Hold, now time.Time) error {
+	if hold.Status != models.HoldActive {
+		return ErrHoldNotActive
+	}
+	// Left for the sweeper to release; it is no longer usable either way.
+	if !now.Before(hold.ExpiresAt) {
+		return ErrHoldExpired
+	}
+	return nil
+}
+
+// captureAmount resolves the amount to capture: all of the hold for zero,
+// and never more than it.
+func captureAmount(hold *models.Hold, amount float64) (float64, error) {
+	if amount == 0 {
+		return hold.Amount, nil
+	}
+	if amount > hold.Amount {
+		return 0, ErrCaptureTooHigh
+	}
+	return amount, nil
+}
+
+// coversCapture reports whether the source account can pay amount and fee
+// out of the hold. The held amount is released by the capture, so only what
+// exceeds it has to fit in what is available.
+func coversCapture(from *models.Account, hold *models.Hold, amount, fee float64) bool {
+	return from.Available()+hold.Amount >= amount+fee
+}
+
+// release returns the held amount to the available balance.
+func release(tx *gorm.DB, hold *models.Hold, status models.HoldStatus) error {
+	var account models.Account
+	if err := lockAccount(tx, hold.AccountID, &account); err != nil {
+		return fmt.Errorf("failed to get source account: %w", err)
+	}
+	released := event(models.HoldReleased, hold.Amount)
+	released.HoldID = ref(hold.ID)
+	if err := appendEvents(tx, &account, released); err != nil {
+		return fmt.Errorf("failed to update source account: %w", err)
+	}
+	hold.Status = status
+	if err := tx.Save(hold).Error; err != nil {
+		return fmt.Errorf("failed to update hold: %w", err)
+	}
+	return nil
+}
+
+func lockAccount(tx *gorm.DB, id uint, account *models.Account) error {
+	return notFound(tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(account, id).Error)
+}
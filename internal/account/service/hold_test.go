@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"txsystem/internal/account/models"
+	"txsystem/pkg/common/config"
+)
+
+// TestAuthorizeArguments covers the checks made before any account is read.
+func TestAuthorizeArguments(t *testing.T) {
+	hs := &HoldService{cfg: config.Holds{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}, now: time.Now}
+	tests := []struct {
+		name    string
+		from    uint
+		to      uint
+		amount  float64
+		ttl     time.Duration
+		wantErr error
+	}{
+		{name: "zero amount", from: 1, to: 2, wantErr: ErrInvalidAmount},
+		{name: "negative amount", from: 1, to: 2, amount: -5, wantErr: ErrInvalidAmount},
+		{name: "same account", from: 1, to: 1, amount: 5, wantErr: ErrSameAccount},
+		{name: "ttl above maximum", from: 1, to: 2, amount: 5, ttl: 25 * time.Hour, wantErr: ErrInvalidTTL},
+		{name: "negative ttl", from: 1, to: 2, amount: 5, ttl: -time.Minute, wantErr: ErrInvalidTTL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := hs.Authorize(context.Background(), tt.from, tt.to, tt.amount, tt.ttl, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckActive(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		status  models.HoldStatus
+		expires time.Time
+		wantErr error
+	}{
+		{name: "active", status: models.HoldActive, expires: now.Add(time.Second)},
+		{name: "expiring now", status: models.HoldActive, expires: now, wantErr: ErrHoldExpired},
+		{name: "past expiry", status: models.HoldActive, expires: now.Add(-time.Hour), wantErr: ErrHoldExpired},
+		{name: "captured", status: models.HoldCaptured, expires: now.Add(time.Hour), wantErr: ErrHoldNotActive},
+		{name: "voided", status: models.HoldVoided, expires: now.Add(time.Hour), wantErr: ErrHoldNotActive},
+		{name: "expired by the sweeper", status: models.HoldExpired, expires: now.Add(-time.Hour), wantErr: ErrHoldNotActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hold := &models.Hold{Status: tt.status, ExpiresAt: tt.expires}
+			if err := checkActive(hold, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkActive = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCaptureAmount(t *testing.T) {
+	hold := &models.Hold{Amount: 50}
+	tests := []struct {
+		name    string
+		amount  float64
+		want    float64
+		wantErr error
+	}{
+		{name: "zero captures all", amount: 0, want: 50},
+		{name: "partial", amount: 20, want: 20},
+		{name: "all", amount: 50, want: 50},
+		{name: "more than held", amount: 50.01, wantErr: ErrCaptureTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := captureAmount(hold, tt.amount)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("captureAmount(%v) = %v, %v; want %v, %v", tt.amount, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCoversCapture(t *testing.T) {
+	tests := []struct {
+		name      string
+		balance   float64
+		held      float64
+		overdraft float64
+		amount    float64
+		fee       float64
+		want      bool
+	}{
+		{name: "only the hold left", balance: 50, held: 50, amount: 50, want: true},
+		{name: "fee beyond the hold", balance: 50, held: 50, amount: 50, fee: 1},
+		{name: "fee from the free balance", balance: 60, held: 50, amount: 50, fee: 1, want: true},
+		{name: "partial capture leaves room for the fee", balance: 50, held: 50, amount: 40, fee: 1, want: true},
+		{name: "fee from the overdraft", balance: 50, held: 50, overdraft: 10, amount: 50, fee: 1, want: true},
+		{name: "fee not taken from another hold", balance: 80, held: 80, amount: 50, fee: 1},
+		{name: "balance spent since the hold", balance: 20, held: 50, amount: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &models.Account{Balance: tt.balance, HeldBalance: tt.held, OverdraftLimit: tt.overdraft}
+			hold := &models.Hold{Amount: 50}
+			if got := coversCapture(from, hold, tt.amount, tt.fee); got != tt.want {
+				t.Errorf("coversCapture = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"txsystem/internal/account/models"
+	ledgermodels "txsystem/internal/ledger/models"
+	txmodels "txsystem/internal/transaction/models"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidInterest = errors.New("invalid interest terms")
+	ErrNoInterest      = errors.New("account has no interest terms")
+)
+
+// Jobs recorded in interest_runs.
+const (
+	jobAccrual = "accrual"
+	jobPosting = "posting"
+)
+
+// interestBatchSize is how many accounts' terms are read at a time.
+const interestBatchSize = 500
+
+var interestLogger = logging.For("interest")
+
+// InterestService keeps the interest terms of accounts, accrues interest
+// every day and pays it out at the end of each compounding period. Every
+// accrual and posting is keyed by account and date, so running a date again
+// changes nothing.
+type InterestService struct {
+	db  *gorm.DB
+	kc  types.ProducerConnection
+	cfg config.Interest
+	now func() time.Time
+}
+
+func NewInterestService(db *gorm.DB, kc types.ProducerConnection, cfg config.Interest) *InterestService {
+	return &InterestService{db: db, kc: kc, cfg: cfg, now: time.Now}
+}
+
+// SetTerms creates or replaces the interest terms of the account. Later
+// accruals use the new terms; past ones are kept.
+func (is *InterestService) SetTerms(ctx context.Context, accountID uint, req *types.InterestRequest) (*models.InterestConfig, error) {
+	terms := &models.InterestConfig{
+		AccountID:   accountID,
+		AnnualRate:  req.AnnualRate,
+		DayCount:    models.DayCount(req.DayCount),
+		Compounding: models.Compounding(req.Compounding),
+	}
+	if terms.DayCount == "" {
+		terms.DayCount = models.DayCountActual365
+	}
+	if terms.Compounding == "" {
+		terms.Compounding = models.CompoundMonthly
+	}
+	switch {
+	case terms.AnnualRate < 0:
+		return nil, fmt.Errorf("%w: annual_rate must not be negative", ErrInvalidInterest)
+	case !validDayCount(terms.DayCount):
+		return nil, fmt.Errorf("%w: day_count must be actual/365, actual/360, actual/actual or 30/360", ErrInvalidInterest)
+	case !validCompounding(terms.Compounding):
+		return nil, fmt.Errorf("%w: compounding must be daily, monthly, quarterly or annually", ErrInvalidInterest)
+	}
+
+	db := is.db.WithContext(ctx)
+	if err := notFound(db.Select("id").First(&models.Account{}, accountID).Error); err != nil {
+		return nil, err
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"annual_rate", "day_count", "compounding", "updated_at"}),
+	}).Create(terms).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store interest terms: %w", err)
+	}
+	return terms, nil
+}
+
+// InterestSummary is an account's interest terms, the interest accrued but
+// not yet paid and the latest postings.
+type InterestSummary struct {
+	Terms    *models.InterestConfig   `json:"terms"`
+	Accrued  float64                  `json:"accrued"`
+	Postings []models.InterestPosting `json:"postings"`
+}
+
+// Summary returns the interest of the account, or ErrNoInterest when it has
+// no terms.
+func (is *InterestService) Summary(ctx context.Context, accountID uint) (*InterestSummary, error) {
+	db := is.db.WithContext(ctx)
+	var terms models.InterestConfig
+	if err := db.First(&terms, accountID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoInterest
+		}
+		return nil, fmt.Errorf("failed to get interest terms: %w", err)
+	}
+	summary := &InterestSummary{Terms: &terms}
+	err := db.Model(&models.InterestAccrual{}).
+		Where("account_id = ? AND posted_on IS NULL", accountID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&summary.Accrued).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to total accrued interest: %w", err)
+	}
+	if err := db.Where("account_id = ?", accountID).Order("posting_date DESC").Limit(12).Find(&summary.Postings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list interest postings: %w", err)
+	}
+	return summary, nil
+}
+
+// Run accrues and posts interest for the days that have ended every
+// interval until ctx is cancelled.
+func (is *InterestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(is.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := is.RunDue(ctx)
+			if err != nil {
+				interestLogger.Error("failed to run interest jobs", "error", err)
+			}
+			if n > 0 {
+				interestLogger.Info("ran interest jobs", "days", n)
+			}
+		}
+	}
+}
+
+// RunDue runs every day after the last completed accrual up to yesterday
+// (UTC), so days missed while no instance was running are caught up. It
+// starts earlier when a month end was accrued but its posting did not
+// complete, so the posting is retried; days already accrued are not accrued
+// again. The first run only covers yesterday.
+func (is *InterestService) RunDue(ctx context.Context) (int, error) {
+	yesterday := startOfDay(is.now()).AddDate(0, 0, -1)
+	var last sql.NullTime
+	err := is.db.WithContext(ctx).Model(&models.InterestRun{}).
+		Where("job = ?", jobAccrual).
+		Select("MAX(run_date)").
+		Row().Scan(&last)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last interest run: %w", err)
+	}
+	day := yesterday
+	if last.Valid {
+		day = startOfDay(last.Time).AddDate(0, 0, 1)
+	}
+
+	var unposted sql.NullTime
+	err = is.db.WithContext(ctx).Model(&models.InterestRun{}).
+		Where("job = ? AND EXTRACT(DAY FROM run_date + INTERVAL '1 day') = 1", jobAccrual).
+		Where("NOT EXISTS (SELECT 1 FROM interest_runs p WHERE p.job = ? AND p.run_date = interest_runs.run_date)", jobPosting).
+		Select("MIN(run_date)").
+		Row().Scan(&unposted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unposted interest runs: %w", err)
+	}
+	if unposted.Valid && startOfDay(unposted.Time).Before(day) {
+		day = startOfDay(unposted.Time)
+	}
+
+	n := 0
+	for ; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		if err := is.RunDate(ctx, day); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RunDate accrues interest for the day and, when it ends a month, posts the
+// interest of every account whose compounding period ends with it. Each job
+// is recorded in interest_runs once done.
+func (is *InterestService) RunDate(ctx context.Context, day time.Time) error {
+	day = startOfDay(day)
+	accrued, err := is.Accrue(ctx, day)
+	if err != nil {
+		return fmt.Errorf("failed to accrue interest for %s: %w", day.Format(time.DateOnly), err)
+	}
+	if err := is.recordRun(ctx, jobAccrual, day); err != nil {
+		return err
+	}
+	interestLogger.Debug("interest accrued", "date", day.Format(time.DateOnly), "accounts", accrued)
+
+	if !endOfMonth(day) {
+		return nil
+	}
+	posted, err := is.Post(ctx, day)
+	if err != nil {
+		return fmt.Errorf("failed to post interest for %s: %w", day.Format(time.DateOnly), err)
+	}
+	if err := is.recordRun(ctx, jobPosting, day); err != nil {
+		return err
+	}
+	interestLogger.Info("interest posted", "date", day.Format(time.DateOnly), "accounts", posted)
+	return nil
+}
+
+func (is *InterestService) recordRun(ctx context.Context, job string, day time.Time) error {
+	run := models.InterestRun{Job: job, RunDate: day}
+	if err := is.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error; err != nil {
+		return fmt.Errorf("failed to record interest run: %w", err)
+	}
+	return nil
+}
+
+// Accrue records the interest every account with a positive rate earned on
+// day and returns how many accruals were new.
+func (is *InterestService) Accrue(ctx context.Context, day time.Time) (int, error) {
+	accrued := 0
+	err := is.eachTerms(ctx, func(terms *models.InterestConfig) error {
+		if terms.AnnualRate <= 0 {
+			return nil
+		}
+		created, err := is.accrue(ctx, terms, day)
+		if err != nil {
+			return fmt.Errorf("account %d: %w", terms.AccountID, err)
+		}
+		if created {
+			accrued++
+		}
+		return nil
+	})
+	return accrued, err
+}
+
+func (is *InterestService) accrue(ctx context.Context, terms *models.InterestConfig, day time.Time) (bool, error) {
+	balance, err := interestBase(is.db.WithContext(ctx), terms, day)
+	if err != nil {
+		return false, err
+	}
+	if balance <= 0 {
+		return false, nil
+	}
+	accrual := models.InterestAccrual{
+		AccountID:   terms.AccountID,
+		AccrualDate: day,
+		Balance:     balance,
+		AnnualRate:  terms.AnnualRate,
+		DayCount:    terms.DayCount,
+		Amount:      balance * terms.AnnualRate / 100 * dayFraction(terms.DayCount, day),
+	}
+	created := is.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&accrual)
+	if created.Error != nil {
+		return false, fmt.Errorf("failed to store interest accrual: %w", created.Error)
+	}
+	return created.RowsAffected == 1, nil
+}
+
+// interestBase is the balance that earns interest on day: the balance at
+// the end of the day without interest, plus the interest paid for earlier
+// periods, plus, with daily compounding, the interest accrued on earlier
+// days. Only what happened by the end of the day counts, so the base does
+// not depend on when the job runs.
+func interestBase(db *gorm.DB, terms *models.InterestConfig, day time.Time) (float64, error) {
+	var principal, paid, unpaid float64
+	// Interest postings are credited after the day they are for, so they are
+	// counted by posting date below instead.
+	err := db.Model(&models.AccountEvent{}).
+		Where("account_id = ? AND created_at < ?", terms.AccountID, day.AddDate(0, 0, 1)).
+		Select("COALESCE(SUM(CASE WHEN type IN ? THEN amount WHEN type IN ? THEN -amount ELSE 0 END), 0)",
+			[]models.AccountEventType{models.AccountOpened, models.AccountCredited, models.FeeRefunded},
+			[]models.AccountEventType{models.AccountDebited, models.FeeCharged, models.OverdraftFeeCharged}).
+		Scan(&principal).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to total account events: %w", err)
+	}
+	err = db.Model(&models.InterestPosting{}).
+		Where("account_id = ? AND posting_date < ?", terms.AccountID, day).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&paid).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to total interest postings: %w", err)
+	}
+	if terms.Compounding == models.CompoundDaily {
+		err = db.Model(&models.InterestAccrual{}).
+			Where("account_id = ? AND accrual_date < ? AND (posted_on IS NULL OR posted_on >= ?)", terms.AccountID, day, day).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&unpaid).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to total accrued interest: %w", err)
+		}
+	}
+	return principal + paid + unpaid, nil
+}
+
+// Post pays out the accrued interest of every account whose compounding
+// period ends on day and returns how many accounts were paid.
+func (is *InterestService) Post(ctx context.Context, day time.Time) (int, error) {
+	posted := 0
+	err := is.eachTerms(ctx, func(terms *models.InterestConfig) error {
+		if !periodEnds(terms.Compounding, day) {
+			return nil
+		}
+		record, err := is.post(ctx, terms.AccountID, day)
+		if err != nil {
+			return fmt.Errorf("account %d: %w", terms.AccountID, err)
+		}
+		if record != nil {
+			posted++
+			publishTransaction(ctx, is.db, is.kc, record)
+		}
+		return nil
+	})
+	return posted, err
+}
+
+// post credits the account with its unpaid interest up to day, rounded to
+// cents, as a completed interest transaction from interest expense. Less
+// than a cent is left for the next posting. It returns nil when there was
+// nothing to pay or the day was posted already.
+func (is *InterestService) post(ctx context.Context, accountID uint, day time.Time) (*txmodels.Transaction, error) {
+	var record *txmodels.Transaction
+	err := is.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := lockAccount(tx, accountID, &account); err != nil {
+			return err
+		}
+		unpaid := tx.Model(&models.InterestAccrual{}).
+			Where("account_id = ? AND posted_on IS NULL AND accrual_date <= ?", accountID, day).
+			Session(&gorm.Session{})
+		var total float64
+		if err := unpaid.Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+			return fmt.Errorf("failed to total accrued interest: %w", err)
+		}
+		amount := math.Round(total*100) / 100
+		if amount <= 0 {
+			return nil
+		}
+
+		posting := models.InterestPosting{AccountID: accountID, PostingDate: day, Amount: amount}
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&posting)
+		if created.Error != nil {
+			return fmt.Errorf("failed to record interest posting: %w", created.Error)
+		}
+		if created.RowsAffected == 0 {
+			return nil
+		}
+
+		record = &txmodels.Transaction{
+			Amount:             amount,
+			Currency:           account.Currency,
+			Description:        "Interest to " + day.Format(time.DateOnly),
+			SourceAccount:      ledgermodels.SystemAccountID(ledgermodels.AccountInterestExpense),
+			DestinationAccount: strconv.FormatUint(uint64(accountID), 10),
+			TransactionType:    "interest",
+			Status:             types.StatusCompleted,
+			TransactionID:      fmt.Sprintf("interest-%d-%s", accountID, day.Format(time.DateOnly)),
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to record interest transaction: %w", err)
+		}
+		if err := enqueue(tx, record); err != nil {
+			return err
+		}
+		credit := event(models.InterestPosted, amount)
+		credit.TransactionID = ref(record.ID)
+		if err := appendEvents(tx, &account, credit); err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+		// The source is outside the accounts table, so a saga compensating
+		// the posting only takes the interest back from the account.
+		applied := models.TransferRecord{
+			TransactionID:      record.ID,
+			DestinationAccount: accountID,
+			Amount:             amount,
+			Status:             models.TransferApplied,
+		}
+		if err := tx.Create(&applied).Error; err != nil {
+			return fmt.Errorf("failed to record interest transfer: %w", err)
+		}
+		if err := tx.Model(&posting).Update("transaction_id", record.ID).Error; err != nil {
+			return fmt.Errorf("failed to update interest posting: %w", err)
+		}
+		if err := unpaid.Update("posted_on", day).Error; err != nil {
+			return fmt.Errorf("failed to mark interest posted: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// eachTerms calls fn with the interest terms of every account, a batch at a
+// time.
+func (is *InterestService) eachTerms(ctx context.Context, fn func(*models.InterestConfig) error) error {
+	var last uint
+	for {
+		var batch []models.InterestConfig
+		err := is.db.WithContext(ctx).Where("account_id > ?", last).Order("account_id").Limit(interestBatchSize).Find(&batch).Error
+		if err != nil {
+			return fmt.Errorf("failed to list interest terms: %w", err)
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < interestBatchSize {
+			return nil
+		}
+		last = batch[len(batch)-1].AccountID
+	}
+}
+
+// dayFraction is the part of a year that day counts for.
+func dayFraction(dc models.DayCount, day time.Time) float64 {
+	switch dc {
+	case models.DayCountActual360:
+		return 1.0 / 360
+	case models.DayCountActualActual:
+		year := time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return 1 / (year.AddDate(1, 0, 0).Sub(year).Hours() / 24)
+	case models.DayCount30360:
+		// Every month counts 30 days: the 31st counts for nothing and the
+		// end of February for the days the month is short.
+		last := day.AddDate(0, 1, -day.Day()).Day()
+		switch {
+		case day.Day() == 31:
+			return 0
+		case day.Day() == last && last < 30:
+			return float64(31-last) / 360
+		}
+		return 1.0 / 360
+	}
+	return 1.0 / 365
+}
+
+// periodEnds reports whether day ends a compounding period. Daily interest
+// is paid out monthly.
+func periodEnds(c models.Compounding, day time.Time) bool {
+	if !endOfMonth(day) {
+		return false
+	}
+	switch c {
+	case models.CompoundQuarterly:
+		return day.Month()%3 == 0
+	case models.CompoundAnnually:
+		return day.Month() == time.December
+	}
+	return true
+}
+
+func endOfMonth(day time.Time) bool {
+	return day.AddDate(0, 0, 1).Day() == 1
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func validDayCount(dc models.DayCount) bool {
+	switch dc {
+	case models.DayCountActual365, models.DayCountActual360, models.DayCountActualActual, models.DayCount30360:
+		return true
+	}
+	return false
+}
+
+func validCompounding(c models.Compounding) bool {
+	switch c {
+	case models.CompoundDaily, models.CompoundMonthly, models.CompoundQuarterly, models.CompoundAnnually:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+	"txsystem/internal/account/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDayFraction(t *testing.T) {
+	tests := []struct {
+		dayCount models.DayCount
+		day      string
+		want     float64
+	}{
+		{models.DayCountActual365, "2024-02-29", 1.0 / 365},
+		{"", "2023-06-15", 1.0 / 365},
+		{models.DayCountActual360, "2024-12-31", 1.0 / 360},
+		{models.DayCountActualActual, "2023-12-31", 1.0 / 365},
+		{models.DayCountActualActual, "2024-01-01", 1.0 / 366},
+		{models.DayCountActualActual, "2024-12-31", 1.0 / 366},
+		{models.DayCountActualActual, "2000-06-01", 1.0 / 366},
+		{models.DayCountActualActual, "2100-06-01", 1.0 / 365},
+		{models.DayCount30360, "2024-03-15", 1.0 / 360},
+		{models.DayCount30360, "2024-03-30", 1.0 / 360},
+		{models.DayCount30360, "2024-03-31", 0},
+		{models.DayCount30360, "2024-04-30", 1.0 / 360},
+		{models.DayCount30360, "2023-02-27", 1.0 / 360},
+		{models.DayCount30360, "2023-02-28", 3.0 / 360},
+		{models.DayCount30360, "2024-02-28", 1.0 / 360},
+		{models.DayCount30360, "2024-02-29", 2.0 / 360},
+	}
+	for _, tt := range tests {
+		if got := dayFraction(tt.dayCount, day(tt.day)); got != tt.want {
+			t.Errorf("dayFraction(%q, %s) = %v, want %v", tt.dayCount, tt.day, got, tt.want)
+		}
+	}
+}
+
+// TestDayFractionYear checks that a whole year adds up to what the
+// convention pays for it.
+func TestDayFractionYear(t *testing.T) {
+	tests := []struct {
+		dayCount models.DayCount
+		year     int
+		want     float64
+	}{
+		{models.DayCountActual365, 2023, 1},
+		{models.DayCountActual365, 2024, 366.0 / 365},
+		{models.DayCountActual360, 2023, 365.0 / 360},
+		{models.DayCountActualActual, 2023, 1},
+		{models.DayCountActualActual, 2024, 1},
+		{models.DayCount30360, 2023, 1},
+		{models.DayCount30360, 2024, 1},
+	}
+	for _, tt := range tests {
+		sum := 0.0
+		for d := time.Date(tt.year, 1, 1, 0, 0, 0, 0, time.UTC); d.Year() == tt.year; d = d.AddDate(0, 0, 1) {
+			sum += dayFraction(tt.dayCount, d)
+		}
+		if math.Abs(sum-tt.want) > 1e-9 {
+			t.Errorf("%s over %d = %v, want %v", tt.dayCount, tt.year, sum, tt.want)
+		}
+	}
+}
+
+func TestPeriodEnds(t *testing.T) {
+	tests := []struct {
+		compounding models.Compounding
+		day         string
+		want        bool
+	}{
+		{models.CompoundDaily, "2024-01-30", false},
+		{models.CompoundDaily, "2024-01-31", true},
+		{models.CompoundMonthly, "2024-02-28", false},
+		{models.CompoundMonthly, "2024-02-29", true},
+		{models.CompoundMonthly, "2023-02-28", true},
+		{models.CompoundQuarterly, "2024-02-29", false},
+		{models.CompoundQuarterly, "2024-03-31", true},
+		{models.CompoundQuarterly, "2024-06-30", true},
+		{models.CompoundAnnually, "2024-11-30", false},
+		{models.CompoundAnnually, "2024-12-31", true},
+	}
+	for _, tt := range tests {
+		if got := periodEnds(tt.compounding, day(tt.day)); got != tt.want {
+			t.Errorf("periodEnds(%s, %s) = %v, want %v", tt.compounding, tt.day, got, tt.want)
+		}
+	}
+}
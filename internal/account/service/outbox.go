@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"txsystem/internal/account/models"
+	txmodels "txsystem/internal/transaction/models"
+	txservice "txsystem/internal/transaction/service"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+
+	"gorm.io/gorm"
+)
+
+// republishLimit bounds how many transactions one sweep publishes.
+const republishLimit = 100
+
+var outboxLogger = logging.For("outbox")
+
+// enqueue records, in the transaction that settles it, that record must be
+// published.
+func enqueue(tx *gorm.DB, record *txmodels.Transaction) error {
+	if err := tx.Create(&models.OutboxEntry{TransactionID: record.ID}).Error; err != nil {
+		return fmt.Errorf("failed to queue transaction for publishing: %w", err)
+	}
+	return nil
+}
+
+// publishTransaction emits a transaction that already moved its money, such
+// as a capture, on the transactions topic so the ledger records it. The
+// money has moved, so a failure is logged, not returned; the transaction
+// stays in the outbox and is published again by the Outbox sweep.
+func publishTransaction(ctx context.Context, db *gorm.DB, kc types.ProducerConnection, record *txmodels.Transaction) {
+	if err := publish(ctx, db, kc, record); err != nil {
+		outboxLogger.ErrorContext(ctx, "failed to publish transaction", "transaction_id", record.ID, "type", record.TransactionType, "error", err)
+	}
+}
+
+// publish produces the transaction event and marks its outbox entry
+// published.
+func publish(ctx context.Context, db *gorm.DB, kc types.ProducerConnection, record *txmodels.Transaction) error {
+	payload, err := json.Marshal(txservice.ToTransactionResponse(record))
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction response: %w", err)
+	}
+	if err := kc.Produce(ctx, string(payload)); err != nil {
+		return fmt.Errorf("failed to produce kafka event: %w", err)
+	}
+	err = db.WithContext(ctx).
+		Model(&models.OutboxEntry{}).
+		Where("transaction_id = ? AND published_at IS NULL", record.ID).
+		Update("published_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark transaction %d published: %w", record.ID, err)
+	}
+	return nil
+}
+
+// Outbox publishes again the captures, interest postings and opening
+// balances whose first publish failed.
+type Outbox struct {
+	db *gorm.DB
+	kc types.ProducerConnection
+}
+
+func NewOutbox(db *gorm.DB, kc types.ProducerConnection) *Outbox {
+	return &Outbox{db: db, kc: kc}
+}
+
+// RunRepublish publishes transactions left unpublished every
+// cfg.RepublishAfter until ctx is done.
+func (o *Outbox) RunRepublish(ctx context.Context, cfg config.Outbox) {
+	ticker := time.NewTicker(cfg.RepublishAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := o.RepublishDue(ctx, time.Now().Add(-cfg.RepublishAfter))
+			if err != nil {
+				outboxLogger.Error("failed to republish transactions", "error", err)
+			}
+			if n > 0 {
+				outboxLogger.Info("republished transactions", "count", n)
+			}
+		}
+	}
+}
+
+// RepublishDue publishes the transactions queued before before and still
+// unpublished. The saga and the ledger ignore repeated events, so a
+// transaction published twice is recorded once.
+func (o *Outbox) RepublishDue(ctx context.Context, before time.Time) (int, error) {
+	var ids []uint
+	err := o.db.WithContext(ctx).
+		Model(&models.OutboxEntry{}).
+		Where("published_at IS NULL AND created_at < ?", before).
+		Order("created_at").
+		Limit(republishLimit).
+		Pluck("transaction_id", &ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unpublished transactions: %w", err)
+	}
+	for i, id := range ids {
+		var record txmodels.Transaction
+		if err := o.db.WithContext(ctx).First(&record, id).Error; err != nil {
+			return i, fmt.Errorf("failed to get transaction %d: %w", id, err)
+		}
+		if err := publish(ctx, o.db, o.kc, &record); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
@@ -1,22 +1,29 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 	"txsystem/internal/ledger/models"
 	"txsystem/internal/ledger/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/export"
+	"txsystem/pkg/common/logging"
 
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type LedgerHandler struct {
-	service *service.LedgerService
+	service   *service.LedgerService
+	ownership *auth.Ownership
 }
 
-func NewLedgerHandler(db *mongo.Database) *LedgerHandler {
+func NewLedgerHandler(db *mongo.Database, calendar *service.Calendar, ownership *auth.Ownership) *LedgerHandler {
 	return &LedgerHandler{
-		service: service.NewLedgerService(db),
+		service:   service.NewLedgerService(db, calendar),
+		ownership: ownership,
 	}
 }
 
@@ -30,6 +37,18 @@ func (h *LedgerHandler) ListLedgersByAccount(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, accountID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to fetch ledger entries",
+		})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "forbidden",
+		})
+	}
+
 	ledgers, err := h.service.ListLedgers(ctx)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -48,52 +67,214 @@ func (h *LedgerHandler) ListLedgersByAccount(c echo.Context) error {
 	return c.JSON(http.StatusOK, accountLedgers)
 }
 
+// ListAllLedgersByDate lists every entry, or those of the business date
+// given as YYYY-MM-DD.
 func (h *LedgerHandler) ListAllLedgersByDate(c echo.Context) error {
 	dateStr := c.QueryParam("date")
+	ctx := c.Request().Context()
 
-	var filterDate time.Time
-	var err error
+	if dateStr == "" {
+		ledgers, err := h.service.ListLedgers(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "failed to fetch ledger entries",
+			})
+		}
+		return c.JSON(http.StatusOK, ledgers)
+	}
 
-	if dateStr != "" {
-		filterDate, err = time.Parse("2006-01-02", dateStr)
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid date format, please use YYYY-MM-DD",
+		})
+	}
+	ledgers, err := h.service.ListByBusinessDate(ctx, date)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to fetch ledger entries",
+		})
+	}
+	return c.JSON(http.StatusOK, ledgers)
+}
+
+// ListChartOfAccounts returns the ledger accounts entries post to.
+func (h *LedgerHandler) ListChartOfAccounts(c echo.Context) error {
+	return c.JSON(http.StatusOK, models.ChartOfAccounts)
+}
+
+// GetTrialBalance totals the book per ledger account, up to the end of the
+// date given as YYYY-MM-DD or up to now.
+func (h *LedgerHandler) GetTrialBalance(c echo.Context) error {
+	var asOf *time.Time
+	if dateStr := c.QueryParam("date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "invalid date format, please use YYYY-MM-DD",
 			})
 		}
+		end := date.AddDate(0, 0, 1)
+		asOf = &end
+	}
+
+	tb, err := h.service.TrialBalance(c.Request().Context(), asOf)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to compute trial balance",
+		})
+	}
+	return c.JSON(http.StatusOK, tb)
+}
+
+// GetDayClose returns the end-of-day summary of a closed business date.
+func (h *LedgerHandler) GetDayClose(c echo.Context) error {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid date format, please use YYYY-MM-DD",
+		})
+	}
+
+	summary, err := h.service.DayClose(c.Request().Context(), date)
+	if errors.Is(err, service.ErrDayNotClosed) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to fetch end-of-day summary",
+		})
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// GetDayBalances returns the opening and closing balances of an account on
+// a closed business date.
+func (h *LedgerHandler) GetDayBalances(c echo.Context) error {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid date format, please use YYYY-MM-DD",
+		})
 	}
 
 	ctx := c.Request().Context()
-	ledgers, err := h.service.ListLedgers(ctx)
+	accountID := c.Param("accountId")
+	allowed, err := h.ownership.CanAccess(ctx, accountID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "failed to fetch ledger entries",
+			"error": "failed to fetch balances",
+		})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "forbidden",
 		})
 	}
 
-	if dateStr == "" {
-		return c.JSON(http.StatusOK, ledgers)
+	balances, err := h.service.DayBalances(ctx, date, accountID)
+	if errors.Is(err, service.ErrDayNotClosed) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to fetch balances",
+		})
 	}
+	return c.JSON(http.StatusOK, balances)
+}
 
-	var filteredLedgers []*models.Ledger
-	for _, ledger := range ledgers {
-		ledgerDate := ledger.CreatedAt.Truncate(24 * time.Hour)
-		filterDateOnly := filterDate.Truncate(24 * time.Hour)
+// CloseDay closes a business date by hand, for when the automatic close is
+// disabled or behind. Closing a closed date returns its summary.
+func (h *LedgerHandler) CloseDay(c echo.Context) error {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "invalid date format, please use YYYY-MM-DD",
+		})
+	}
 
-		if ledgerDate.Equal(filterDateOnly) {
-			filteredLedgers = append(filteredLedgers, ledger)
+	summary, err := h.service.CloseDay(c.Request().Context(), date)
+	switch {
+	case errors.Is(err, service.ErrDayOpen), errors.Is(err, service.ErrDayOutOfOrder), errors.Is(err, service.ErrDayClosed):
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "failed to close business date",
+		})
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// ExportLedger streams ledger entries in the order they were written as CSV,
+// NDJSON or Parquet, filtered by business date and account. Without an
+// account only admins may export.
+func (h *LedgerHandler) ExportLedger(c echo.Context) error {
+	format, err := export.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	filter, err := export.ParseFilter(c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("account"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	ctx := c.Request().Context()
+	if filter.AccountID != "" {
+		allowed, err := h.ownership.CanAccess(ctx, filter.AccountID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "failed to export ledger entries",
+			})
 		}
+		if !allowed {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "forbidden",
+			})
+		}
+	} else if p, _ := auth.FromContext(ctx); p == nil || !p.IsAdmin() {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "forbidden",
+		})
 	}
 
-	return c.JSON(http.StatusOK, filteredLedgers)
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, format.ContentType())
+	w.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filter.FileName("ledger", format)))
+	export.DeclareTrailers(w.Header())
+	w.WriteHeader(http.StatusOK)
+	// The status is sent, so a failure is only reported in the trailer.
+	rows, err := h.service.Export(ctx, filter, format, w)
+	export.SetTrailer(w.Header(), rows, err)
+	if err != nil {
+		logging.For("export").ErrorContext(ctx, "ledger export failed", "error", err)
+	}
+	return nil
 }
 
-func InitRoutes(e *echo.Echo, db *mongo.Database) {
-	h := NewLedgerHandler(db)
+func InitRoutes(e *echo.Echo, db *mongo.Database, calendar *service.Calendar, ownership *auth.Ownership) {
+	h := NewLedgerHandler(db, calendar, ownership)
 
-	e.Logger.Info("Initializing ledger routes")
+	logging.For("http").Info("initializing ledger routes")
 	g := e.Group("/api/v1/ledger")
 	g.GET("/account/:accountId", h.ListLedgersByAccount)
-	g.GET("/", h.ListAllLedgersByDate) // New endpoint to get all ledgers with optional date filtering
+	// The unfiltered listing spans every account, so it is admin only.
+	g.GET("/", h.ListAllLedgersByDate, auth.RequireAdmin())
+	g.GET("/accounts", h.ListChartOfAccounts)
+	g.GET("/export", h.ExportLedger)
+	g.GET("/trial-balance", h.GetTrialBalance, auth.RequireAdmin())
+	g.GET("/eod/:date", h.GetDayClose, auth.RequireAdmin())
+	g.POST("/eod/:date/close", h.CloseDay, auth.RequireAdmin())
+	g.GET("/eod/:date/accounts/:accountId", h.GetDayBalances)
 
 }
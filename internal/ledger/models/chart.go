@@ -0,0 +1,87 @@
+package models
+
+import "strings"
+
+type AccountClass string
+
+const (
+	ClassAsset     AccountClass = "asset"
+	ClassLiability AccountClass = "liability"
+	ClassEquity    AccountClass = "equity"
+	ClassRevenue   AccountClass = "revenue"
+	ClassExpense   AccountClass = "expense"
+)
+
+// Posting sides.
+const (
+	SideDebit  = "debit"
+	SideCredit = "credit"
+)
+
+// NormalBalance returns the side on which accounts of the class increase.
+func (c AccountClass) NormalBalance() string {
+	if c == ClassAsset || c == ClassExpense {
+		return SideDebit
+	}
+	return SideCredit
+}
+
+// Codes of the ledger accounts in the chart. Customer balances are owed to
+// the customers, so they sit under customer deposits, with the customer's
+// account number on each entry.
+const (
+	AccountExternalClearing = "1000"
+	AccountSuspense         = "1900"
+	AccountCustomerDeposits = "2000"
+	AccountRetainedEarnings = "3000"
+	AccountFeeIncome        = "4000"
+	AccountInterestExpense  = "5000"
+)
+
+// LedgerAccount is an account of the chart that entries post to.
+type LedgerAccount struct {
+	Code          string       `json:"code"`
+	Name          string       `json:"name"`
+	Class         AccountClass `json:"class"`
+	NormalBalance string       `json:"normal_balance"`
+}
+
+func ledgerAccount(code, name string, class AccountClass) LedgerAccount {
+	return LedgerAccount{Code: code, Name: name, Class: class, NormalBalance: class.NormalBalance()}
+}
+
+// ChartOfAccounts lists every ledger account, ordered by code.
+var ChartOfAccounts = []LedgerAccount{
+	ledgerAccount(AccountExternalClearing, "External clearing", ClassAsset),
+	ledgerAccount(AccountSuspense, "Suspense", ClassAsset),
+	ledgerAccount(AccountCustomerDeposits, "Customer deposits", ClassLiability),
+	ledgerAccount(AccountRetainedEarnings, "Retained earnings", ClassEquity),
+	ledgerAccount(AccountFeeIncome, "Fee income", ClassRevenue),
+	ledgerAccount(AccountInterestExpense, "Interest expense", ClassExpense),
+}
+
+// SystemAccountID is the AccountID of entries posted to a ledger account
+// other than customer deposits. The prefix keeps it apart from customer
+// account numbers.
+func SystemAccountID(code string) string {
+	return "gl-" + code
+}
+
+// LedgerAccountOf returns the ledger account that entries for accountID
+// post to: the code of a SystemAccountID, customer deposits otherwise.
+func LedgerAccountOf(accountID string) string {
+	if code, ok := strings.CutPrefix(accountID, "gl-"); ok {
+		return code
+	}
+	return AccountCustomerDeposits
+}
+
+// LookupLedgerAccount returns the chart entry for code.
+func LookupLedgerAccount(code string) (LedgerAccount, bool) {
+	for _, a := range ChartOfAccounts {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return LedgerAccount{}, false
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// DayBalance is the movement of one account on one ledger account over a
+// business date. Debits and Credits are positive totals, and Closing is
+// Opening plus Credits less Debits, signed as entry amounts are.
+type DayBalance struct {
+	BusinessDate  string  `bson:"business_date" json:"business_date"`
+	AccountID     string  `bson:"account_id,omitempty" json:"account_id,omitempty"`
+	LedgerAccount string  `bson:"ledger_account" json:"ledger_account"`
+	Opening       float64 `bson:"opening" json:"opening"`
+	Debits        float64 `bson:"debits" json:"debits"`
+	Credits       float64 `bson:"credits" json:"credits"`
+	Closing       float64 `bson:"closing" json:"closing"`
+	Entries       int64   `bson:"entries" json:"entries"`
+}
+
+// Close states. A close starts as a closing marker, which already stops new
+// entries for the date, and becomes closed once its balances are stored.
+// Closes written before the marker have no status and count as closed.
+const (
+	DayClosing = "closing"
+	DayClosed  = "closed"
+)
+
+// DayClose summarises a closed business date. It is finalized last, so a
+// closed status means its balances are complete. LedgerAccounts rolls the
+// account balances up per ledger account.
+type DayClose struct {
+	BusinessDate   string       `bson:"_id" json:"business_date"`
+	Status         string       `bson:"status,omitempty" json:"status"`
+	CutoffAt       time.Time    `bson:"cutoff_at" json:"cutoff_at"`
+	ClosedAt       time.Time    `bson:"closed_at" json:"closed_at"`
+	Accounts       int          `bson:"accounts" json:"accounts"`
+	Entries        int64        `bson:"entries" json:"entries"`
+	TotalDebits    float64      `bson:"total_debits" json:"total_debits"`
+	TotalCredits   float64      `bson:"total_credits" json:"total_credits"`
+	Balanced       bool         `bson:"balanced" json:"balanced"`
+	LedgerAccounts []DayBalance `bson:"ledger_accounts" json:"ledger_accounts"`
+}
@@ -1,15 +1,104 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Entry types. A transfer is recorded as a debit of the source and a credit
+// of the destination, plus a pair of fee entries when it was charged one; a
+// voided transfer gets a reversal of each.
+const (
+	EntryDebit    = "debit"
+	EntryCredit   = "credit"
+	EntryFee      = "fee"
+	EntryReversal = "reversal"
+)
+
+// Legs of a transaction. A transaction has at most one entry per leg, so
+// the transaction ID and leg identify an entry however often it is written.
+const (
+	LegDebit          = "debit"
+	LegCredit         = "credit"
+	LegDebitReversal  = "debit-reversal"
+	LegCreditReversal = "credit-reversal"
+	LegFee            = "fee"
+	LegFeeIncome      = "fee-income"
+)
+
+// ReversalLeg is the leg that reverses leg.
+func ReversalLeg(leg string) string {
+	return leg + "-reversal"
+}
+
+// EntryKey is the deterministic key of a transaction's entry for leg.
+func EntryKey(transactionID uint64, leg string) string {
+	return fmt.Sprintf("%d:%s", transactionID, leg)
+}
+
+// Ledger is one entry. It posts Amount to LedgerAccount, a code from the
+// chart of accounts, on behalf of AccountID: the customer account for
+// customer deposits, SystemAccountID of the code for the others. Amount
+// is signed: debits are negative, and Side says the same in words. Entries
+// written before the chart have neither and belong to customer deposits.
+//
+// Entries of an account form a hash chain: Seq counts them from 1, PrevHash
+// is the Hash of the entry before, and Hash covers the entry's content and
+// PrevHash, so changing or removing an entry breaks every later link.
+// Entries written before chaining have no Seq, and those written before keys
+// no Key.
+//
+// BusinessDate is the date, as YYYY-MM-DD, whose end-of-day close covers the
+// entry. Entries written before business dates have none and count as
+// before the first close.
 type Ledger struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Amount    float64            `bson:"amount" json:"amount"`
-	AccountID string             `bson:"account_id" json:"account_id"`
-	Type      string             `bson:"type" json:"type"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Key           string             `bson:"key,omitempty" json:"key,omitempty"`
+	TransactionID uint64             `bson:"transaction_id" json:"transaction_id"`
+	Amount        float64            `bson:"amount" json:"amount"`
+	AccountID     string             `bson:"account_id" json:"account_id"`
+	LedgerAccount string             `bson:"ledger_account,omitempty" json:"ledger_account,omitempty"`
+	Side          string             `bson:"side,omitempty" json:"side,omitempty"`
+	Type          string             `bson:"type" json:"type"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	BusinessDate  string             `bson:"business_date,omitempty" json:"business_date,omitempty"`
+	Seq           int64              `bson:"seq,omitempty" json:"seq,omitempty"`
+	PrevHash      string             `bson:"prev_hash,omitempty" json:"prev_hash,omitempty"`
+	Hash          string             `bson:"hash,omitempty" json:"hash,omitempty"`
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's content and PrevHash.
+// CreatedAt is taken at millisecond precision, as MongoDB stores it. The
+// ledger account, side and business date are only covered when set, so
+// entries written before them keep their hashes.
+func (l *Ledger) ComputeHash() string {
+	content := fmt.Sprintf("%d|%s|%d|%s|%s|%d|%s",
+		l.Seq,
+		l.AccountID,
+		l.TransactionID,
+		l.Type,
+		strconv.FormatFloat(l.Amount, 'g', -1, 64),
+		l.CreatedAt.UnixMilli(),
+		l.PrevHash,
+	)
+	if l.LedgerAccount != "" {
+		content += "|" + l.LedgerAccount + "|" + l.Side
+	}
+	if l.BusinessDate != "" {
+		content += "|" + l.BusinessDate
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// Void marks a transaction whose entries must not be recorded, or were
+// reversed.
+type Void struct {
+	TransactionID uint64    `bson:"_id"`
+	CreatedAt     time.Time `bson:"created_at"`
 }
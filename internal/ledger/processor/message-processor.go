@@ -2,34 +2,81 @@ package processor
 
 import (
 	"context"
-	"time"
-	"txsystem/internal/ledger/models"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"txsystem/internal/ledger/service"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/types"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ReasonVoided answers a record command for a transaction already voided.
+const ReasonVoided = "voided"
+
+var logger = logging.For("ledger")
+
 type messageProcessor struct {
-	s *service.LedgerService
+	s       *service.LedgerService
+	replies types.ProducerConnection
 }
 
-func NewMessageProcessor(db *mongo.Database) types.MessageProcessor {
+// NewMessageProcessor is the ledger participant of the transfer saga: it
+// records and voids ledger entries on command and answers on replies.
+func NewMessageProcessor(db *mongo.Database, calendar *service.Calendar, replies types.ProducerConnection) types.MessageProcessor {
 	return &messageProcessor{
-		s: service.NewLedgerService(db),
+		s:       service.NewLedgerService(db, calendar),
+		replies: replies,
 	}
 }
 
-func (mp *messageProcessor) ProcessMessage(message string) error {
-	ctx := context.Background()
-	ledger := &models.Ledger{
-		ID:        primitive.NewObjectID(),
-		CreatedAt: time.Now(),
+func (mp *messageProcessor) ProcessMessage(ctx context.Context, message string) error {
+	var cmd types.SagaCommand
+	if err := json.Unmarshal([]byte(message), &cmd); err != nil {
+		return fmt.Errorf("failed to decode saga command: %w", err)
 	}
-	err := mp.s.CreateLedger(ctx, ledger)
+
+	var reason string
+	switch cmd.Action {
+	case types.SagaRecord:
+		err := mp.s.Record(ctx, cmd.TransactionID, cmd.SourceAccount, cmd.DestinationAccount, cmd.Amount, cmd.Fee)
+		if errors.Is(err, service.ErrVoided) {
+			reason = ReasonVoided
+		} else if err != nil {
+			mp.warnClosed(ctx, cmd, err)
+			return err
+		}
+	case types.SagaVoid:
+		if err := mp.s.Void(ctx, cmd.TransactionID); err != nil {
+			mp.warnClosed(ctx, cmd, err)
+			return err
+		}
+	default:
+		logger.WarnContext(ctx, "unknown saga command, skipping", "action", cmd.Action, "saga_id", cmd.SagaID)
+		return nil
+	}
+
+	payload, err := json.Marshal(types.SagaReply{
+		SagaID:        cmd.SagaID,
+		TransactionID: cmd.TransactionID,
+		Action:        cmd.Action,
+		OK:            reason == "",
+		Reason:        reason,
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to encode saga reply: %w", err)
+	}
+	if err := mp.replies.Produce(ctx, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish saga reply: %w", err)
 	}
 	return nil
 }
+
+// warnClosed notes a command that raced the close of a business date. The
+// command is retried and its entries land in the next open date.
+func (mp *messageProcessor) warnClosed(ctx context.Context, cmd types.SagaCommand, err error) {
+	if errors.Is(err, service.ErrDayClosed) {
+		logger.WarnContext(ctx, "business date closed while recording, retrying", "action", cmd.Action, "saga_id", cmd.SagaID, "error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"txsystem/pkg/common/config"
+)
+
+// Calendar maps times to business dates. A business date runs from the
+// cut-off of the day before up to its own cut-off, in the calendar's
+// timezone. Dates are returned as midnight UTC of the calendar day.
+type Calendar struct {
+	location *time.Location
+	cutoff   time.Duration
+}
+
+// MidnightUTC closes each business date at midnight UTC. It suits tools that
+// only read the ledger.
+var MidnightUTC = &Calendar{location: time.UTC, cutoff: 24 * time.Hour}
+
+func NewCalendar(cfg config.EOD) (*Calendar, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone %q: %w", cfg.Timezone, err)
+	}
+	return &Calendar{location: location, cutoff: cfg.Cutoff}, nil
+}
+
+// CutoffAt returns the instant the business date ends. A 24h cut-off ends
+// it at midnight of the following day.
+func (c *Calendar) CutoffAt(date time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, int(c.cutoff/time.Hour), int(c.cutoff%time.Hour/time.Minute), 0, 0, c.location)
+}
+
+// DateOf returns the business date t falls on.
+func (c *Calendar) DateOf(t time.Time) time.Time {
+	y, m, d := t.In(c.location).Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if !t.Before(c.CutoffAt(date)) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// Window returns the instants the business date covers, from start
+// inclusive to end exclusive.
+func (c *Calendar) Window(date time.Time) (start, end time.Time) {
+	return c.CutoffAt(date.AddDate(0, 0, -1)), c.CutoffAt(date)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"txsystem/internal/ledger/models"
+)
+
+// chain returns n linked entries of one account.
+func chain(n int) []models.Ledger {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	entries := make([]models.Ledger, n)
+	prev := &models.Ledger{}
+	for i := range entries {
+		e := &entries[i]
+		*e = models.Ledger{
+			TransactionID: uint64(100 + i),
+			AccountID:     "42",
+			LedgerAccount: models.AccountCustomerDeposits,
+			Side:          models.SideCredit,
+			Type:          models.EntryCredit,
+			Amount:        float64(i+1) * 10.5,
+			CreatedAt:     created.Add(time.Duration(i) * time.Minute),
+			BusinessDate:  "2024-03-01",
+			Seq:           prev.Seq + 1,
+			PrevHash:      prev.Hash,
+		}
+		e.Hash = e.ComputeHash()
+		prev = e
+	}
+	return entries
+}
+
+// verify walks entries as VerifyChain walks the stored chain and returns the
+// sequence number where the first link breaks and why, or 0 and "".
+func verify(entries []models.Ledger) (int64, string) {
+	prev := &models.Ledger{}
+	for i := range entries {
+		if problem := linkProblem(prev, &entries[i]); problem != "" {
+			return prev.Seq + 1, problem
+		}
+		prev = &entries[i]
+	}
+	return 0, ""
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		tamper      func([]models.Ledger) []models.Ledger
+		wantSeq     int64
+		wantProblem string
+	}{
+		{name: "intact", tamper: func(es []models.Ledger) []models.Ledger { return es }},
+		{name: "empty", tamper: func([]models.Ledger) []models.Ledger { return nil }},
+		{
+			name:   "truncated tail stays intact",
+			tamper: func(es []models.Ledger) []models.Ledger { return es[:3] },
+		},
+		{
+			name:        "amount changed",
+			tamper:      func(es []models.Ledger) []models.Ledger { es[2].Amount = 1e6; return es },
+			wantSeq:     3,
+			wantProblem: "hash does not match",
+		},
+		{
+			name:        "business date moved",
+			tamper:      func(es []models.Ledger) []models.Ledger { es[1].BusinessDate = "2024-03-02"; return es },
+			wantSeq:     2,
+			wantProblem: "hash does not match",
+		},
+		{
+			name: "created at moved",
+			tamper: func(es []models.Ledger) []models.Ledger {
+				es[0].CreatedAt = es[0].CreatedAt.Add(time.Millisecond)
+				return es
+			},
+			wantSeq:     1,
+			wantProblem: "hash does not match",
+		},
+		{
+			name: "entry rehashed after a change",
+			tamper: func(es []models.Ledger) []models.Ledger {
+				es[1].Amount = -5
+				es[1].Hash = es[1].ComputeHash()
+				return es
+			},
+			wantSeq:     3,
+			wantProblem: "prev_hash does not match",
+		},
+		{
+			name:        "entry removed",
+			tamper:      func(es []models.Ledger) []models.Ledger { return append(es[:1], es[2:]...) },
+			wantSeq:     2,
+			wantProblem: "expected seq 2, found 3",
+		},
+		{
+			name:        "first entry removed",
+			tamper:      func(es []models.Ledger) []models.Ledger { return es[1:] },
+			wantSeq:     1,
+			wantProblem: "expected seq 1, found 2",
+		},
+		{
+			name: "entries swapped",
+			tamper: func(es []models.Ledger) []models.Ledger {
+				es[1], es[2] = es[2], es[1]
+				return es
+			},
+			wantSeq:     2,
+			wantProblem: "expected seq 2, found 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, problem := verify(tt.tamper(chain(5)))
+			if seq != tt.wantSeq {
+				t.Errorf("broken at seq %d (%s), want %d", seq, problem, tt.wantSeq)
+			}
+			if !strings.Contains(problem, tt.wantProblem) || (tt.wantProblem == "" && problem != "") {
+				t.Errorf("problem = %q, want %q", problem, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestComputeHashOptionalFields(t *testing.T) {
+	legacy := models.Ledger{
+		TransactionID: 1,
+		AccountID:     "42",
+		Type:          models.EntryDebit,
+		Amount:        -10,
+		CreatedAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Seq:           1,
+	}
+	// Entries written before the chart and business dates hash their
+	// original fields only, so adding the fields later cannot break them.
+	withChart := legacy
+	withChart.LedgerAccount = models.AccountCustomerDeposits
+	withChart.Side = models.SideDebit
+	withDate := withChart
+	withDate.BusinessDate = "2024-01-01"
+
+	hashes := map[string]bool{}
+	for _, e := range []models.Ledger{legacy, withChart, withDate} {
+		hashes[e.ComputeHash()] = true
+	}
+	if len(hashes) != 3 {
+		t.Errorf("optional fields do not change the hash: %d distinct hashes", len(hashes))
+	}
+
+	// CreatedAt is hashed at millisecond precision, as MongoDB stores it.
+	rounded := legacy
+	rounded.CreatedAt = legacy.CreatedAt.Add(999 * time.Microsecond)
+	if rounded.ComputeHash() != legacy.ComputeHash() {
+		t.Error("sub-millisecond CreatedAt changes the hash")
+	}
+}
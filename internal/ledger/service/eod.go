@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"txsystem/internal/ledger/models"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrDayClosed     = errors.New("business date is closed")
+	ErrDayNotClosed  = errors.New("business date is not closed")
+	ErrDayOpen       = errors.New("business date has not reached its cut-off")
+	ErrDayOutOfOrder = errors.New("previous business date is not closed")
+)
+
+var eodLogger = logging.For("eod")
+
+// balanceKey identifies the balance of an account on a ledger account.
+type balanceKey struct {
+	AccountID     string `bson:"account_id"`
+	LedgerAccount string `bson:"ledger_account"`
+}
+
+// RunEOD closes business dates as they fall due until ctx is done.
+func (s *LedgerService) RunEOD(ctx context.Context, cfg config.EOD) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.CloseDue(ctx, cfg.Delay)
+			if err != nil {
+				eodLogger.Error("failed to close business dates", "error", err)
+			}
+			if n > 0 {
+				eodLogger.Info("closed business dates", "days", n)
+			}
+		}
+	}
+}
+
+// CloseDue closes, in order, every business date after the last close whose
+// cut-off passed at least delay ago, so dates missed while no instance was
+// running are caught up. The first run only closes the latest such date;
+// the entries before it make up its opening balances.
+func (s *LedgerService) CloseDue(ctx context.Context, delay time.Duration) (int, error) {
+	latest := s.calendar.DateOf(time.Now().Add(-delay)).AddDate(0, 0, -1)
+	last, err := s.lastClosed(ctx)
+	if err != nil {
+		return 0, err
+	}
+	day := latest
+	if last != "" {
+		lastDay, err := time.Parse(time.DateOnly, last)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse last business date %q: %w", last, err)
+		}
+		day = lastDay.AddDate(0, 0, 1)
+	}
+
+	n := 0
+	for ; !day.After(latest); day = day.AddDate(0, 0, 1) {
+		if _, err := s.CloseDay(ctx, day); err != nil {
+			return n, fmt.Errorf("failed to close %s: %w", day.Format(time.DateOnly), err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// CloseDay closes the business date once its cut-off has passed: it writes
+// a closing marker, so no entry lands in the date while it is totaled,
+// stores the opening and closing balance of every account with a balance or
+// entries, then finalizes the marker into the day summary. Dates close in
+// order, and closing a closed date returns its summary; a marker left by an
+// interrupted close is picked up again. Dates before the first close are
+// part of its opening balances and cannot be closed on their own.
+func (s *LedgerService) CloseDay(ctx context.Context, date time.Time) (*models.DayClose, error) {
+	day := date.Format(time.DateOnly)
+	closed, err := s.DayClose(ctx, date)
+	if err == nil {
+		return closed, nil
+	}
+	if !errors.Is(err, ErrDayNotClosed) {
+		return nil, err
+	}
+	cutoffAt := s.calendar.CutoffAt(date)
+	if time.Now().Before(cutoffAt) {
+		return nil, ErrDayOpen
+	}
+	last, err := s.lastClosed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if last != "" && day < last {
+		return nil, ErrDayClosed
+	}
+	if last != "" && day != nextDate(last) {
+		return nil, ErrDayOutOfOrder
+	}
+
+	marker := models.DayClose{BusinessDate: day, Status: models.DayClosing, CutoffAt: cutoffAt.UTC()}
+	if _, err := s.closes.InsertOne(ctx, marker); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to mark %s as closing: %w", day, err)
+	}
+
+	balances, err := s.openingBalances(ctx, last, day)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.totals(ctx, bson.M{"business_date": day})
+	if err != nil {
+		return nil, err
+	}
+	lines := dayLines(day, balances, movements)
+	if err := s.storeBalances(ctx, lines); err != nil {
+		return nil, err
+	}
+
+	summary := summarize(day, lines)
+	summary.CutoffAt = cutoffAt.UTC()
+	summary.ClosedAt = time.Now().UTC()
+	res, err := s.closes.ReplaceOne(ctx, bson.M{"_id": day, "status": models.DayClosing}, summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record close of %s: %w", day, err)
+	}
+	if res.MatchedCount == 0 {
+		// Another instance closed the date first.
+		return s.DayClose(ctx, date)
+	}
+	eodLogger.Info("business date closed", "date", day, "accounts", summary.Accounts, "entries", summary.Entries, "balanced", summary.Balanced)
+	return summary, nil
+}
+
+// dayLines adds the day's movements to the opening balances and returns a
+// line per account with a balance or entries, ordered by ledger account and
+// account.
+func dayLines(day string, opening, movements map[balanceKey]*models.DayBalance) []models.DayBalance {
+	for key, m := range movements {
+		b, ok := opening[key]
+		if !ok {
+			b = &models.DayBalance{AccountID: key.AccountID, LedgerAccount: key.LedgerAccount}
+			opening[key] = b
+		}
+		b.Debits, b.Credits, b.Entries = m.Debits, m.Credits, m.Entries
+	}
+
+	lines := make([]models.DayBalance, 0, len(opening))
+	for _, b := range opening {
+		if b.Opening == 0 && b.Entries == 0 {
+			continue
+		}
+		b.BusinessDate = day
+		b.Closing = b.Opening + b.Credits - b.Debits
+		lines = append(lines, *b)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].LedgerAccount != lines[j].LedgerAccount {
+			return lines[i].LedgerAccount < lines[j].LedgerAccount
+		}
+		return lines[i].AccountID < lines[j].AccountID
+	})
+	return lines
+}
+
+// summarize rolls the lines up per ledger account into a closed summary of
+// the day, without its cut-off and close times.
+func summarize(day string, lines []models.DayBalance) *models.DayClose {
+	summary := &models.DayClose{
+		BusinessDate:   day,
+		Status:         models.DayClosed,
+		Accounts:       len(lines),
+		LedgerAccounts: []models.DayBalance{},
+	}
+	byLedgerAccount := map[string]int{}
+	for _, l := range lines {
+		i, ok := byLedgerAccount[l.LedgerAccount]
+		if !ok {
+			i = len(summary.LedgerAccounts)
+			byLedgerAccount[l.LedgerAccount] = i
+			summary.LedgerAccounts = append(summary.LedgerAccounts, models.DayBalance{BusinessDate: day, LedgerAccount: l.LedgerAccount})
+		}
+		r := &summary.LedgerAccounts[i]
+		r.Opening += l.Opening
+		r.Debits += l.Debits
+		r.Credits += l.Credits
+		r.Closing += l.Closing
+		r.Entries += l.Entries
+		summary.Entries += l.Entries
+		summary.TotalDebits += l.Debits
+		summary.TotalCredits += l.Credits
+	}
+	summary.Balanced = math.Abs(summary.TotalDebits-summary.TotalCredits) < balanceTolerance
+	return summary
+}
+
+// DayClose returns the summary of a closed business date, or
+// ErrDayNotClosed, also while the date is still closing.
+func (s *LedgerService) DayClose(ctx context.Context, date time.Time) (*models.DayClose, error) {
+	var summary models.DayClose
+	err := s.closes.FindOne(ctx, bson.M{"_id": date.Format(time.DateOnly), "status": bson.M{"$ne": models.DayClosing}}).Decode(&summary)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrDayNotClosed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find close: %w", err)
+	}
+	return &summary, nil
+}
+
+// DayBalances returns the balances of the account on a closed business
+// date, one per ledger account it posts to.
+func (s *LedgerService) DayBalances(ctx context.Context, date time.Time, accountID string) ([]models.DayBalance, error) {
+	if _, err := s.DayClose(ctx, date); err != nil {
+		return nil, err
+	}
+	cursor, err := s.balances.Find(ctx,
+		bson.M{"business_date": date.Format(time.DateOnly), "account_id": accountID},
+		options.Find().SetSort(bson.D{{Key: "ledger_account", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find balances: %w", err)
+	}
+	balances := []models.DayBalance{}
+	if err := cursor.All(ctx, &balances); err != nil {
+		return nil, fmt.Errorf("failed to read balances: %w", err)
+	}
+	return balances, nil
+}
+
+// ListByBusinessDate returns the entries of the business date. Entries
+// written before business dates are matched by their creation time.
+func (s *LedgerService) ListByBusinessDate(ctx context.Context, date time.Time) ([]*models.Ledger, error) {
+	start, end := s.calendar.Window(date)
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"$or": []bson.M{
+			{"business_date": date.Format(time.DateOnly)},
+			{"business_date": bson.M{"$exists": false}, "created_at": bson.M{"$gte": start, "$lt": end}},
+		}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entries: %w", err)
+	}
+	ledgers := []*models.Ledger{}
+	if err := cursor.All(ctx, &ledgers); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
+	return ledgers, nil
+}
+
+// openingBalances returns the closing balances of the last close, or, before
+// the first close, the totals of every entry before the date.
+func (s *LedgerService) openingBalances(ctx context.Context, last, day string) (map[balanceKey]*models.DayBalance, error) {
+	if last == "" {
+		totals, err := s.totals(ctx, bson.M{"$or": []bson.M{
+			{"business_date": bson.M{"$lt": day}},
+			{"business_date": bson.M{"$exists": false}},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		opening := make(map[balanceKey]*models.DayBalance, len(totals))
+		for key, t := range totals {
+			opening[key] = &models.DayBalance{AccountID: key.AccountID, LedgerAccount: key.LedgerAccount, Opening: t.Credits - t.Debits}
+		}
+		return opening, nil
+	}
+
+	cursor, err := s.balances.Find(ctx, bson.M{"business_date": last})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find balances of %s: %w", last, err)
+	}
+	var previous []models.DayBalance
+	if err := cursor.All(ctx, &previous); err != nil {
+		return nil, fmt.Errorf("failed to read balances of %s: %w", last, err)
+	}
+	opening := make(map[balanceKey]*models.DayBalance, len(previous))
+	for _, p := range previous {
+		key := balanceKey{AccountID: p.AccountID, LedgerAccount: p.LedgerAccount}
+		opening[key] = &models.DayBalance{AccountID: p.AccountID, LedgerAccount: p.LedgerAccount, Opening: p.Closing}
+	}
+	return opening, nil
+}
+
+// totals sums the entries matching match per account and ledger account.
+func (s *LedgerService) totals(ctx context.Context, match bson.M) (map[balanceKey]*models.DayBalance, error) {
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"account_id": "$account_id",
+				// Entries written before the chart are customer entries.
+				"ledger_account": bson.M{"$ifNull": bson.A{"$ledger_account", models.AccountCustomerDeposits}},
+			},
+			"debits":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$lt": bson.A{"$amount", 0}}, bson.M{"$multiply": bson.A{"$amount", -1}}, 0}}},
+			"credits": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$amount", 0}}, "$amount", 0}}},
+			"entries": bson.M{"$sum": 1},
+		}},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total ledger entries: %w", err)
+	}
+	var rows []struct {
+		Key     balanceKey `bson:"_id"`
+		Debits  float64    `bson:"debits"`
+		Credits float64    `bson:"credits"`
+		Entries int64      `bson:"entries"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to read ledger totals: %w", err)
+	}
+	totals := make(map[balanceKey]*models.DayBalance, len(rows))
+	for _, r := range rows {
+		totals[r.Key] = &models.DayBalance{
+			AccountID:     r.Key.AccountID,
+			LedgerAccount: r.Key.LedgerAccount,
+			Debits:        r.Debits,
+			Credits:       r.Credits,
+			Entries:       r.Entries,
+		}
+	}
+	return totals, nil
+}
+
+// storeBalances writes the balances, replacing any left by an interrupted
+// close of the same date.
+func (s *LedgerService) storeBalances(ctx context.Context, lines []models.DayBalance) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(lines))
+	for _, l := range lines {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"business_date": l.BusinessDate, "account_id": l.AccountID, "ledger_account": l.LedgerAccount}).
+			SetReplacement(l).
+			SetUpsert(true))
+	}
+	if _, err := s.balances.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to store balances: %w", err)
+	}
+	return nil
+}
+
+// lastClosed returns the latest closed business date, or "" before the
+// first close.
+func (s *LedgerService) lastClosed(ctx context.Context) (string, error) {
+	return s.latestClose(ctx, bson.M{"status": bson.M{"$ne": models.DayClosing}})
+}
+
+// lastClosing returns the latest business date that is closed or being
+// closed, after which entries may still be dated, or "" before the first
+// close.
+func (s *LedgerService) lastClosing(ctx context.Context) (string, error) {
+	return s.latestClose(ctx, bson.M{})
+}
+
+func (s *LedgerService) latestClose(ctx context.Context, filter bson.M) (string, error) {
+	var last models.DayClose
+	err := s.closes.FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{"_id": 1}),
+	).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find last close: %w", err)
+	}
+	return last.BusinessDate, nil
+}
+
+// nextDate returns the date after day, both as YYYY-MM-DD.
+func nextDate(day string) string {
+	t, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, 0, 1).Format(time.DateOnly)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+	"txsystem/internal/ledger/models"
+	"txsystem/pkg/common/config"
+)
+
+func TestCalendar(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		cutoff   time.Duration
+		at       string
+		want     string
+	}{
+		{name: "midnight cut-off, late evening", timezone: "UTC", cutoff: 24 * time.Hour, at: "2024-03-01T23:59:59Z", want: "2024-03-01"},
+		{name: "midnight cut-off, at midnight", timezone: "UTC", cutoff: 24 * time.Hour, at: "2024-03-02T00:00:00Z", want: "2024-03-02"},
+		{name: "17:00 cut-off, before", timezone: "UTC", cutoff: 17 * time.Hour, at: "2024-03-01T16:59:59Z", want: "2024-03-01"},
+		{name: "17:00 cut-off, at the cut-off", timezone: "UTC", cutoff: 17 * time.Hour, at: "2024-03-01T17:00:00Z", want: "2024-03-02"},
+		{name: "17:30 cut-off", timezone: "UTC", cutoff: 17*time.Hour + 30*time.Minute, at: "2024-03-01T17:15:00Z", want: "2024-03-01"},
+		{name: "month end rolls over", timezone: "UTC", cutoff: 17 * time.Hour, at: "2024-02-29T18:00:00Z", want: "2024-03-01"},
+		{name: "local midnight before UTC midnight", timezone: "Europe/Berlin", cutoff: 24 * time.Hour, at: "2024-03-01T23:30:00Z", want: "2024-03-02"},
+		{name: "local date behind UTC", timezone: "America/New_York", cutoff: 24 * time.Hour, at: "2024-03-02T03:00:00Z", want: "2024-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCalendar(config.EOD{Timezone: tt.timezone, Cutoff: tt.cutoff})
+			if err != nil {
+				t.Fatalf("NewCalendar: %v", err)
+			}
+			at := date(t, time.RFC3339, tt.at)
+			got := c.DateOf(at)
+			if got.Format(time.DateOnly) != tt.want {
+				t.Fatalf("DateOf(%s) = %s, want %s", tt.at, got.Format(time.DateOnly), tt.want)
+			}
+			start, end := c.Window(got)
+			if at.Before(start) || !at.Before(end) {
+				t.Errorf("%s is outside the window [%s, %s) of its date", tt.at, start, end)
+			}
+			if !end.Equal(c.CutoffAt(got)) || !start.Equal(c.CutoffAt(got.AddDate(0, 0, -1))) {
+				t.Errorf("window [%s, %s) does not run between cut-offs", start, end)
+			}
+		})
+	}
+}
+
+func date(t *testing.T, layout, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(layout, s)
+	if err != nil {
+		t.Fatalf("bad time %s: %v", s, err)
+	}
+	return d
+}
+
+func TestNextDate(t *testing.T) {
+	tests := []struct{ day, want string }{
+		{"2024-02-28", "2024-02-29"},
+		{"2024-02-29", "2024-03-01"},
+		{"2023-02-28", "2023-03-01"},
+		{"2024-12-31", "2025-01-01"},
+		{"not a date", ""},
+	}
+	for _, tt := range tests {
+		if got := nextDate(tt.day); got != tt.want {
+			t.Errorf("nextDate(%q) = %q, want %q", tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestDayLines(t *testing.T) {
+	const day = "2024-03-01"
+	key := func(account, ledgerAccount string) balanceKey {
+		return balanceKey{AccountID: account, LedgerAccount: ledgerAccount}
+	}
+	opening := func(account, ledgerAccount string, amount float64) *models.DayBalance {
+		return &models.DayBalance{AccountID: account, LedgerAccount: ledgerAccount, Opening: amount}
+	}
+	moved := func(account, ledgerAccount string, debits, credits float64, entries int64) *models.DayBalance {
+		return &models.DayBalance{AccountID: account, LedgerAccount: ledgerAccount, Debits: debits, Credits: credits, Entries: entries}
+	}
+	tests := []struct {
+		name      string
+		opening   map[balanceKey]*models.DayBalance
+		movements map[balanceKey]*models.DayBalance
+		want      []models.DayBalance
+	}{
+		{
+			name: "nothing",
+			want: []models.DayBalance{},
+		},
+		{
+			name:    "quiet account keeps its balance",
+			opening: map[balanceKey]*models.DayBalance{key("1", "customer_deposits"): opening("1", "customer_deposits", 100)},
+			want: []models.DayBalance{
+				{BusinessDate: day, AccountID: "1", LedgerAccount: "customer_deposits", Opening: 100, Closing: 100},
+			},
+		},
+		{
+			name: "movements on opened and new accounts",
+			opening: map[balanceKey]*models.DayBalance{
+				key("1", "customer_deposits"): opening("1", "customer_deposits", 100),
+				key("1", "fee_income"):        opening("1", "fee_income", 0),
+			},
+			movements: map[balanceKey]*models.DayBalance{
+				key("1", "customer_deposits"): moved("1", "customer_deposits", 31, 0, 2),
+				key("2", "customer_deposits"): moved("2", "customer_deposits", 0, 30, 1),
+				key("1", "fee_income"):        moved("1", "fee_income", 0, 1, 1),
+			},
+			want: []models.DayBalance{
+				{BusinessDate: day, AccountID: "1", LedgerAccount: "customer_deposits", Opening: 100, Debits: 31, Closing: 69, Entries: 2},
+				{BusinessDate: day, AccountID: "2", LedgerAccount: "customer_deposits", Credits: 30, Closing: 30, Entries: 1},
+				{BusinessDate: day, AccountID: "1", LedgerAccount: "fee_income", Credits: 1, Closing: 1, Entries: 1},
+			},
+		},
+		{
+			name:    "closed out account with no entries is dropped",
+			opening: map[balanceKey]*models.DayBalance{key("3", "customer_deposits"): opening("3", "customer_deposits", 0)},
+			want:    []models.DayBalance{},
+		},
+		{
+			name:      "entries netting to zero are kept",
+			movements: map[balanceKey]*models.DayBalance{key("4", "customer_deposits"): moved("4", "customer_deposits", 5, 5, 2)},
+			want: []models.DayBalance{
+				{BusinessDate: day, AccountID: "4", LedgerAccount: "customer_deposits", Debits: 5, Credits: 5, Entries: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opening == nil {
+				tt.opening = map[balanceKey]*models.DayBalance{}
+			}
+			if got := dayLines(day, tt.opening, tt.movements); !slices.Equal(got, tt.want) {
+				t.Errorf("lines =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	const day = "2024-03-01"
+	tests := []struct {
+		name         string
+		lines        []models.DayBalance
+		wantBalanced bool
+		wantRollup   []models.DayBalance
+	}{
+		{
+			name:         "empty day",
+			wantBalanced: true,
+			wantRollup:   []models.DayBalance{},
+		},
+		{
+			name: "transfer with fee",
+			lines: []models.DayBalance{
+				{AccountID: "1", LedgerAccount: "customer_deposits", Opening: 100, Debits: 31, Closing: 69, Entries: 2},
+				{AccountID: "2", LedgerAccount: "customer_deposits", Credits: 30, Closing: 30, Entries: 1},
+				{AccountID: "1", LedgerAccount: "fee_income", Credits: 1, Closing: 1, Entries: 1},
+			},
+			wantBalanced: true,
+			wantRollup: []models.DayBalance{
+				{BusinessDate: day, LedgerAccount: "customer_deposits", Opening: 100, Debits: 31, Credits: 30, Closing: 99, Entries: 3},
+				{BusinessDate: day, LedgerAccount: "fee_income", Credits: 1, Closing: 1, Entries: 1},
+			},
+		},
+		{
+			name: "one-sided entry",
+			lines: []models.DayBalance{
+				{AccountID: "1", LedgerAccount: "customer_deposits", Debits: 10, Closing: -10, Entries: 1},
+			},
+			wantRollup: []models.DayBalance{
+				{BusinessDate: day, LedgerAccount: "customer_deposits", Debits: 10, Closing: -10, Entries: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summarize(day, tt.lines)
+			if s.BusinessDate != day || s.Status != models.DayClosed || s.Accounts != len(tt.lines) {
+				t.Errorf("summary = %s %s with %d accounts, want %s closed with %d", s.BusinessDate, s.Status, s.Accounts, day, len(tt.lines))
+			}
+			if s.Balanced != tt.wantBalanced {
+				t.Errorf("balanced = %v with debits %v and credits %v, want %v", s.Balanced, s.TotalDebits, s.TotalCredits, tt.wantBalanced)
+			}
+			var entries int64
+			for _, l := range tt.lines {
+				entries += l.Entries
+			}
+			if s.Entries != entries {
+				t.Errorf("entries = %d, want %d", s.Entries, entries)
+			}
+			if !slices.Equal(s.LedgerAccounts, tt.wantRollup) {
+				t.Errorf("ledger accounts =\n%+v\nwant\n%+v", s.LedgerAccounts, tt.wantRollup)
+			}
+		})
+	}
+}
@@ -2,32 +2,331 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 	"txsystem/internal/ledger/models"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrVoided = errors.New("transaction was voided")
+
+// maxAppendAttempts bounds how often appendEntry retries after losing a race
+// for the next sequence number of an account.
+const maxAppendAttempts = 10
+
 type LedgerService struct {
 	collection *mongo.Collection
+	voids      *mongo.Collection
+	closes     *mongo.Collection
+	balances   *mongo.Collection
+	calendar   *Calendar
 }
 
-func NewLedgerService(db *mongo.Database) *LedgerService {
+// NewLedgerService dates entries into business dates with calendar.
+func NewLedgerService(db *mongo.Database, calendar *Calendar) *LedgerService {
 	return &LedgerService{
 		collection: db.Collection("ledger"),
+		voids:      db.Collection("ledger_voids"),
+		closes:     db.Collection("ledger_eod"),
+		balances:   db.Collection("ledger_balances"),
+		calendar:   calendar,
 	}
 }
 
-func (s *LedgerService) CreateLedger(ctx context.Context, ledger *models.Ledger) error {
-	ledger.CreatedAt = time.Now()
-	_, err := s.collection.InsertOne(ctx, ledger)
-	if err != nil {
+// Record appends the debit and credit entries of a transfer, and moves a
+// non-zero fee from the source to fee income. Customer accounts post to
+// customer deposits and system accounts, such as gl-5000 paying interest,
+// to their own ledger account. Each entry is written at most once, so Record
+// can be repeated. It returns ErrVoided once the transaction has been
+// voided, and ErrDayClosed when an entry would land in a closed business
+// date; repeating it later dates the entry into the open one.
+func (s *LedgerService) Record(ctx context.Context, transactionID uint64, source, destination string, amount, fee float64) error {
+	err := s.voids.FindOne(ctx, bson.M{"_id": transactionID}).Err()
+	if err == nil {
+		return ErrVoided
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to check void: %w", err)
+	}
+
+	debit := models.Ledger{
+		TransactionID: transactionID,
+		AccountID:     source,
+		LedgerAccount: models.LedgerAccountOf(source),
+		Type:          models.EntryDebit,
+		Amount:        -amount,
+	}
+	if err := s.appendEntry(ctx, models.LegDebit, debit); err != nil {
+		return err
+	}
+	credit := models.Ledger{
+		TransactionID: transactionID,
+		AccountID:     destination,
+		LedgerAccount: models.LedgerAccountOf(destination),
+		Type:          models.EntryCredit,
+		Amount:        amount,
+	}
+	if err := s.appendEntry(ctx, models.LegCredit, credit); err != nil {
 		return err
 	}
+	if fee == 0 {
+		return nil
+	}
+
+	charge := models.Ledger{
+		TransactionID: transactionID,
+		AccountID:     source,
+		LedgerAccount: models.AccountCustomerDeposits,
+		Type:          models.EntryFee,
+		Amount:        -fee,
+	}
+	if err := s.appendEntry(ctx, models.LegFee, charge); err != nil {
+		return err
+	}
+	income := models.Ledger{
+		TransactionID: transactionID,
+		AccountID:     models.SystemAccountID(models.AccountFeeIncome),
+		LedgerAccount: models.AccountFeeIncome,
+		Type:          models.EntryFee,
+		Amount:        fee,
+	}
+	return s.appendEntry(ctx, models.LegFeeIncome, income)
+}
+
+// Void stops the transaction from being recorded and reverses any entries
+// it already has. It can be repeated.
+func (s *LedgerService) Void(ctx context.Context, transactionID uint64) error {
+	_, err := s.voids.UpdateOne(ctx,
+		bson.M{"_id": transactionID},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record void: %w", err)
+	}
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"transaction_id": transactionID,
+		"type":           bson.M{"$in": []string{models.EntryDebit, models.EntryCredit, models.EntryFee}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find entries: %w", err)
+	}
+	var entries []models.Ledger
+	if err := cursor.All(ctx, &entries); err != nil {
+		return fmt.Errorf("failed to read entries: %w", err)
+	}
+	for _, e := range entries {
+		// Entries written before keys are the debit and credit legs.
+		leg := e.Type
+		if _, keyed, ok := strings.Cut(e.Key, ":"); ok {
+			leg = keyed
+		}
+		ledgerAccount := e.LedgerAccount
+		if ledgerAccount == "" {
+			ledgerAccount = models.AccountCustomerDeposits
+		}
+		reversal := models.Ledger{
+			TransactionID: transactionID,
+			AccountID:     e.AccountID,
+			LedgerAccount: ledgerAccount,
+			Type:          models.EntryReversal,
+			Amount:        -e.Amount,
+		}
+		if err := s.appendEntry(ctx, models.ReversalLeg(leg), reversal); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// EnsureIndexes creates the unique indexes that keep each account chain
+// linear and each entry written once. Entries written before chaining or
+// keys lack the field and are left out. It also indexes entries by
+// business date and keeps one balance per account and date.
+func (s *LedgerService) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"seq": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"key": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "business_date", Value: 1}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ledger indexes: %w", err)
+	}
+	_, err = s.balances.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "business_date", Value: 1}, {Key: "account_id", Value: 1}, {Key: "ledger_account", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create balance indexes: %w", err)
+	}
+	return nil
+}
+
+// appendEntry adds the entry for the transaction's leg to the end of the
+// account chain unless it was written already, so redelivered commands
+// write nothing twice. Entries are only ever inserted; nothing in the ledger
+// is updated or deleted. The caller sets the transaction, accounts, type and
+// amount; the rest is filled in here. The entry is dated into the business
+// date of its creation, which must not be closed or closing yet.
+func (s *LedgerService) appendEntry(ctx context.Context, leg string, entry models.Ledger) error {
+	entry.Key = models.EntryKey(entry.TransactionID, leg)
+	entry.Side = models.SideCredit
+	if entry.Amount < 0 {
+		entry.Side = models.SideDebit
+	}
+	written := bson.M{"$or": []bson.M{
+		{"key": entry.Key},
+		// Entries written before keys.
+		{"key": bson.M{"$exists": false}, "transaction_id": entry.TransactionID, "account_id": entry.AccountID, "type": entry.Type},
+	}}
+	for range maxAppendAttempts {
+		err := s.collection.FindOne(ctx, written).Err()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("failed to check %s entry: %w", entry.Type, err)
+		}
+
+		tip, err := s.tip(ctx, entry.AccountID)
+		if err != nil {
+			return err
+		}
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+		entry.BusinessDate = s.calendar.DateOf(entry.CreatedAt).Format(time.DateOnly)
+		last, err := s.lastClosing(ctx)
+		if err != nil {
+			return err
+		}
+		if entry.BusinessDate <= last {
+			return fmt.Errorf("failed to write %s entry for %s: %w", entry.Type, entry.BusinessDate, ErrDayClosed)
+		}
+		entry.Seq = tip.Seq + 1
+		entry.PrevHash = tip.Hash
+		entry.Hash = entry.ComputeHash()
+
+		_, err = s.collection.InsertOne(ctx, entry)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another writer took the sequence number or wrote the entry.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s entry: %w", entry.Type, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("failed to write %s entry: account %s kept changing", entry.Type, entry.AccountID)
+}
+
+// tip returns the last chained entry of the account, or a zero entry when
+// the chain is empty.
+func (s *LedgerService) tip(ctx context.Context, accountID string) (*models.Ledger, error) {
+	var tip models.Ledger
+	err := s.collection.FindOne(ctx,
+		bson.M{"account_id": accountID, "seq": bson.M{"$exists": true}},
+		options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}),
+	).Decode(&tip)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to find chain tip: %w", err)
+	}
+	return &tip, nil
+}
+
+// ChainBreak is the first entry of an account chain that does not link up.
+type ChainBreak struct {
+	AccountID string
+	Seq       int64
+	EntryID   primitive.ObjectID
+	Problem   string
+}
+
+// ChainAccounts returns every account with chained entries.
+func (s *LedgerService) ChainAccounts(ctx context.Context) ([]string, error) {
+	values, err := s.collection.Distinct(ctx, "account_id", bson.M{"seq": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger accounts: %w", err)
+	}
+	accounts := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok {
+			accounts = append(accounts, id)
+		}
+	}
+	return accounts, nil
+}
+
+// VerifyChain walks the account chain from the first entry and returns the
+// first broken link, or nil and the tip when the chain is intact. Removing
+// entries from the end leaves a shorter intact chain, so keep the tips to
+// compare against.
+func (s *LedgerService) VerifyChain(ctx context.Context, accountID string) (*ChainBreak, *models.Ledger, error) {
+	cursor, err := s.collection.Find(ctx,
+		bson.M{"account_id": accountID, "seq": bson.M{"$exists": true}},
+		options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chain: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	prev := &models.Ledger{}
+	for cursor.Next(ctx) {
+		var e models.Ledger
+		if err := cursor.Decode(&e); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode entry: %w", err)
+		}
+		if problem := linkProblem(prev, &e); problem != "" {
+			return &ChainBreak{AccountID: accountID, Seq: prev.Seq + 1, EntryID: e.ID, Problem: problem}, nil, nil
+		}
+		prev = &e
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read chain: %w", err)
+	}
+	return nil, prev, nil
+}
+
+// linkProblem describes why e cannot follow prev in a chain, or returns ""
+// when it can. The first entry follows an empty prev.
+func linkProblem(prev, e *models.Ledger) string {
+	switch {
+	case e.Seq != prev.Seq+1:
+		return fmt.Sprintf("expected seq %d, found %d", prev.Seq+1, e.Seq)
+	case e.PrevHash != prev.Hash:
+		return "prev_hash does not match the previous entry"
+	case e.Hash != e.ComputeHash():
+		return "hash does not match the entry content"
+	}
+	return ""
+}
+
+// Unchained counts the entries written before chaining, which verification
+// cannot cover.
+func (s *LedgerService) Unchained(ctx context.Context) (int64, error) {
+	n, err := s.collection.CountDocuments(ctx, bson.M{"seq": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unchained entries: %w", err)
+	}
+	return n, nil
+}
+
 func (s *LedgerService) ListLedgers(ctx context.Context) ([]*models.Ledger, error) {
 	cursor, err := s.collection.Find(ctx, bson.M{})
 	if err != nil {
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+	"txsystem/internal/ledger/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// balanceTolerance absorbs float rounding when comparing the totals.
+const balanceTolerance = 1e-6
+
+// TrialBalanceLine totals the entries of one ledger account. Balance is
+// signed towards the account's normal balance, so it is positive for an
+// asset with more debits than credits.
+type TrialBalanceLine struct {
+	models.LedgerAccount
+	Debits  float64 `json:"debits"`
+	Credits float64 `json:"credits"`
+	Balance float64 `json:"balance"`
+}
+
+// TrialBalance lists every ledger account with its totals. Balanced reports
+// whether total debits equal total credits across the book.
+type TrialBalance struct {
+	AsOf         *time.Time         `json:"as_of,omitempty"`
+	Lines        []TrialBalanceLine `json:"lines"`
+	TotalDebits  float64            `json:"total_debits"`
+	TotalCredits float64            `json:"total_credits"`
+	Balanced     bool               `json:"balanced"`
+}
+
+// TrialBalance totals every entry created before asOf, or every entry when
+// asOf is nil. Each account of the chart gets a line, and so does any other
+// code found on entries, so nothing is left out of the totals.
+func (s *LedgerService) TrialBalance(ctx context.Context, asOf *time.Time) (*TrialBalance, error) {
+	match := bson.M{}
+	if asOf != nil {
+		match["created_at"] = bson.M{"$lt": *asOf}
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			// Entries written before the chart are customer entries.
+			"_id":     bson.M{"$ifNull": bson.A{"$ledger_account", models.AccountCustomerDeposits}},
+			"debits":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$lt": bson.A{"$amount", 0}}, bson.M{"$multiply": bson.A{"$amount", -1}}, 0}}},
+			"credits": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$amount", 0}}, "$amount", 0}}},
+		}},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total ledger entries: %w", err)
+	}
+	var totals []struct {
+		Code    string  `bson:"_id"`
+		Debits  float64 `bson:"debits"`
+		Credits float64 `bson:"credits"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, fmt.Errorf("failed to read ledger totals: %w", err)
+	}
+
+	byCode := make(map[string]int, len(totals))
+	for i, t := range totals {
+		byCode[t.Code] = i
+	}
+	tb := &TrialBalance{AsOf: asOf, Lines: []TrialBalanceLine{}}
+	add := func(account models.LedgerAccount) {
+		line := TrialBalanceLine{LedgerAccount: account}
+		if i, ok := byCode[account.Code]; ok {
+			line.Debits = totals[i].Debits
+			line.Credits = totals[i].Credits
+			delete(byCode, account.Code)
+		}
+		line.Balance = line.Credits - line.Debits
+		if account.NormalBalance == models.SideDebit {
+			line.Balance = -line.Balance
+		}
+		tb.Lines = append(tb.Lines, line)
+		tb.TotalDebits += line.Debits
+		tb.TotalCredits += line.Credits
+	}
+	for _, account := range models.ChartOfAccounts {
+		add(account)
+	}
+	for _, t := range totals {
+		if _, ok := byCode[t.Code]; ok {
+			add(models.LedgerAccount{Code: t.Code, Name: "Unknown"})
+		}
+	}
+	tb.Balanced = math.Abs(tb.TotalDebits-tb.TotalCredits) < balanceTolerance
+	return tb, nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type SagaStatus string
+
+const (
+	SagaRunning      SagaStatus = "running"
+	SagaCompensating SagaStatus = "compensating"
+	// SagaCompleted, SagaFailed and SagaCompensated are terminal. A failed
+	// saga was rejected before anything needed undoing.
+	SagaCompleted   SagaStatus = "completed"
+	SagaFailed      SagaStatus = "failed"
+	SagaCompensated SagaStatus = "compensated"
+)
+
+// Saga drives one transfer through the account and ledger participants.
+// Step is the action awaiting a reply, sent Attempts times; DeadlineAt is
+// when it is sent again. Both are cleared once the saga ends.
+type Saga struct {
+	ID                 uint `gorm:"primaryKey;autoIncrement"`
+	TransactionID      uint
+	Amount             float64
+	Fee                float64
+	SourceAccount      string
+	DestinationAccount string
+	TransactionType    string
+	Status             SagaStatus
+	Step               string
+	Attempts           int
+	FailureReason      string
+	DeadlineAt         *time.Time
+	CreatedAt          time.Time `gorm:"autoCreateTime"`
+	UpdatedAt          time.Time `gorm:"autoUpdateTime"`
+}
+
+func (s *Saga) Terminal() bool {
+	switch s.Status {
+	case SagaCompleted, SagaFailed, SagaCompensated:
+		return true
+	}
+	return false
+}
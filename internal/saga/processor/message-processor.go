@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"txsystem/internal/saga/service"
+	"txsystem/pkg/common/types"
+)
+
+type transactionProcessor struct {
+	coordinator *service.Coordinator
+}
+
+// NewTransactionProcessor starts a saga for each transaction event.
+func NewTransactionProcessor(c *service.Coordinator) types.MessageProcessor {
+	return &transactionProcessor{coordinator: c}
+}
+
+func (p *transactionProcessor) ProcessMessage(ctx context.Context, message string) error {
+	var event types.TransactionResponse
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		return fmt.Errorf("failed to decode transaction event: %w", err)
+	}
+	return p.coordinator.Start(ctx, &event)
+}
+
+type replyProcessor struct {
+	coordinator *service.Coordinator
+}
+
+// NewReplyProcessor feeds participant replies to the coordinator.
+func NewReplyProcessor(c *service.Coordinator) types.MessageProcessor {
+	return &replyProcessor{coordinator: c}
+}
+
+func (p *replyProcessor) ProcessMessage(ctx context.Context, message string) error {
+	var reply types.SagaReply
+	if err := json.Unmarshal([]byte(message), &reply); err != nil {
+		return fmt.Errorf("failed to decode saga reply: %w", err)
+	}
+	return p.coordinator.HandleReply(ctx, &reply)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"txsystem/internal/saga/models"
+	txrepository "txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SagaRepository interface {
+	// Create stores s unless its transaction has a saga already, and reports
+	// whether it was stored.
+	Create(ctx context.Context, s *models.Saga) (bool, error)
+	// Update locks the saga and passes it to fn, which reports whether it
+	// changed it. Changes are saved, and when the saga has ended its outcome
+	// is written to the transaction in the same database transaction. It
+	// returns nil when the saga does not exist.
+	Update(ctx context.Context, id uint, fn func(s *models.Saga) bool) (s *models.Saga, changed bool, err error)
+	// Due returns the IDs of up to limit unfinished sagas whose step
+	// deadline is at or before now.
+	Due(ctx context.Context, now time.Time, limit int) ([]uint, error)
+}
+
+type sagaRepo struct {
+	db *gorm.DB
+}
+
+func NewSagaRepository(db *gorm.DB) SagaRepository {
+	return &sagaRepo{db: db}
+}
+
+func (r *sagaRepo) Create(ctx context.Context, s *models.Saga) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "transaction_id"}}, DoNothing: true}).
+		Create(s)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *sagaRepo) Update(ctx context.Context, id uint, fn func(s *models.Saga) bool) (*models.Saga, bool, error) {
+	var s models.Saga
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, id).Error; err != nil {
+			return err
+		}
+		if changed = fn(&s); !changed {
+			return nil
+		}
+		if err := tx.Save(&s).Error; err != nil {
+			return fmt.Errorf("failed to save saga: %w", err)
+		}
+
+		var status types.TransactionStatus
+		switch s.Status {
+		case models.SagaCompleted:
+			status = types.StatusCompleted
+		case models.SagaFailed, models.SagaCompensated:
+			status = types.StatusFailed
+		default:
+			return nil
+		}
+		if err := txrepository.NewTransactionRepository(tx).UpdateStatus(ctx, s.TransactionID, status, s.FailureReason); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &s, changed, nil
+}
+
+func (r *sagaRepo) Due(ctx context.Context, now time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	result := r.db.WithContext(ctx).
+		Model(&models.Saga{}).
+		Where("status IN ? AND deadline_at <= ?", []models.SagaStatus{models.SagaRunning, models.SagaCompensating}, now).
+		Order("deadline_at").
+		Limit(limit).
+		Pluck("id", &ids)
+	return ids, result.Error
+}
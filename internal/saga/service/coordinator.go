@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"txsystem/internal/saga/models"
+	"txsystem/internal/saga/repository"
+	txrepository "txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/types"
+)
+
+// Failure reasons for sagas compensated because a step never answered.
+const (
+	ReasonTransferTimeout = "transfer_timeout"
+	ReasonLedgerTimeout   = "ledger_timeout"
+)
+
+var logger = logging.For("saga")
+
+// Coordinator runs a transfer saga for every new transaction: move the
+// balances, then record the ledger entries. When the ledger step fails or a
+// step times out it voids the ledger entries and reverses the balances, so
+// the transaction always ends completed or failed with both stores agreeing.
+type Coordinator struct {
+	sagas   repository.SagaRepository
+	txs     txrepository.TransactionRepository
+	account types.ProducerConnection
+	ledger  types.ProducerConnection
+	status  types.ProducerConnection
+	cfg     config.Saga
+	now     func() time.Time
+}
+
+// NewCoordinator sends commands on account and ledger and announces
+// settled transactions on status.
+func NewCoordinator(sagas repository.SagaRepository, txs txrepository.TransactionRepository, account, ledger, status types.ProducerConnection, cfg config.Saga) *Coordinator {
+	return &Coordinator{
+		sagas:   sagas,
+		txs:     txs,
+		account: account,
+		ledger:  ledger,
+		status:  status,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// Start begins the saga for a transaction event. Pending transactions start
+// with the balance move; completed ones (hold captures, interest) moved their
+// balances already and start with the ledger. Repeated events start nothing.
+func (c *Coordinator) Start(ctx context.Context, ev *types.TransactionResponse) error {
+	var step string
+	switch types.TransactionStatus(ev.Status) {
+	case types.StatusPending:
+		step = types.SagaTransfer
+	case types.StatusCompleted:
+		step = types.SagaRecord
+	default:
+		return nil
+	}
+
+	s := &models.Saga{
+		TransactionID:      uint(ev.ID),
+		Amount:             ev.Amount,
+		Fee:                ev.Fee,
+		SourceAccount:      ev.SourceAccount,
+		DestinationAccount: ev.DestinationAccount,
+		TransactionType:    ev.TransactionType,
+		Status:             models.SagaRunning,
+	}
+	c.enter(s, step)
+	created, err := c.sagas.Create(ctx, s)
+	if err != nil {
+		return fmt.Errorf("failed to create saga: %w", err)
+	}
+	if !created {
+		return nil
+	}
+	return c.send(ctx, s)
+}
+
+// HandleReply moves the saga on when the reply answers its current step.
+// Late and repeated replies are ignored.
+func (c *Coordinator) HandleReply(ctx context.Context, r *types.SagaReply) error {
+	s, changed, err := c.sagas.Update(ctx, uint(r.SagaID), func(s *models.Saga) bool {
+		if s.Terminal() || s.Step != r.Action {
+			return false
+		}
+		c.advance(s, r)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update saga %d: %w", r.SagaID, err)
+	}
+	if s == nil {
+		logger.WarnContext(ctx, "reply for unknown saga, skipping", "saga_id", r.SagaID)
+		return nil
+	}
+	return c.after(ctx, s, changed)
+}
+
+func (c *Coordinator) advance(s *models.Saga, r *types.SagaReply) {
+	switch r.Action {
+	case types.SagaTransfer:
+		if r.OK {
+			// The ledger records the overdraft fee as part of the fee.
+			s.Fee += r.OverdraftFee
+			c.enter(s, types.SagaRecord)
+		} else {
+			s.FailureReason = r.Reason
+			c.finish(s, models.SagaFailed)
+		}
+	case types.SagaRecord:
+		if r.OK {
+			c.finish(s, models.SagaCompleted)
+		} else {
+			c.compensate(s, r.Reason, types.SagaVoid)
+		}
+	case types.SagaVoid:
+		c.enter(s, types.SagaReverse)
+	case types.SagaReverse:
+		c.finish(s, models.SagaCompensated)
+	}
+}
+
+// Run sends unanswered steps again every PollInterval until ctx is done.
+func (c *Coordinator) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Keep draining while full batches come back.
+		for {
+			n, err := c.RunDue(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "saga timeout run failed", "error", err)
+			}
+			if err != nil || n < c.cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// RunDue handles up to BatchSize sagas whose step deadline has passed and
+// returns how many it found. A forward step is sent again up to MaxAttempts
+// times, then the saga is compensated. Compensating steps are sent again
+// until they are answered.
+func (c *Coordinator) RunDue(ctx context.Context) (int, error) {
+	ids, err := c.sagas.Due(ctx, c.now().UTC(), c.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find due sagas: %w", err)
+	}
+	for _, id := range ids {
+		if err := c.timeout(ctx, id); err != nil {
+			logger.ErrorContext(ctx, "failed to handle saga timeout", "saga_id", id, "error", err)
+		}
+	}
+	return len(ids), nil
+}
+
+func (c *Coordinator) timeout(ctx context.Context, id uint) error {
+	now := c.now().UTC()
+	s, changed, err := c.sagas.Update(ctx, id, func(s *models.Saga) bool {
+		// Another coordinator may have handled it since it was found.
+		if s.Terminal() || s.DeadlineAt == nil || s.DeadlineAt.After(now) {
+			return false
+		}
+		metrics.SagaStepTimeouts.WithLabelValues(s.Step).Inc()
+		if s.Status == models.SagaRunning && s.Attempts >= c.cfg.MaxAttempts {
+			if s.Step == types.SagaTransfer {
+				// The move may or may not have happened; reversing is safe
+				// either way.
+				c.compensate(s, ReasonTransferTimeout, types.SagaReverse)
+			} else {
+				c.compensate(s, ReasonLedgerTimeout, types.SagaVoid)
+			}
+			return true
+		}
+		if s.Status == models.SagaCompensating && s.Attempts >= c.cfg.MaxAttempts {
+			logger.WarnContext(ctx, "compensation step still unanswered", "saga_id", s.ID, "step", s.Step, "attempts", s.Attempts)
+		}
+		s.Attempts++
+		c.schedule(s)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+	return c.after(ctx, s, changed)
+}
+
+// after sends the command for the step the saga entered, or announces the
+// outcome once it has ended. Outcomes are announced again for repeated
+// replies, in case the first announcement was lost.
+func (c *Coordinator) after(ctx context.Context, s *models.Saga, changed bool) error {
+	if s.Terminal() {
+		if changed {
+			metrics.SagasFinished.WithLabelValues(string(s.Status)).Inc()
+			logger.InfoContext(ctx, "saga finished", "saga_id", s.ID, "transaction_id", s.TransactionID,
+				"status", s.Status, "reason", s.FailureReason)
+		}
+		return c.announce(ctx, s)
+	}
+	if !changed {
+		return nil
+	}
+	return c.send(ctx, s)
+}
+
+func (c *Coordinator) enter(s *models.Saga, step string) {
+	s.Step = step
+	s.Attempts = 1
+	c.schedule(s)
+}
+
+func (c *Coordinator) compensate(s *models.Saga, reason, step string) {
+	s.Status = models.SagaCompensating
+	s.FailureReason = reason
+	c.enter(s, step)
+}
+
+func (c *Coordinator) finish(s *models.Saga, status models.SagaStatus) {
+	s.Status = status
+	s.Step = ""
+	s.Attempts = 0
+	s.DeadlineAt = nil
+}
+
+func (c *Coordinator) schedule(s *models.Saga) {
+	deadline := c.now().UTC().Add(c.cfg.StepTimeout)
+	s.DeadlineAt = &deadline
+}
+
+// send publishes the command for the saga's current step. If it fails the
+// step times out and is sent again.
+func (c *Coordinator) send(ctx context.Context, s *models.Saga) error {
+	payload, err := json.Marshal(types.SagaCommand{
+		SagaID:             uint64(s.ID),
+		TransactionID:      uint64(s.TransactionID),
+		Action:             s.Step,
+		Amount:             s.Amount,
+		Fee:                s.Fee,
+		SourceAccount:      s.SourceAccount,
+		DestinationAccount: s.DestinationAccount,
+		TransactionType:    s.TransactionType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode saga command: %w", err)
+	}
+
+	participant := c.account
+	if s.Step == types.SagaRecord || s.Step == types.SagaVoid {
+		participant = c.ledger
+	}
+	if err := participant.Produce(ctx, string(payload)); err != nil {
+		return fmt.Errorf("failed to send %s command: %w", s.Step, err)
+	}
+	return nil
+}
+
+// announce publishes the settled status of the saga's transaction. An error
+// is returned so the consumer retries; the retry only announces again.
+func (c *Coordinator) announce(ctx context.Context, s *models.Saga) error {
+	tx, err := c.txs.GetByID(ctx, s.TransactionID)
+	if err != nil {
+		return fmt.Errorf("failed to load transaction: %w", err)
+	}
+	if tx == nil {
+		return nil
+	}
+
+	var eventType string
+	switch tx.Status {
+	case types.StatusCompleted:
+		eventType = types.EventTransactionCompleted
+	case types.StatusFailed:
+		eventType = types.EventTransactionFailed
+	default:
+		return nil
+	}
+
+	payload, err := json.Marshal(types.TransactionStatusEvent{
+		EventID:            fmt.Sprintf("tx-%d-%s", tx.ID, tx.Status),
+		Type:               eventType,
+		TransactionID:      uint64(tx.ID),
+		Reference:          tx.TransactionID,
+		Status:             string(tx.Status),
+		FailureReason:      tx.FailureReason,
+		Amount:             tx.Amount,
+		SourceAccount:      tx.SourceAccount,
+		DestinationAccount: tx.DestinationAccount,
+		TransactionType:    tx.TransactionType,
+		OccurredAt:         c.now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode status event: %w", err)
+	}
+	if err := c.status.Produce(ctx, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish status event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+	"txsystem/internal/saga/models"
+	txmodels "txsystem/internal/transaction/models"
+	txrepository "txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/types"
+)
+
+// fakeSagas keeps sagas in memory and, like the real repository, writes
+// the outcome of an ended saga to its transaction.
+type fakeSagas struct {
+	sagas map[uint]*models.Saga
+	txs   *fakeTxs
+}
+
+func (f *fakeSagas) Create(_ context.Context, s *models.Saga) (bool, error) {
+	for _, existing := range f.sagas {
+		if existing.TransactionID == s.TransactionID {
+			return false, nil
+		}
+	}
+	s.ID = uint(len(f.sagas) + 1)
+	saved := *s
+	f.sagas[s.ID] = &saved
+	return true, nil
+}
+
+func (f *fakeSagas) Update(_ context.Context, id uint, fn func(s *models.Saga) bool) (*models.Saga, bool, error) {
+	stored, ok := f.sagas[id]
+	if !ok {
+		return nil, false, nil
+	}
+	s := *stored
+	if !fn(&s) {
+		return stored, false, nil
+	}
+	f.sagas[id] = &s
+	switch s.Status {
+	case models.SagaCompleted:
+		f.txs.settle(s.TransactionID, types.StatusCompleted, s.FailureReason)
+	case models.SagaFailed, models.SagaCompensated:
+		f.txs.settle(s.TransactionID, types.StatusFailed, s.FailureReason)
+	}
+	return &s, true, nil
+}
+
+func (f *fakeSagas) Due(context.Context, time.Time, int) ([]uint, error) {
+	return nil, nil
+}
+
+type fakeTxs struct {
+	txrepository.TransactionRepository
+	txs map[uint]*txmodels.Transaction
+}
+
+func (f *fakeTxs) GetByID(_ context.Context, id uint) (*txmodels.Transaction, error) {
+	return f.txs[id], nil
+}
+
+func (f *fakeTxs) settle(id uint, status types.TransactionStatus, reason string) {
+	if tx, ok := f.txs[id]; ok {
+		tx.Status, tx.FailureReason = status, reason
+	}
+}
+
+// fakeProducer records the messages produced on one topic.
+type fakeProducer struct {
+	messages []string
+}
+
+func (f *fakeProducer) Produce(_ context.Context, message string) error {
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func (f *fakeProducer) ProduceBatch(_ context.Context, messages []string) error {
+	f.messages = append(f.messages, messages...)
+	return nil
+}
+
+func (f *fakeProducer) Close()            {}
+func (f *fakeProducer) IsConnected() bool { return true }
+
+type harness struct {
+	c                       *Coordinator
+	sagas                   *fakeSagas
+	txs                     *fakeTxs
+	account, ledger, status *fakeProducer
+	now                     time.Time
+}
+
+func newHarness() *harness {
+	h := &harness{
+		txs:     &fakeTxs{txs: map[uint]*txmodels.Transaction{}},
+		account: &fakeProducer{},
+		ledger:  &fakeProducer{},
+		status:  &fakeProducer{},
+		now:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	h.sagas = &fakeSagas{sagas: map[uint]*models.Saga{}, txs: h.txs}
+	cfg := config.Saga{StepTimeout: 30 * time.Second, MaxAttempts: 3, BatchSize: 10}
+	h.c = NewCoordinator(h.sagas, h.txs, h.account, h.ledger, h.status, cfg)
+	h.c.now = func() time.Time { return h.now }
+	return h
+}
+
+// seed stores saga 1, for transaction 1, in the given state.
+func (h *harness) seed(status models.SagaStatus, step string, attempts int) {
+	h.txs.txs[1] = &txmodels.Transaction{ID: 1, Amount: 50, Status: types.StatusPending}
+	deadline := h.now.Add(30 * time.Second)
+	s := &models.Saga{ID: 1, TransactionID: 1, Amount: 50, Fee: 1, Status: status, Step: step, Attempts: attempts, DeadlineAt: &deadline}
+	if s.Terminal() {
+		s.Attempts, s.DeadlineAt = 0, nil
+	}
+	h.sagas.sagas[1] = s
+}
+
+func commands(t *testing.T, p *fakeProducer) []string {
+	t.Helper()
+	actions := []string{}
+	for _, m := range p.messages {
+		var cmd types.SagaCommand
+		if err := json.Unmarshal([]byte(m), &cmd); err != nil {
+			t.Fatalf("bad command %s: %v", m, err)
+		}
+		actions = append(actions, cmd.Action)
+	}
+	return actions
+}
+
+func events(t *testing.T, p *fakeProducer) []string {
+	t.Helper()
+	kinds := []string{}
+	for _, m := range p.messages {
+		var ev struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal([]byte(m), &ev); err != nil {
+			t.Fatalf("bad event %s: %v", m, err)
+		}
+		kinds = append(kinds, ev.Type)
+	}
+	return kinds
+}
+
+func TestCoordinatorStart(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      types.TransactionStatus
+		repeat      bool
+		wantStep    string
+		wantAccount []string
+		wantLedger  []string
+	}{
+		{name: "pending moves the balances", status: types.StatusPending, wantStep: types.SagaTransfer, wantAccount: []string{types.SagaTransfer}, wantLedger: []string{}},
+		{name: "completed goes to the ledger", status: types.StatusCompleted, wantStep: types.SagaRecord, wantAccount: []string{}, wantLedger: []string{types.SagaRecord}},
+		{name: "failed starts nothing", status: types.StatusFailed, wantAccount: []string{}, wantLedger: []string{}},
+		{name: "repeated event starts once", status: types.StatusPending, repeat: true, wantStep: types.SagaTransfer, wantAccount: []string{types.SagaTransfer}, wantLedger: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness()
+			ev := &types.TransactionResponse{ID: 1, Amount: 50, Fee: 1, Status: string(tt.status)}
+			runs := 1
+			if tt.repeat {
+				runs = 2
+			}
+			for range runs {
+				if err := h.c.Start(context.Background(), ev); err != nil {
+					t.Fatalf("Start: %v", err)
+				}
+			}
+			if got := commands(t, h.account); !slices.Equal(got, tt.wantAccount) {
+				t.Errorf("account commands = %v, want %v", got, tt.wantAccount)
+			}
+			if got := commands(t, h.ledger); !slices.Equal(got, tt.wantLedger) {
+				t.Errorf("ledger commands = %v, want %v", got, tt.wantLedger)
+			}
+			if tt.wantStep == "" {
+				if len(h.sagas.sagas) != 0 {
+					t.Errorf("saga created for a %s transaction", tt.status)
+				}
+				return
+			}
+			s := h.sagas.sagas[1]
+			if s == nil || s.Step != tt.wantStep || s.Attempts != 1 || s.Status != models.SagaRunning {
+				t.Fatalf("saga = %+v, want running at %s", s, tt.wantStep)
+			}
+			if !s.DeadlineAt.Equal(h.now.Add(30 * time.Second)) {
+				t.Errorf("deadline = %s, want one step timeout from now", s.DeadlineAt)
+			}
+		})
+	}
+}
+
+func TestCoordinatorHandleReply(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      models.SagaStatus
+		step        string
+		reply       types.SagaReply
+		wantStatus  models.SagaStatus
+		wantStep    string
+		wantReason  string
+		wantFee     float64
+		wantAccount []string
+		wantLedger  []string
+		wantEvents  []string
+	}{
+		{
+			name: "transfer applied goes to the ledger with the overdraft fee", status: models.SagaRunning, step: types.SagaTransfer,
+			reply:      types.SagaReply{Action: types.SagaTransfer, OK: true, OverdraftFee: 2},
+			wantStatus: models.SagaRunning, wantStep: types.SagaRecord, wantFee: 3,
+			wantAccount: []string{}, wantLedger: []string{types.SagaRecord}, wantEvents: []string{},
+		},
+		{
+			name: "transfer rejected fails without compensation", status: models.SagaRunning, step: types.SagaTransfer,
+			reply:      types.SagaReply{Action: types.SagaTransfer, Reason: "insufficient_funds"},
+			wantStatus: models.SagaFailed, wantReason: "insufficient_funds", wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{}, wantEvents: []string{types.EventTransactionFailed},
+		},
+		{
+			name: "recorded completes", status: models.SagaRunning, step: types.SagaRecord,
+			reply:      types.SagaReply{Action: types.SagaRecord, OK: true},
+			wantStatus: models.SagaCompleted, wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{}, wantEvents: []string{types.EventTransactionCompleted},
+		},
+		{
+			name: "ledger rejection voids the entries", status: models.SagaRunning, step: types.SagaRecord,
+			reply:      types.SagaReply{Action: types.SagaRecord, Reason: "chain_broken"},
+			wantStatus: models.SagaCompensating, wantStep: types.SagaVoid, wantReason: "chain_broken", wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{types.SagaVoid}, wantEvents: []string{},
+		},
+		{
+			name: "voided reverses the balances", status: models.SagaCompensating, step: types.SagaVoid,
+			reply:      types.SagaReply{Action: types.SagaVoid, OK: true},
+			wantStatus: models.SagaCompensating, wantStep: types.SagaReverse, wantFee: 1,
+			wantAccount: []string{types.SagaReverse}, wantLedger: []string{}, wantEvents: []string{},
+		},
+		{
+			name: "reversed is compensated", status: models.SagaCompensating, step: types.SagaReverse,
+			reply:      types.SagaReply{Action: types.SagaReverse, OK: true},
+			wantStatus: models.SagaCompensated, wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{}, wantEvents: []string{types.EventTransactionFailed},
+		},
+		{
+			name: "reply for another step is ignored", status: models.SagaRunning, step: types.SagaRecord,
+			reply:      types.SagaReply{Action: types.SagaTransfer, OK: true, OverdraftFee: 2},
+			wantStatus: models.SagaRunning, wantStep: types.SagaRecord, wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{}, wantEvents: []string{},
+		},
+		{
+			name: "repeated reply announces again", status: models.SagaCompleted,
+			reply:      types.SagaReply{Action: types.SagaRecord, OK: true},
+			wantStatus: models.SagaCompleted, wantFee: 1,
+			wantAccount: []string{}, wantLedger: []string{}, wantEvents: []string{types.EventTransactionCompleted},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness()
+			h.seed(tt.status, tt.step, 1)
+			if tt.status == models.SagaCompleted {
+				h.txs.txs[1].Status = types.StatusCompleted
+			}
+			tt.reply.SagaID = 1
+			if err := h.c.HandleReply(context.Background(), &tt.reply); err != nil {
+				t.Fatalf("HandleReply: %v", err)
+			}
+
+			s := h.sagas.sagas[1]
+			if s.Status != tt.wantStatus || s.Step != tt.wantStep || s.FailureReason != tt.wantReason || s.Fee != tt.wantFee {
+				t.Errorf("saga = %s at %q, reason %q, fee %v; want %s at %q, reason %q, fee %v",
+					s.Status, s.Step, s.FailureReason, s.Fee, tt.wantStatus, tt.wantStep, tt.wantReason, tt.wantFee)
+			}
+			if s.Terminal() && (s.DeadlineAt != nil || s.Attempts != 0) {
+				t.Errorf("ended saga keeps deadline %v and %d attempts", s.DeadlineAt, s.Attempts)
+			}
+			if got := commands(t, h.account); !slices.Equal(got, tt.wantAccount) {
+				t.Errorf("account commands = %v, want %v", got, tt.wantAccount)
+			}
+			if got := commands(t, h.ledger); !slices.Equal(got, tt.wantLedger) {
+				t.Errorf("ledger commands = %v, want %v", got, tt.wantLedger)
+			}
+			if got := events(t, h.status); !slices.Equal(got, tt.wantEvents) {
+				t.Errorf("status events = %v, want %v", got, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestCoordinatorTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       models.SagaStatus
+		step         string
+		attempts     int
+		elapsed      time.Duration
+		wantStatus   models.SagaStatus
+		wantStep     string
+		wantAttempts int
+		wantReason   string
+		wantAccount  []string
+		wantLedger   []string
+	}{
+		{
+			name: "not due yet", status: models.SagaRunning, step: types.SagaTransfer, attempts: 1, elapsed: 10 * time.Second,
+			wantStatus: models.SagaRunning, wantStep: types.SagaTransfer, wantAttempts: 1,
+			wantAccount: []string{}, wantLedger: []string{},
+		},
+		{
+			name: "transfer sent again", status: models.SagaRunning, step: types.SagaTransfer, attempts: 1, elapsed: time.Minute,
+			wantStatus: models.SagaRunning, wantStep: types.SagaTransfer, wantAttempts: 2,
+			wantAccount: []string{types.SagaTransfer}, wantLedger: []string{},
+		},
+		{
+			name: "transfer out of attempts is reversed", status: models.SagaRunning, step: types.SagaTransfer, attempts: 3, elapsed: time.Minute,
+			wantStatus: models.SagaCompensating, wantStep: types.SagaReverse, wantAttempts: 1, wantReason: ReasonTransferTimeout,
+			wantAccount: []string{types.SagaReverse}, wantLedger: []string{},
+		},
+		{
+			name: "record out of attempts is voided", status: models.SagaRunning, step: types.SagaRecord, attempts: 3, elapsed: time.Minute,
+			wantStatus: models.SagaCompensating, wantStep: types.SagaVoid, wantAttempts: 1, wantReason: ReasonLedgerTimeout,
+			wantAccount: []string{}, wantLedger: []string{types.SagaVoid},
+		},
+		{
+			name: "compensation is sent again past the limit", status: models.SagaCompensating, step: types.SagaReverse, attempts: 5, elapsed: time.Minute,
+			wantStatus: models.SagaCompensating, wantStep: types.SagaReverse, wantAttempts: 6,
+			wantAccount: []string{types.SagaReverse}, wantLedger: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness()
+			h.seed(tt.status, tt.step, tt.attempts)
+			h.now = h.now.Add(tt.elapsed)
+			if err := h.c.timeout(context.Background(), 1); err != nil {
+				t.Fatalf("timeout: %v", err)
+			}
+
+			s := h.sagas.sagas[1]
+			if s.Status != tt.wantStatus || s.Step != tt.wantStep || s.Attempts != tt.wantAttempts || s.FailureReason != tt.wantReason {
+				t.Errorf("saga = %s at %q, attempt %d, reason %q; want %s at %q, attempt %d, reason %q",
+					s.Status, s.Step, s.Attempts, s.FailureReason, tt.wantStatus, tt.wantStep, tt.wantAttempts, tt.wantReason)
+			}
+			if len(tt.wantAccount)+len(tt.wantLedger) > 0 && !s.DeadlineAt.Equal(h.now.Add(30*time.Second)) {
+				t.Errorf("deadline = %s, want one step timeout from now", s.DeadlineAt)
+			}
+			if got := commands(t, h.account); !slices.Equal(got, tt.wantAccount) {
+				t.Errorf("account commands = %v, want %v", got, tt.wantAccount)
+			}
+			if got := commands(t, h.ledger); !slices.Equal(got, tt.wantLedger) {
+				t.Errorf("ledger commands = %v, want %v", got, tt.wantLedger)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package fees
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"txsystem/pkg/common/logging"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var logger = logging.For("fees")
+
+// DefaultTier is the tier of accounts nobody has assigned one.
+const DefaultTier = "standard"
+
+// defaultCurrency is charged in when the source account is unknown; the
+// transfer fails at settlement anyway.
+const defaultCurrency = "USD"
+
+// Quote is the fee for a transaction and what it was based on.
+type Quote struct {
+	Fee             float64
+	Currency        string
+	ScheduleVersion int
+}
+
+type scheduleVersion struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Document  string
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (scheduleVersion) TableName() string {
+	return "fee_schedules"
+}
+
+// Engine prices transactions with the fee schedules loaded at startup.
+type Engine struct {
+	db        *gorm.DB
+	schedules *Schedules
+}
+
+// NewEngine loads the fee schedules at path and records their version in
+// the fee_schedules table, refusing a version already recorded with other
+// content. An empty path charges no fees.
+func NewEngine(ctx context.Context, path string, db *gorm.DB) (*Engine, error) {
+	e := &Engine{db: db, schedules: &Schedules{}}
+	if path == "" {
+		return e, nil
+	}
+	s, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.record(ctx, s); err != nil {
+		return nil, err
+	}
+	e.schedules = s
+	logger.Info("fee schedules loaded", "path", path, "version", s.Version)
+	return e, nil
+}
+
+func (e *Engine) record(ctx context.Context, s *Schedules) error {
+	doc, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode fee schedules: %w", err)
+	}
+	v := scheduleVersion{Version: s.Version, Document: string(doc)}
+	if err := e.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&v).Error; err != nil {
+		return fmt.Errorf("failed to record fee schedules: %w", err)
+	}
+	var stored scheduleVersion
+	if err := e.db.WithContext(ctx).First(&stored, s.Version).Error; err != nil {
+		return fmt.Errorf("failed to read fee schedules version %d: %w", s.Version, err)
+	}
+	if stored.Document != v.Document {
+		return fmt.Errorf("fee schedules version %d is already recorded with different content; raise the version", s.Version)
+	}
+	return nil
+}
+
+// Schedules returns the recorded schedules of version, or nil when the
+// version is unknown.
+func (e *Engine) Schedules(ctx context.Context, version int) (*Schedules, error) {
+	var stored scheduleVersion
+	found := e.db.WithContext(ctx).Where("version = ?", version).Limit(1).Find(&stored)
+	if found.Error != nil {
+		return nil, fmt.Errorf("failed to read fee schedules version %d: %w", version, found.Error)
+	}
+	if found.RowsAffected == 0 {
+		return nil, nil
+	}
+	var s Schedules
+	if err := json.Unmarshal([]byte(stored.Document), &s); err != nil {
+		return nil, fmt.Errorf("failed to decode fee schedules version %d: %w", version, err)
+	}
+	return &s, nil
+}
+
+// Quote prices a transfer out of sourceAccount. The currency and tier come
+// from the source account; an ID that is not a number is priced as unknown.
+func (e *Engine) Quote(ctx context.Context, transactionType, sourceAccount string, amount float64) (*Quote, error) {
+	var accounts []struct {
+		Currency string
+		Tier     string
+	}
+	if id, err := strconv.ParseUint(sourceAccount, 10, 63); err == nil {
+		err := e.db.WithContext(ctx).
+			Table("accounts").
+			Select("currency, tier").
+			Where("id = ?", id).
+			Limit(1).
+			Scan(&accounts).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up source account: %w", err)
+		}
+	}
+	currency, tier := defaultCurrency, DefaultTier
+	if len(accounts) == 1 {
+		currency, tier = accounts[0].Currency, accounts[0].Tier
+	}
+
+	q := &Quote{Currency: currency, ScheduleVersion: e.schedules.Version}
+	if sc := e.schedules.Match(transactionType, currency, tier); sc != nil {
+		q.Fee = sc.Fee(amount)
+	}
+	return q, nil
+}
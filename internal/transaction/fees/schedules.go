@@ -0,0 +1,129 @@
+package fees
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schedules is the content of the fee schedules file. Version identifies
+// the content: transactions record the version they were charged under, so
+// any change to the schedules needs a new version.
+type Schedules struct {
+	Version   int        `yaml:"version" json:"version"`
+	Schedules []Schedule `yaml:"schedules" json:"schedules"`
+}
+
+// Schedule prices the transactions that match its transaction type,
+// currency and account tier; an empty field matches anything. The fee is
+// Flat plus Percent of the amount, with Flat and Percent taken from the
+// first band that holds the amount when there is one. It is then raised to
+// Min and capped at Max; a zero Max is no cap.
+type Schedule struct {
+	TransactionType string  `yaml:"transaction_type" json:"transaction_type,omitempty"`
+	Currency        string  `yaml:"currency" json:"currency,omitempty"`
+	Tier            string  `yaml:"tier" json:"tier,omitempty"`
+	Flat            float64 `yaml:"flat" json:"flat,omitempty"`
+	Percent         float64 `yaml:"percent" json:"percent,omitempty"`
+	Bands           []Band  `yaml:"bands" json:"bands,omitempty"`
+	Min             float64 `yaml:"min" json:"min,omitempty"`
+	Max             float64 `yaml:"max" json:"max,omitempty"`
+}
+
+// Band prices amounts up to and including UpTo. The last band may leave UpTo
+// at 0 to hold every larger amount.
+type Band struct {
+	UpTo    float64 `yaml:"up_to" json:"up_to,omitempty"`
+	Flat    float64 `yaml:"flat" json:"flat,omitempty"`
+	Percent float64 `yaml:"percent" json:"percent,omitempty"`
+}
+
+// Load reads and validates a fee schedules file.
+func Load(path string) (*Schedules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fee schedules file: %w", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee schedules file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse decodes fee schedules from YAML. Unknown keys are rejected so a typo
+// cannot silently change what is charged.
+func Parse(data []byte) (*Schedules, error) {
+	var s Schedules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("fee schedules file is empty")
+		}
+		return nil, err
+	}
+
+	if s.Version < 1 {
+		return nil, errors.New("version must be at least 1")
+	}
+	for i, sc := range s.Schedules {
+		if sc.Flat < 0 || sc.Percent < 0 || sc.Min < 0 || sc.Max < 0 {
+			return nil, fmt.Errorf("schedule %d: flat, percent, min and max must not be negative", i)
+		}
+		if sc.Max > 0 && sc.Min > sc.Max {
+			return nil, fmt.Errorf("schedule %d: min must not exceed max", i)
+		}
+		last := 0.0
+		for j, b := range sc.Bands {
+			if b.Flat < 0 || b.Percent < 0 {
+				return nil, fmt.Errorf("schedule %d band %d: flat and percent must not be negative", i, j)
+			}
+			if b.UpTo == 0 && j != len(sc.Bands)-1 {
+				return nil, fmt.Errorf("schedule %d band %d: only the last band may leave up_to unset", i, j)
+			}
+			if b.UpTo != 0 && b.UpTo <= last {
+				return nil, fmt.Errorf("schedule %d band %d: up_to must increase", i, j)
+			}
+			last = b.UpTo
+		}
+	}
+	return &s, nil
+}
+
+// Match returns the first schedule that applies, or nil when none does.
+func (s *Schedules) Match(transactionType, currency, tier string) *Schedule {
+	for i := range s.Schedules {
+		sc := &s.Schedules[i]
+		if (sc.TransactionType == "" || sc.TransactionType == transactionType) &&
+			(sc.Currency == "" || sc.Currency == currency) &&
+			(sc.Tier == "" || sc.Tier == tier) {
+			return sc
+		}
+	}
+	return nil
+}
+
+// Fee prices amount, rounded to two decimals.
+func (sc *Schedule) Fee(amount float64) float64 {
+	flat, percent := sc.Flat, sc.Percent
+	for _, b := range sc.Bands {
+		if b.UpTo == 0 || amount <= b.UpTo {
+			flat, percent = b.Flat, b.Percent
+			break
+		}
+	}
+	fee := flat + amount*percent/100
+	if fee < sc.Min {
+		fee = sc.Min
+	}
+	if sc.Max > 0 && fee > sc.Max {
+		fee = sc.Max
+	}
+	return math.Round(fee*100) / 100
+}
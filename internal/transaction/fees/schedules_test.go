@@ -0,0 +1,127 @@
+package fees
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: `
+version: 3
+schedules:
+  - transaction_type: transfer
+    currency: USD
+    flat: 0.25
+    percent: 1
+    min: 0.5
+    max: 25
+    bands:
+      - {up_to: 100, flat: 0.1}
+      - {up_to: 1000, percent: 0.5}
+      - {percent: 0.25}
+`,
+		},
+		{name: "empty", yaml: "", wantErr: "empty"},
+		{name: "no version", yaml: "schedules: []", wantErr: "version must be at least 1"},
+		{name: "unknown key", yaml: "version: 1\nschedules:\n  - flat: 1\n    fixed: 2\n", wantErr: "field fixed not found"},
+		{name: "negative flat", yaml: "version: 1\nschedules:\n  - flat: -1\n", wantErr: "must not be negative"},
+		{name: "min above max", yaml: "version: 1\nschedules:\n  - {min: 5, max: 1}\n", wantErr: "min must not exceed max"},
+		{name: "min without max", yaml: "version: 1\nschedules:\n  - {min: 5}\n"},
+		{name: "negative band", yaml: "version: 1\nschedules:\n  - bands: [{up_to: 10, percent: -1}]\n", wantErr: "band 0"},
+		{name: "open band not last", yaml: "version: 1\nschedules:\n  - bands: [{flat: 1}, {up_to: 10}]\n", wantErr: "only the last band"},
+		{name: "bands not increasing", yaml: "version: 1\nschedules:\n  - bands: [{up_to: 10}, {up_to: 10}]\n", wantErr: "up_to must increase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleFee(t *testing.T) {
+	banded := Schedule{
+		Flat:    5,
+		Percent: 2,
+		Bands: []Band{
+			{UpTo: 100, Flat: 0.5},
+			{UpTo: 1000, Flat: 1, Percent: 0.5},
+		},
+	}
+	tests := []struct {
+		name     string
+		schedule Schedule
+		amount   float64
+		want     float64
+	}{
+		{name: "flat and percent", schedule: Schedule{Flat: 0.3, Percent: 2.9}, amount: 100, want: 3.2},
+		{name: "zero schedule", schedule: Schedule{}, amount: 100, want: 0},
+		{name: "first band", schedule: banded, amount: 50, want: 0.5},
+		{name: "band bound is inclusive", schedule: banded, amount: 100, want: 0.5},
+		{name: "second band", schedule: banded, amount: 100.01, want: 1.5},
+		{name: "second band bound", schedule: banded, amount: 1000, want: 6},
+		{name: "above the bands uses the schedule", schedule: banded, amount: 2000, want: 45},
+		{
+			name:     "open last band",
+			schedule: Schedule{Flat: 9, Bands: []Band{{UpTo: 10, Flat: 1}, {Percent: 1}}},
+			amount:   5000,
+			want:     50,
+		},
+		{name: "raised to min", schedule: Schedule{Percent: 1, Min: 0.5}, amount: 10, want: 0.5},
+		{name: "capped at max", schedule: Schedule{Percent: 1, Max: 25}, amount: 10000, want: 25},
+		{name: "zero max is no cap", schedule: Schedule{Percent: 1}, amount: 10000, want: 100},
+		{name: "min and max together", schedule: Schedule{Percent: 1, Min: 1, Max: 2}, amount: 150, want: 1.5},
+		{name: "rounds down below half a cent", schedule: Schedule{Percent: 0.125}, amount: 10, want: 0.01},
+		{name: "rounds up from half a cent", schedule: Schedule{Percent: 1.5}, amount: 333.33, want: 5},
+		{name: "min is rounded too", schedule: Schedule{Min: 0.123}, amount: 1, want: 0.12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Fee(tt.amount); got != tt.want {
+				t.Errorf("Fee(%v) = %v, want %v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s := &Schedules{Version: 1, Schedules: []Schedule{
+		{TransactionType: "transfer", Currency: "EUR", Tier: "premium", Flat: 1},
+		{TransactionType: "transfer", Currency: "EUR", Flat: 2},
+		{Currency: "EUR", Flat: 3},
+	}}
+	tests := []struct {
+		txType, currency, tier string
+		want                   float64
+		none                   bool
+	}{
+		{txType: "transfer", currency: "EUR", tier: "premium", want: 1},
+		{txType: "transfer", currency: "EUR", tier: "standard", want: 2},
+		{txType: "payment", currency: "EUR", tier: "premium", want: 3},
+		{txType: "transfer", currency: "USD", tier: "premium", none: true},
+	}
+	for _, tt := range tests {
+		got := s.Match(tt.txType, tt.currency, tt.tier)
+		switch {
+		case tt.none && got != nil:
+			t.Errorf("Match(%s, %s, %s) = %+v, want none", tt.txType, tt.currency, tt.tier, got)
+		case !tt.none && (got == nil || got.Flat != tt.want):
+			t.Errorf("Match(%s, %s, %s) = %+v, want the schedule with flat %v", tt.txType, tt.currency, tt.tier, got, tt.want)
+		}
+	}
+}
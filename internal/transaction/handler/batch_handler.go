@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Submit a batch of transactions
+// @Description CreateBatch accepts a JSON array, NDJSON (application/x-ndjson) or CSV (text/csv) body, or the same as a multipart upload in the "file" field. Every item is validated first; the batch is stored in one database transaction, then its events are published in one Kafka transaction.
+// @Tags transactions
+// @Accept json
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Param transactions body []types.TransactionRequest true "Transactions"
+// @Success 202 {object} types.BatchResponse "Batch accepted"
+// @Failure 400 {object} types.BatchRejectedResponse "error:invalid batch"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
+// @Failure 413 {object} map[string]string "error:batch too large"
+// @Failure 500 {object} map[string]string "error:failed to submit batch"
+// @Security BearerAuth
+// @Router /api/v1/transactions/batch [post]
+func (h *Handler) CreateBatch(c echo.Context) error {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, h.batchCfg.MaxBodyBytes)
+
+	format, body, err := batchUpload(c)
+	if err != nil {
+		return batchBodyError(c, err)
+	}
+	defer body.Close()
+
+	items, err := service.ParseBatch(format, body, h.batchCfg.MaxItems)
+	if err != nil {
+		return batchBodyError(c, err)
+	}
+
+	ctx := req.Context()
+	problems := service.ValidateBatch(items)
+	p, _ := auth.FromContext(ctx)
+	if p == nil || !p.IsAdmin() {
+		var owned []string
+		if p != nil {
+			if owned, err = h.ownership.AccountsOf(ctx, p.Subject); err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to submit batch"})
+			}
+		}
+		for i, item := range items {
+			if !slices.Contains(owned, item.SourceAccount) {
+				problems = append(problems, types.BatchItemError{Index: i, Error: "source account not accessible"})
+			}
+		}
+	}
+	if len(problems) > 0 {
+		slices.SortStableFunc(problems, func(a, b types.BatchItemError) int { return a.Index - b.Index })
+		return c.JSON(http.StatusBadRequest, types.BatchRejectedResponse{Error: "invalid batch", Items: problems})
+	}
+
+	submitter := ""
+	if p != nil {
+		submitter = p.Subject
+	}
+	resp, err := h.batches.Submit(ctx, submitter, items)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to submit batch"})
+	}
+	return c.JSON(http.StatusAccepted, resp)
+}
+
+// @Summary Get batch status
+// @Description GetBatch summarises the outcome of every item in a batch.
+// @Tags transactions
+// @Produce json
+// @Param id path string true "Batch ID"
+// @Success 200 {object} types.BatchStatusResponse "Batch status"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
+// @Failure 404 {object} map[string]string "error:batch not found"
+// @Failure 500 {object} map[string]string "error:failed to fetch batch"
+// @Security BearerAuth
+// @Router /api/v1/transactions/batch/{id} [get]
+func (h *Handler) GetBatch(c echo.Context) error {
+	ctx := c.Request().Context()
+	status, err := h.batches.Status(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrBatchNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "batch not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch batch"})
+	}
+
+	p, _ := auth.FromContext(ctx)
+	if p == nil || (!p.IsAdmin() && p.Subject != status.SubmittedBy) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "batch not found"})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// batchUpload returns the batch format and body, unwrapping a multipart
+// upload from its "file" field.
+func batchUpload(c echo.Context) (string, io.ReadCloser, error) {
+	req := c.Request()
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		return batchFormat(mediaType, ""), req.Body, nil
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, err
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	partType, _, _ := mime.ParseMediaType(fh.Header.Get(echo.HeaderContentType))
+	return batchFormat(partType, fh.Filename), file, nil
+}
+
+// batchFormat picks the format from the media type, falling back to the
+// file extension for uploads sent as application/octet-stream.
+func batchFormat(mediaType, filename string) string {
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		return service.FormatJSON
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return service.FormatNDJSON
+	case "text/csv":
+		return service.FormatCSV
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return service.FormatJSON
+	case ".ndjson", ".jsonl":
+		return service.FormatNDJSON
+	case ".csv":
+		return service.FormatCSV
+	}
+	return mediaType
+}
+
+func batchBodyError(c echo.Context, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "batch too large"})
+	}
+	return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
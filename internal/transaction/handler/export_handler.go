@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/export"
+	"txsystem/pkg/common/logging"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Export transactions
+// @Description ExportTransactions streams transactions in ID order as CSV, NDJSON or Parquet, for spreadsheets and the data warehouse. Without account only admins may export. The status is sent before the first row, so a complete export ends with an X-Export-Rows trailer holding the row count and a failed one with X-Export-Error instead.
+// @Tags transactions
+// @Produce text/csv,application/x-ndjson,application/vnd.apache.parquet
+// @Param format query string false "csv (default), ndjson or parquet"
+// @Param from query string false "First day of creation, YYYY-MM-DD (UTC)"
+// @Param to query string false "Last day of creation, YYYY-MM-DD (UTC)"
+// @Param account query string false "Only transfers touching this account"
+// @Success 200 {file} file "Export"
+// @Failure 400 {object} map[string]string "error:format must be csv, ndjson or parquet"
+// @Failure 403 {object} map[string]string "error:forbidden"
+// @Failure 404 {object} map[string]string "error:account not found"
+// @Security BearerAuth
+// @Router /api/v1/transactions/export [get]
+func (h *Handler) ExportTransactions(c echo.Context) error {
+	format, err := export.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	filter, err := export.ParseFilter(c.QueryParam("from"), c.QueryParam("to"), c.QueryParam("account"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	ctx := c.Request().Context()
+	if filter.AccountID != "" {
+		allowed, err := h.ownership.CanAccess(ctx, filter.AccountID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to export transactions"})
+		}
+		if !allowed {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "account not found"})
+		}
+	} else if p, _ := auth.FromContext(ctx); p == nil || !p.IsAdmin() {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, format.ContentType())
+	w.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filter.FileName("transactions", format)))
+	export.DeclareTrailers(w.Header())
+	w.WriteHeader(http.StatusOK)
+	// The status is sent, so a failure is only reported in the trailer.
+	rows, err := h.service.Export(ctx, filter, format, w)
+	export.SetTrailer(w.Header(), rows, err)
+	if err != nil {
+		logging.For("export").ErrorContext(ctx, "transaction export failed", "error", err)
+	}
+	return nil
+}
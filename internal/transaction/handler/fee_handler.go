@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"txsystem/internal/transaction/fees"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FeeHandler struct {
+	fees *fees.Engine
+}
+
+func NewFeeHandler(fe *fees.Engine) *FeeHandler {
+	return &FeeHandler{fees: fe}
+}
+
+// @Summary Get a fee schedules version
+// @Description GetFeeSchedules returns the fee schedules recorded under a version, as charged to the transactions carrying that fee_schedule_version.
+// @Tags fees
+// @Produce json
+// @Param version path int true "Fee schedules version"
+// @Success 200 {object} fees.Schedules "Fee schedules"
+// @Failure 400 {object} map[string]string "error:invalid version"
+// @Failure 404 {object} map[string]string "error:fee schedules version not found"
+// @Failure 500 {object} map[string]string "error:failed to get fee schedules"
+// @Security BearerAuth
+// @Router /api/v1/fees/schedules/{version} [get]
+func (h *FeeHandler) GetFeeSchedules(c echo.Context) error {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid version"})
+	}
+	s, err := h.fees.Schedules(c.Request().Context(), version)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get fee schedules"})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "fee schedules version not found"})
+	}
+	return c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary List scheduled transfers
+// @Description GetSchedules lists the schedules debiting the caller's accounts, or every schedule for admins.
+// @Tags schedules
+// @Produce json
+// @Success 200 {array} types.ScheduleResponse "List of schedules"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
+// @Failure 500 {object} map[string]string "error:failed to fetch schedules"
+// @Security BearerAuth
+// @Router /api/v1/schedules [get]
+func (h *Handler) GetSchedules(c echo.Context) error {
+	ctx := c.Request().Context()
+	p, _ := auth.FromContext(ctx)
+
+	var schedules []*types.ScheduleResponse
+	var err error
+	if p != nil && p.IsAdmin() {
+		schedules, err = h.schedules.GetSchedules(ctx)
+	} else {
+		var accounts []string
+		if p != nil {
+			accounts, err = h.ownership.AccountsOf(ctx, p.Subject)
+		}
+		if err == nil {
+			schedules, err = h.schedules.GetSchedulesForAccounts(ctx, accounts)
+		}
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch schedules"})
+	}
+
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// @Summary Get a scheduled transfer
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} types.ScheduleResponse "Schedule details"
+// @Failure 400 {object} map[string]string "error:invalid schedule ID"
+// @Failure 404 {object} map[string]string "error:schedule not found"
+// @Security BearerAuth
+// @Router /api/v1/schedules/{id} [get]
+func (h *Handler) GetSchedule(c echo.Context) error {
+	return h.scheduleAction(c, func(ctx context.Context, id uint) (*types.ScheduleResponse, error) {
+		return h.schedules.GetSchedule(ctx, id)
+	})
+}
+
+// @Summary Pause a scheduled transfer
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} types.ScheduleResponse "Paused schedule"
+// @Failure 404 {object} map[string]string "error:schedule not found"
+// @Failure 409 {object} map[string]string "error:schedule cannot change to the requested state"
+// @Security BearerAuth
+// @Router /api/v1/schedules/{id}/pause [post]
+func (h *Handler) PauseSchedule(c echo.Context) error {
+	return h.scheduleAction(c, h.schedules.Pause)
+}
+
+// @Summary Resume a paused scheduled transfer
+// @Description Occurrences that fell due while paused are skipped.
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} types.ScheduleResponse "Resumed schedule"
+// @Failure 404 {object} map[string]string "error:schedule not found"
+// @Failure 409 {object} map[string]string "error:schedule cannot change to the requested state"
+// @Security BearerAuth
+// @Router /api/v1/schedules/{id}/resume [post]
+func (h *Handler) ResumeSchedule(c echo.Context) error {
+	return h.scheduleAction(c, h.schedules.Resume)
+}
+
+// @Summary Cancel a scheduled transfer
+// @Tags schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Success 200 {object} types.ScheduleResponse "Cancelled schedule"
+// @Failure 404 {object} map[string]string "error:schedule not found"
+// @Failure 409 {object} map[string]string "error:schedule cannot change to the requested state"
+// @Security BearerAuth
+// @Router /api/v1/schedules/{id}/cancel [post]
+func (h *Handler) CancelSchedule(c echo.Context) error {
+	return h.scheduleAction(c, h.schedules.Cancel)
+}
+
+// scheduleAction loads the schedule, checks the caller owns its source
+// account and runs action on it.
+func (h *Handler) scheduleAction(c echo.Context, action func(ctx context.Context, id uint) (*types.ScheduleResponse, error)) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid schedule ID"})
+	}
+
+	ctx := c.Request().Context()
+	schedule, err := h.schedules.GetSchedule(ctx, uint(id))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	allowed, err := h.ownership.CanAccess(ctx, schedule.SourceAccount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch schedule"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": service.ErrScheduleNotFound.Error()})
+	}
+
+	schedule, err = action(ctx, uint(id))
+	if err != nil {
+		return scheduleError(c, err)
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
+
+func scheduleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrScheduleNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrScheduleState):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidSchedule):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update schedule"})
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Stream live transaction updates
+// @Description StreamTransactions sends server-sent events as transactions are created (transaction.created) and settled (transaction.completed, transaction.failed). Each event ID is a Kafka cursor; reconnecting with it in Last-Event-ID resumes after that event. Without one the stream starts at the current end.
+// @Tags transactions
+// @Produce text/event-stream
+// @Param account query string false "Only transfers touching this account"
+// @Param status query string false "Only events with this status (pending, completed, failed)"
+// @Param Last-Event-ID header string false "Resume after this event"
+// @Param last_event_id query string false "Resume after this event, for clients that cannot set headers"
+// @Success 200 {object} types.TransactionStatusEvent "Event stream"
+// @Failure 400 {object} map[string]string "error:invalid Last-Event-ID"
+// @Failure 404 {object} map[string]string "error:account not found"
+// @Failure 503 {object} map[string]string "error:too many open streams"
+// @Security BearerAuth
+// @Router /api/v1/transactions/stream [get]
+func (h *Handler) StreamTransactions(c echo.Context) error {
+	filter := service.StreamFilter{
+		Account: c.QueryParam("account"),
+		Status:  types.TransactionStatus(c.QueryParam("status")),
+	}
+	switch filter.Status {
+	case "", types.StatusPending, types.StatusCompleted, types.StatusFailed:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid status"})
+	}
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.QueryParam("last_event_id")
+	}
+	from, err := messaging.ParseCursor(lastEventID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid Last-Event-ID"})
+	}
+
+	ctx := c.Request().Context()
+	if filter.Account != "" {
+		allowed, err := h.ownership.CanAccess(ctx, filter.Account)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to open stream"})
+		}
+		if !allowed {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "account not found"})
+		}
+	}
+	if p, _ := auth.FromContext(ctx); p == nil || !p.IsAdmin() {
+		filter.Visible = []string{}
+		if p != nil {
+			owned, err := h.ownership.AccountsOf(ctx, p.Subject)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to open stream"})
+			}
+			filter.Visible = append(filter.Visible, owned...)
+		}
+	}
+
+	release, err := h.stream.Acquire()
+	if errors.Is(err, service.ErrStreamBusy) {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+	}
+	defer release()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	// Events and heartbeats are written from different goroutines.
+	var mu sync.Mutex
+	write := func(format string, args ...any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(h.streamCfg.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if write(": keepalive\n\n") != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	err = h.stream.Stream(ctx, from, filter, func(ev service.StreamEvent) error {
+		return write("id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data)
+	})
+	if err != nil && ctx.Err() == nil {
+		logging.For("stream").ErrorContext(ctx, "transaction stream failed", "error", err)
+		_ = write("event: error\ndata: {\"error\":\"stream interrupted\"}\n\n")
+	}
+	return nil
+}
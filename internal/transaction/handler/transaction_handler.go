@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"txsystem/internal/transaction/fees"
 	"txsystem/internal/transaction/repository"
 	"txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
 	"txsystem/pkg/common/types"
 
 	"github.com/labstack/echo/v4"
@@ -13,24 +19,44 @@ import (
 )
 
 type Handler struct {
-	service *service.TransactionService
+	service   *service.TransactionService
+	schedules *service.ScheduleService
+	batches   *service.BatchService
+	batchCfg  config.Batch
+	stream    *service.StreamService
+	streamCfg config.Stream
+	ownership *auth.Ownership
+	// accounts is nil when the account check is disabled.
+	accounts *service.AccountChecker
 }
 
-func NewHandler(s *service.TransactionService) *Handler {
+func NewHandler(s *service.TransactionService, ss *service.ScheduleService, bs *service.BatchService, batchCfg config.Batch, st *service.StreamService, streamCfg config.Stream, o *auth.Ownership, ac *service.AccountChecker) *Handler {
 	return &Handler{
-		service: s,
+		service:   s,
+		schedules: ss,
+		batches:   bs,
+		batchCfg:  batchCfg,
+		stream:    st,
+		streamCfg: streamCfg,
+		ownership: o,
+		accounts:  ac,
 	}
 }
 
 // @Summary Create a new transaction
-// @Description CreateTransaction handles creating a transaction.
+// @Description CreateTransaction handles creating a transaction. With execute_at or recurrence the transfer is scheduled instead and the schedule is returned. When the account check is enabled, transfers that cannot settle are rejected with 422 and a reason code (account_not_found, currency_mismatch, insufficient_funds).
 // @Tags transactions
 // @Accept json
 // @Produce json
 // @Param transaction body types.TransactionRequest true "Transaction request"
-// @Success 201 {object} map[string]string "message:transaction created"
+// @Success 201 {object} types.CreateTransactionResponse "Created transaction, with its fee"
+// @Success 202 {object} types.ScheduleResponse "Scheduled transfer"
 // @Failure 400 {object} map[string]string "error:invalid request"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
+// @Failure 403 {object} map[string]string "error:forbidden"
+// @Failure 422 {object} map[string]string "error:insufficient available balance in source account, reason:insufficient_funds"
 // @Failure 500 {object} map[string]string "error:failed to create transaction"
+// @Security BearerAuth
 // @Router /api/v1/transactions [post]
 func (h *Handler) CreateTransaction(c echo.Context) error {
 	var req types.TransactionRequest
@@ -38,11 +64,36 @@ func (h *Handler) CreateTransaction(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
 	}
 
-	if err := h.service.CreateTransaction(c.Request().Context(), &req); err != nil {
+	ctx := c.Request().Context()
+	allowed, err := h.ownership.CanAccess(ctx, req.SourceAccount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create transaction"})
+	}
+	if !allowed {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+	}
+
+	if service.IsScheduled(&req) {
+		schedule, err := h.schedules.CreateSchedule(ctx, &req)
+		if err != nil {
+			return scheduleError(c, err)
+		}
+		return c.JSON(http.StatusAccepted, schedule)
+	}
+
+	if h.accounts != nil {
+		var rejection *service.Rejection
+		if err := h.accounts.Check(ctx, &req); errors.As(err, &rejection) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": rejection.Message, "reason": rejection.Reason})
+		}
+	}
+
+	tx, err := h.service.CreateTransaction(ctx, &req)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create transaction"})
 	}
 
-	return c.JSON(http.StatusCreated, map[string]string{"message": "transaction created"})
+	return c.JSON(http.StatusCreated, types.CreateTransactionResponse{Message: "transaction created", Transaction: tx})
 }
 
 // @Summary Get transactions
@@ -52,10 +103,28 @@ func (h *Handler) CreateTransaction(c echo.Context) error {
 // @Success 200 {array} types.TransactionResponse "List of transactions"
 // @Failure 400 {object} map[string]string "error:bad request"
 // @Failure 404 {object} map[string]string "error:transactions not found"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
 // @Failure 500 {object} map[string]string "error:failed to fetch transactions"
+// @Security BearerAuth
 // @Router /api/v1/transactions [get]
 func (h *Handler) GetTransactions(c echo.Context) error {
-	transactions, err := h.service.GetTransactions(c.Request().Context())
+	ctx := c.Request().Context()
+	p, _ := auth.FromContext(ctx)
+
+	var transactions []*types.TransactionResponse
+	var err error
+	if p != nil && p.IsAdmin() {
+		transactions, err = h.service.GetTransactions(ctx)
+	} else {
+		// Non-admins only see transfers touching their own accounts.
+		var accounts []string
+		if p != nil {
+			accounts, err = h.ownership.AccountsOf(ctx, p.Subject)
+		}
+		if err == nil {
+			transactions, err = h.service.GetTransactionsForAccounts(ctx, accounts)
+		}
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch transactions"})
 	}
@@ -71,7 +140,9 @@ func (h *Handler) GetTransactions(c echo.Context) error {
 // @Success 200 {object} types.TransactionResponse "Transaction details"
 // @Failure 400 {object} map[string]string "error:invalid transaction ID"
 // @Failure 404 {object} map[string]string "error:transaction not found"
+// @Failure 401 {object} map[string]string "error:missing bearer token"
 // @Failure 500 {object} map[string]string "error:failed to fetch transaction"
+// @Security BearerAuth
 // @Router /api/v1/transactions/{id} [get]
 func (h *Handler) GetTransaction(c echo.Context) error {
 	idParam := c.Param("id")
@@ -80,7 +151,8 @@ func (h *Handler) GetTransaction(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid transaction ID"})
 	}
 
-	tx, err := h.service.GetTransaction(c.Request().Context(), uint(id))
+	ctx := c.Request().Context()
+	tx, err := h.service.GetTransaction(ctx, uint(id))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch transaction"})
 	}
@@ -88,15 +160,50 @@ func (h *Handler) GetTransaction(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "transaction not found"})
 	}
 
+	// Transactions on someone else's accounts are reported as missing so
+	// their IDs cannot be probed.
+	visible, err := h.canSee(ctx, tx)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch transaction"})
+	}
+	if !visible {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "transaction not found"})
+	}
+
 	return c.JSON(http.StatusOK, tx)
 }
 
-func InitRoutes(e *echo.Echo, kc types.ProducerConnection, db *gorm.DB) {
-	transactionService := service.NewTransactionService(kc, repository.NewTransactionRepository(db))
-	h := NewHandler(transactionService)
-	e.Logger.Info("Initializing transaction routes")
+func (h *Handler) canSee(ctx context.Context, tx *types.TransactionResponse) (bool, error) {
+	ok, err := h.ownership.CanAccess(ctx, tx.SourceAccount)
+	if err != nil || ok {
+		return ok, err
+	}
+	return h.ownership.CanAccess(ctx, tx.DestinationAccount)
+}
+
+func InitRoutes(e *echo.Echo, kc types.ProducerConnection, db *gorm.DB, cfg *config.TransactionService, accounts *service.AccountChecker, fe *fees.Engine) {
+	transactionService := service.NewTransactionService(kc, repository.NewTransactionRepository(db), fe)
+	scheduleService := service.NewScheduleService(repository.NewScheduleRepository(db))
+	batchService := service.NewBatchService(kc, repository.NewBatchRepository(db), fe)
+	streamService := service.NewStreamService(cfg.Kafka, cfg.Stream)
+	h := NewHandler(transactionService, scheduleService, batchService, cfg.Batch, streamService, cfg.Stream, auth.NewOwnership(db), accounts)
+	logging.For("http").Info("initializing transaction routes")
 	g := e.Group("/api/v1/transactions")
 	g.POST("", h.CreateTransaction)
 	g.GET("", h.GetTransactions)
+	g.GET("/stream", h.StreamTransactions)
+	g.GET("/export", h.ExportTransactions)
 	g.GET("/:id", h.GetTransaction)
+	g.POST("/batch", h.CreateBatch)
+	g.GET("/batch/:id", h.GetBatch)
+
+	sg := e.Group("/api/v1/schedules")
+	sg.GET("", h.GetSchedules)
+	sg.GET("/:id", h.GetSchedule)
+	sg.POST("/:id/pause", h.PauseSchedule)
+	sg.POST("/:id/resume", h.ResumeSchedule)
+	sg.POST("/:id/cancel", h.CancelSchedule)
+
+	fh := NewFeeHandler(fe)
+	e.GET("/api/v1/fees/schedules/:version", fh.GetFeeSchedules)
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Batch groups transactions submitted together. Per-item outcomes are read
+// from the transactions carrying its ID. PublishedAt is set once the events
+// of its transactions were published.
+type Batch struct {
+	ID          string `gorm:"primaryKey"`
+	Submitter   string
+	ItemCount   int
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	PublishedAt *time.Time
+}
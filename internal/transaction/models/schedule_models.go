@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+	"txsystem/pkg/common/types"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	SchedulePaused    ScheduleStatus = "paused"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+	ScheduleCompleted ScheduleStatus = "completed"
+)
+
+// Schedule is a future or recurring transfer. Occurrence n runs at ExecuteAt
+// advanced n periods of Frequency; NextIndex is the next n to run and
+// Occurrences counts the transfers created so far.
+type Schedule struct {
+	ID                 uint `gorm:"primaryKey;autoIncrement"`
+	Amount             float64
+	Description        string
+	SourceAccount      string
+	DestinationAccount string
+	TransactionType    string
+	ExecuteAt          time.Time
+	// Frequency is empty for a one-off transfer.
+	Frequency      types.Frequency
+	Until          *time.Time
+	MaxOccurrences int
+	NextIndex      int
+	Occurrences    int
+	NextRunAt      *time.Time
+	Status         ScheduleStatus
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
@@ -6,9 +6,13 @@ import (
 )
 
 type Transaction struct {
-	ID                 uint `gorm:"primaryKey;autoIncrement" json:"id,omitempty"`
-	Amount             float64
-	Currency           string
+	ID       uint `gorm:"primaryKey;autoIncrement" json:"id,omitempty"`
+	Amount   float64
+	Currency string
+	// Fee is charged to the source account on top of Amount, as priced by
+	// version FeeScheduleVersion of the fee schedules.
+	Fee                float64
+	FeeScheduleVersion int
 	Description        string
 	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt          time.Time `gorm:"autoUpdateTime" json:"updated_at"`
@@ -16,5 +20,9 @@ type Transaction struct {
 	DestinationAccount string
 	TransactionType    string
 	Status             types.TransactionStatus
+	FailureReason      string
 	TransactionID      string
+	// BatchID and BatchIndex place a transaction submitted in a batch.
+	BatchID    string
+	BatchIndex int
 }
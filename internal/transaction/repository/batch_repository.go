@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"txsystem/internal/transaction/models"
+
+	"gorm.io/gorm"
+)
+
+type BatchRepository interface {
+	// Create stores the batch and its transactions in one database
+	// transaction.
+	Create(ctx context.Context, b *models.Batch, txs []models.Transaction) error
+	// MarkPublished records that the batch's events were published.
+	MarkPublished(ctx context.Context, id string) error
+	// Unpublished returns up to limit batches created before before whose
+	// events were not published, oldest first.
+	Unpublished(ctx context.Context, before time.Time, limit int) ([]models.Batch, error)
+	GetByID(ctx context.Context, id string) (*models.Batch, error)
+	// Items returns the batch's transactions in submission order.
+	Items(ctx context.Context, id string) ([]models.Transaction, error)
+}
+
+type batchRepo struct {
+	db *gorm.DB
+}
+
+func NewBatchRepository(db *gorm.DB) BatchRepository {
+	return &batchRepo{db: db}
+}
+
+func (r *batchRepo) Create(ctx context.Context, b *models.Batch, txs []models.Transaction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(b).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(txs, 500).Error
+	})
+}
+
+func (r *batchRepo) MarkPublished(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Batch{}).
+		Where("id = ? AND published_at IS NULL", id).
+		Update("published_at", time.Now()).Error
+}
+
+func (r *batchRepo) Unpublished(ctx context.Context, before time.Time, limit int) ([]models.Batch, error) {
+	var batches []models.Batch
+	result := r.db.WithContext(ctx).
+		Where("published_at IS NULL AND created_at < ?", before).
+		Order("created_at").
+		Limit(limit).
+		Find(&batches)
+	return batches, result.Error
+}
+
+func (r *batchRepo) GetByID(ctx context.Context, id string) (*models.Batch, error) {
+	var b models.Batch
+	result := r.db.WithContext(ctx).First(&b, "id = ?", id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &b, result.Error
+}
+
+func (r *batchRepo) Items(ctx context.Context, id string) ([]models.Transaction, error) {
+	var txs []models.Transaction
+	result := r.db.WithContext(ctx).Where("batch_id = ?", id).Order("batch_index").Find(&txs)
+	return txs, result.Error
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"txsystem/internal/transaction/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduleRepository interface {
+	Create(ctx context.Context, s *models.Schedule) error
+	GetByID(ctx context.Context, id uint) (*models.Schedule, error)
+	Update(ctx context.Context, s *models.Schedule) error
+	List(ctx context.Context, limit, offset int) ([]models.Schedule, error)
+	ListByAccounts(ctx context.Context, accounts []string, limit, offset int) ([]models.Schedule, error)
+	// WithDue locks up to limit active schedules due at now, skipping rows
+	// another worker holds, and calls fn for each inside one transaction.
+	// Schedules fn succeeds on are saved; the first error is returned after
+	// the rest have been processed.
+	WithDue(ctx context.Context, now time.Time, limit int, fn func(s *models.Schedule) error) (int, error)
+}
+
+type scheduleRepo struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepo{db: db}
+}
+
+func (r *scheduleRepo) Create(ctx context.Context, s *models.Schedule) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *scheduleRepo) GetByID(ctx context.Context, id uint) (*models.Schedule, error) {
+	var s models.Schedule
+	result := r.db.WithContext(ctx).First(&s, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &s, result.Error
+}
+
+func (r *scheduleRepo) Update(ctx context.Context, s *models.Schedule) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+func (r *scheduleRepo) List(ctx context.Context, limit, offset int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	result := r.db.WithContext(ctx).Order("id").Limit(limit).Offset(offset).Find(&schedules)
+	return schedules, result.Error
+}
+
+func (r *scheduleRepo) ListByAccounts(ctx context.Context, accounts []string, limit, offset int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	if len(accounts) == 0 {
+		return schedules, nil
+	}
+	result := r.db.WithContext(ctx).
+		Where("source_account IN ?", accounts).
+		Order("id").Limit(limit).Offset(offset).
+		Find(&schedules)
+	return schedules, result.Error
+}
+
+func (r *scheduleRepo) WithDue(ctx context.Context, now time.Time, limit int, fn func(s *models.Schedule) error) (int, error) {
+	processed := 0
+	var firstErr error
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.Schedule
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_run_at <= ?", models.ScheduleActive, now).
+			Order("next_run_at").Limit(limit).
+			Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			if err := fn(&due[i]); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if err := tx.Save(&due[i]).Error; err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return processed, firstErr
+}
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 	"txsystem/internal/transaction/models"
+	"txsystem/pkg/common/types"
 
 	"gorm.io/gorm"
 	// adjust import path accordingly
@@ -12,9 +14,20 @@ import (
 type TransactionRepository interface {
 	Create(ctx context.Context, tx *models.Transaction) error
 	GetByID(ctx context.Context, id uint) (*models.Transaction, error)
+	GetByTransactionID(ctx context.Context, transactionID string) (*models.Transaction, error)
 	Update(ctx context.Context, tx *models.Transaction) error
+	UpdateStatus(ctx context.Context, id uint, status types.TransactionStatus, reason string) error
 	Delete(ctx context.Context, id uint) error
 	List(ctx context.Context, limit, offset int) ([]models.Transaction, error)
+	ListByAccounts(ctx context.Context, accounts []string, limit, offset int) ([]models.Transaction, error)
+	// ListAfter returns up to limit transactions with IDs above afterID in ID
+	// order. A nil accounts slice matches every transaction; otherwise only
+	// transfers touching one of accounts match. An empty status matches all.
+	ListAfter(ctx context.Context, accounts []string, status types.TransactionStatus, afterID uint, limit int) ([]models.Transaction, error)
+	// ListCreatedAfter returns up to limit transactions with IDs above
+	// afterID in ID order, created from from up to before to. A zero time
+	// leaves that end open, and an empty accountID matches every account.
+	ListCreatedAfter(ctx context.Context, from, to time.Time, accountID string, afterID uint, limit int) ([]models.Transaction, error)
 }
 
 type transactionRepo struct {
@@ -38,10 +51,26 @@ func (r *transactionRepo) GetByID(ctx context.Context, id uint) (*models.Transac
 	return &tx, result.Error
 }
 
+func (r *transactionRepo) GetByTransactionID(ctx context.Context, transactionID string) (*models.Transaction, error) {
+	var tx models.Transaction
+	result := r.db.WithContext(ctx).Where("transaction_id = ?", transactionID).First(&tx)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &tx, result.Error
+}
+
 func (r *transactionRepo) Update(ctx context.Context, tx *models.Transaction) error {
 	return r.db.WithContext(ctx).Save(tx).Error
 }
 
+func (r *transactionRepo) UpdateStatus(ctx context.Context, id uint, status types.TransactionStatus, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Transaction{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"status": status, "failure_reason": reason}).Error
+}
+
 func (r *transactionRepo) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&models.Transaction{}, id).Error
 }
@@ -51,3 +80,47 @@ func (r *transactionRepo) List(ctx context.Context, limit, offset int) ([]models
 	result := r.db.WithContext(ctx).Limit(limit).Offset(offset).Find(&transactions)
 	return transactions, result.Error
 }
+
+func (r *transactionRepo) ListByAccounts(ctx context.Context, accounts []string, limit, offset int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if len(accounts) == 0 {
+		return transactions, nil
+	}
+	result := r.db.WithContext(ctx).
+		Where("source_account IN ? OR destination_account IN ?", accounts, accounts).
+		Limit(limit).Offset(offset).
+		Find(&transactions)
+	return transactions, result.Error
+}
+
+func (r *transactionRepo) ListAfter(ctx context.Context, accounts []string, status types.TransactionStatus, afterID uint, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	if accounts != nil && len(accounts) == 0 {
+		return transactions, nil
+	}
+	q := r.db.WithContext(ctx).Where("id > ?", afterID)
+	if accounts != nil {
+		q = q.Where("source_account IN ? OR destination_account IN ?", accounts, accounts)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	result := q.Order("id").Limit(limit).Find(&transactions)
+	return transactions, result.Error
+}
+
+func (r *transactionRepo) ListCreatedAfter(ctx context.Context, from, to time.Time, accountID string, afterID uint, limit int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	q := r.db.WithContext(ctx).Where("id > ?", afterID)
+	if !from.IsZero() {
+		q = q.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("created_at < ?", to)
+	}
+	if accountID != "" {
+		q = q.Where("source_account = ? OR destination_account = ?", accountID, accountID)
+	}
+	result := q.Order("id").Limit(limit).Find(&transactions)
+	return transactions, result.Error
+}
@@ -0,0 +1,129 @@
+// Package rpc serves the transaction gRPC API from the same service as the
+// REST handlers.
+package rpc
+
+import (
+	"context"
+	"time"
+	transactionv1 "txsystem/api/transaction/v1"
+	"txsystem/internal/transaction/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/types"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	transactionv1.UnimplementedTransactionServiceServer
+	service   *service.TransactionService
+	ownership *auth.Ownership
+}
+
+func NewServer(s *service.TransactionService, o *auth.Ownership) *Server {
+	return &Server{service: s, ownership: o}
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *transactionv1.GetTransactionRequest) (*transactionv1.Transaction, error) {
+	tx, err := s.service.GetTransaction(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch transaction")
+	}
+	if tx == nil {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+
+	// Reported as missing when not visible, as in the REST API.
+	visible, err := s.visible(ctx, tx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch transaction")
+	}
+	if !visible {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+	return toProto(tx), nil
+}
+
+func (s *Server) ListTransactions(req *transactionv1.ListTransactionsRequest, stream transactionv1.TransactionService_ListTransactionsServer) error {
+	ctx := stream.Context()
+	txStatus := types.TransactionStatus(req.GetStatus())
+	switch txStatus {
+	case "", types.StatusPending, types.StatusCompleted, types.StatusFailed:
+	default:
+		return status.Error(codes.InvalidArgument, "invalid status")
+	}
+
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, auth.ErrMissingToken.Error())
+	}
+	// nil lists everything; admins only.
+	var accounts []string
+	if !p.IsAdmin() {
+		owned, err := s.ownership.AccountsOf(ctx, p.Subject)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to fetch transactions")
+		}
+		accounts = append([]string{}, owned...)
+	}
+	if account := req.GetAccount(); account != "" {
+		allowed, err := s.ownership.CanAccess(ctx, account)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to fetch transactions")
+		}
+		if !allowed {
+			return status.Error(codes.NotFound, "account not found")
+		}
+		accounts = []string{account}
+	}
+
+	err := s.service.EachTransaction(ctx, accounts, txStatus, uint(req.GetAfterId()), func(tx *types.TransactionResponse) error {
+		return stream.Send(toProto(tx))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, "failed to fetch transactions")
+	}
+	return nil
+}
+
+func (s *Server) visible(ctx context.Context, tx *types.TransactionResponse) (bool, error) {
+	ok, err := s.ownership.CanAccess(ctx, tx.SourceAccount)
+	if err != nil || ok {
+		return ok, err
+	}
+	return s.ownership.CanAccess(ctx, tx.DestinationAccount)
+}
+
+func toProto(tx *types.TransactionResponse) *transactionv1.Transaction {
+	return &transactionv1.Transaction{
+		Id:                 tx.ID,
+		Amount:             tx.Amount,
+		Currency:           tx.Currency,
+		Fee:                tx.Fee,
+		FeeScheduleVersion: int32(tx.FeeScheduleVersion),
+		Description:        tx.Description,
+		SourceAccount:      tx.SourceAccount,
+		DestinationAccount: tx.DestinationAccount,
+		TransactionType:    tx.TransactionType,
+		Status:             tx.Status,
+		FailureReason:      tx.FailureReason,
+		Reference:          tx.TransactionID,
+		CreatedAt:          timestamp(tx.CreatedAt),
+		UpdatedAt:          timestamp(tx.UpdatedAt),
+	}
+}
+
+func timestamp(s string) *timestamppb.Timestamp {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+	accountv1 "txsystem/api/account/v1"
+	"txsystem/internal/transaction/fees"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/types"
+
+	"github.com/sony/gobreaker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reason codes for transfers rejected by the account check. They match the
+// codes settlement would fail the transfer with.
+const (
+	ReasonAccountNotFound   = "account_not_found"
+	ReasonCurrencyMismatch  = "currency_mismatch"
+	ReasonInsufficientFunds = "insufficient_funds"
+)
+
+var checkLogger = logging.For("account-check")
+
+// Rejection is a transfer that cannot settle, found before it is stored.
+type Rejection struct {
+	Reason  string
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return r.Message
+}
+
+// AccountChecker asks the account service whether a transfer can settle.
+// Calls share a circuit breaker so an unavailable account service costs
+// nothing once it has tripped.
+type AccountChecker struct {
+	client  accountv1.AccountServiceClient
+	fees    *fees.Engine
+	timeout time.Duration
+	breaker *gobreaker.CircuitBreaker
+}
+
+func NewAccountChecker(client accountv1.AccountServiceClient, fe *fees.Engine, cfg config.AccountCheck) *AccountChecker {
+	return &AccountChecker{
+		client:  client,
+		fees:    fe,
+		timeout: cfg.Timeout,
+		breaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:    "account-service",
+			Timeout: cfg.BreakerCooldown,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				return counts.ConsecutiveFailures >= uint32(cfg.BreakerFailures)
+			},
+			// A missing account is an answer, not a failure.
+			IsSuccessful: func(err error) bool {
+				return err == nil || status.Code(err) == codes.NotFound
+			},
+			OnStateChange: func(name string, from, to gobreaker.State) {
+				checkLogger.Warn("circuit breaker changed state", "breaker", name, "from", from.String(), "to", to.String())
+			},
+		}),
+	}
+}
+
+// Check returns a *Rejection when either account is missing, the currencies
+// differ or the source's available balance is below the amount plus the fee
+// it will be charged. When the account service cannot answer it returns nil
+// and settlement decides.
+func (ac *AccountChecker) Check(ctx context.Context, req *types.TransactionRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, ac.timeout)
+	defer cancel()
+
+	source, err := ac.account(ctx, req.SourceAccount)
+	if err != nil {
+		return ac.unavailable(ctx, err)
+	}
+	if source == nil {
+		return ac.reject(ReasonAccountNotFound, "source account not found")
+	}
+	destination, err := ac.account(ctx, req.DestinationAccount)
+	if err != nil {
+		return ac.unavailable(ctx, err)
+	}
+	if destination == nil {
+		return ac.reject(ReasonAccountNotFound, "destination account not found")
+	}
+
+	if source.GetCurrency() != destination.GetCurrency() {
+		return ac.reject(ReasonCurrencyMismatch, "source and destination accounts use different currencies")
+	}
+	quote, err := ac.fees.Quote(ctx, req.TransactionType, req.SourceAccount, req.Amount)
+	if err != nil {
+		return ac.unavailable(ctx, err)
+	}
+	if req.Amount+quote.Fee > source.GetAvailableBalance() {
+		return ac.reject(ReasonInsufficientFunds, "insufficient available balance in source account for the amount and fee")
+	}
+	metrics.AccountChecks.WithLabelValues("passed").Inc()
+	return nil
+}
+
+// account returns nil without an error when the account does not exist.
+func (ac *AccountChecker) account(ctx context.Context, id string) (*accountv1.Account, error) {
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	res, err := ac.breaker.Execute(func() (interface{}, error) {
+		return ac.client.GetAccount(ctx, &accountv1.GetAccountRequest{Id: parsed})
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res.(*accountv1.Account), nil
+}
+
+func (ac *AccountChecker) reject(reason, message string) error {
+	metrics.AccountChecks.WithLabelValues("rejected").Inc()
+	return &Rejection{Reason: reason, Message: message}
+}
+
+func (ac *AccountChecker) unavailable(ctx context.Context, err error) error {
+	metrics.AccountChecks.WithLabelValues("unavailable").Inc()
+	checkLogger.WarnContext(ctx, "account check skipped", "error", err)
+	return nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"txsystem/internal/transaction/fees"
+	"txsystem/internal/transaction/models"
+	"txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/types"
+
+	"github.com/google/uuid"
+)
+
+// Batch upload formats accepted by ParseBatch.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+var (
+	ErrInvalidBatch  = errors.New("invalid batch")
+	ErrBatchNotFound = errors.New("batch not found")
+)
+
+var batchLogger = logging.For("batch")
+
+// republishLimit is how many unpublished batches one sweep publishes.
+const republishLimit = 100
+
+// csvColumns are the recognised CSV header names. amount, source_account and
+// destination_account are required; the order is free.
+var csvColumns = []string{"amount", "source_account", "destination_account", "description", "transaction_type"}
+
+// ParseBatch decodes a batch in the given format, refusing more than
+// maxItems items.
+func ParseBatch(format string, r io.Reader, maxItems int) ([]types.TransactionRequest, error) {
+	var items []types.TransactionRequest
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&items)
+	case FormatNDJSON:
+		items, err = parseNDJSON(r, maxItems)
+	case FormatCSV:
+		items, err = parseCSV(r, maxItems)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidBatch, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no items", ErrInvalidBatch)
+	}
+	if len(items) > maxItems {
+		return nil, fmt.Errorf("%w: more than %d items", ErrInvalidBatch, maxItems)
+	}
+	return items, nil
+}
+
+func parseNDJSON(r io.Reader, maxItems int) ([]types.TransactionRequest, error) {
+	var items []types.TransactionRequest
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var item types.TransactionRequest
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+		if len(items) > maxItems {
+			break
+		}
+	}
+	return items, scanner.Err()
+}
+
+func parseCSV(r io.Reader, maxItems int) ([]types.TransactionRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		index[name] = i
+	}
+	for _, required := range csvColumns[:3] {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := index[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var items []types.TransactionRequest
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		amount, err := strconv.ParseFloat(field(row, "amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, field(row, "amount"))
+		}
+		items = append(items, types.TransactionRequest{
+			Amount:             amount,
+			SourceAccount:      field(row, "source_account"),
+			DestinationAccount: field(row, "destination_account"),
+			Description:        field(row, "description"),
+			TransactionType:    field(row, "transaction_type"),
+		})
+		if len(items) > maxItems {
+			break
+		}
+	}
+	return items, nil
+}
+
+// ValidateBatch checks every item and returns one error per invalid item.
+func ValidateBatch(items []types.TransactionRequest) []types.BatchItemError {
+	var problems []types.BatchItemError
+	for i, item := range items {
+		var msg string
+		switch {
+		case item.Amount <= 0:
+			msg = "amount must be positive"
+		case item.SourceAccount == "" || item.DestinationAccount == "":
+			msg = "source_account and destination_account are required"
+		case item.SourceAccount == item.DestinationAccount:
+			msg = "source and destination accounts cannot be the same"
+		case IsScheduled(&item):
+			msg = "execute_at and recurrence are not supported in batches"
+		}
+		if msg != "" {
+			problems = append(problems, types.BatchItemError{Index: i, Error: msg})
+		}
+	}
+	return problems
+}
+
+// BatchService submits many transactions at once: one database transaction
+// for the rows, then one Kafka transaction for their events.
+type BatchService struct {
+	kc   types.ProducerConnection
+	repo repository.BatchRepository
+	fees *fees.Engine
+}
+
+func NewBatchService(kc types.ProducerConnection, repo repository.BatchRepository, fe *fees.Engine) *BatchService {
+	return &BatchService{kc: kc, repo: repo, fees: fe}
+}
+
+// Submit stores the validated items under a new batch ID and publishes them.
+// The rows commit before the events are produced, so consumers always find
+// them. A batch whose events fail to publish is still accepted and
+// published again by RunRepublish.
+func (bs *BatchService) Submit(ctx context.Context, submitter string, items []types.TransactionRequest) (*types.BatchResponse, error) {
+	batch := &models.Batch{
+		ID:        uuid.NewString(),
+		Submitter: submitter,
+		ItemCount: len(items),
+	}
+	txs := make([]models.Transaction, len(items))
+	for i := range items {
+		model, err := newTransaction(ctx, bs.fees, &items[i])
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = *model
+		txs[i].BatchID = batch.ID
+		txs[i].BatchIndex = i
+	}
+
+	if err := bs.repo.Create(ctx, batch, txs); err != nil {
+		return nil, fmt.Errorf("failed to submit batch: %w", err)
+	}
+	for i := range txs {
+		metrics.TransactionsCreated.WithLabelValues(txs[i].TransactionType).Inc()
+	}
+	if err := bs.publish(ctx, batch.ID, txs); err != nil {
+		batchLogger.WarnContext(ctx, "batch stored but not published, will retry", "batch_id", batch.ID, "error", err)
+	}
+	return &types.BatchResponse{BatchID: batch.ID, ItemCount: batch.ItemCount}, nil
+}
+
+// RunRepublish publishes batches left unpublished every
+// cfg.RepublishAfter until ctx is done.
+func (bs *BatchService) RunRepublish(ctx context.Context, cfg config.Batch) {
+	ticker := time.NewTicker(cfg.RepublishAfter)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := bs.RepublishDue(ctx, time.Now().Add(-cfg.RepublishAfter))
+			if err != nil {
+				batchLogger.Error("failed to republish batches", "error", err)
+			}
+			if n > 0 {
+				batchLogger.Info("republished batches", "count", n)
+			}
+		}
+	}
+}
+
+// RepublishDue publishes the pending items of batches created before
+// before and still unpublished. Consumers ignore repeated events, so a
+// batch published twice settles once.
+func (bs *BatchService) RepublishDue(ctx context.Context, before time.Time) (int, error) {
+	batches, err := bs.repo.Unpublished(ctx, before, republishLimit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unpublished batches: %w", err)
+	}
+	for i, b := range batches {
+		txs, err := bs.repo.Items(ctx, b.ID)
+		if err != nil {
+			return i, fmt.Errorf("failed to get items of batch %s: %w", b.ID, err)
+		}
+		if err := bs.publish(ctx, b.ID, txs); err != nil {
+			return i, err
+		}
+	}
+	return len(batches), nil
+}
+
+// publish produces the events of the batch's pending transactions in one
+// Kafka transaction and marks the batch published.
+func (bs *BatchService) publish(ctx context.Context, id string, txs []models.Transaction) error {
+	var messages []string
+	for i := range txs {
+		if txs[i].Status != types.StatusPending {
+			continue
+		}
+		payload, err := json.Marshal(ToTransactionResponse(&txs[i]))
+		if err != nil {
+			return fmt.Errorf("failed to marshal transaction response: %w", err)
+		}
+		messages = append(messages, string(payload))
+	}
+	if len(messages) > 0 {
+		if err := bs.kc.ProduceBatch(ctx, messages); err != nil {
+			return fmt.Errorf("failed to produce kafka events: %w", err)
+		}
+	}
+	if err := bs.repo.MarkPublished(ctx, id); err != nil {
+		return fmt.Errorf("failed to mark batch %s published: %w", id, err)
+	}
+	return nil
+}
+
+// Status summarises the outcome of every item in the batch.
+func (bs *BatchService) Status(ctx context.Context, id string) (*types.BatchStatusResponse, error) {
+	batch, err := bs.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+	if batch == nil {
+		return nil, ErrBatchNotFound
+	}
+	txs, err := bs.repo.Items(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch items: %w", err)
+	}
+
+	resp := &types.BatchStatusResponse{
+		BatchID:     batch.ID,
+		SubmittedBy: batch.Submitter,
+		CreatedAt:   batch.CreatedAt.Format(time.RFC3339),
+		Total:       len(txs),
+		Items:       make([]types.BatchItemStatus, 0, len(txs)),
+	}
+	for _, tx := range txs {
+		switch tx.Status {
+		case types.StatusCompleted:
+			resp.Completed++
+		case types.StatusFailed:
+			resp.Failed++
+		default:
+			resp.Pending++
+		}
+		resp.Items = append(resp.Items, types.BatchItemStatus{
+			Index:         tx.BatchIndex,
+			ID:            uint64(tx.ID),
+			Status:        string(tx.Status),
+			FailureReason: tx.FailureReason,
+		})
+	}
+
+	switch {
+	case resp.Pending > 0:
+		resp.Status = string(types.StatusPending)
+	case resp.Failed == 0:
+		resp.Status = string(types.StatusCompleted)
+	case resp.Completed == 0:
+		resp.Status = string(types.StatusFailed)
+	default:
+		resp.Status = "partially_failed"
+	}
+	return resp, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"txsystem/pkg/common/types"
+)
+
+func TestParseBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		body     string
+		maxItems int
+		want     []types.TransactionRequest
+		wantErr  string
+	}{
+		{
+			name:   "json",
+			format: FormatJSON,
+			body:   `[{"amount": 10, "source_account": "1", "destination_account": "2", "description": "rent"}]`,
+			want:   []types.TransactionRequest{{Amount: 10, SourceAccount: "1", DestinationAccount: "2", Description: "rent"}},
+		},
+		{
+			name:   "ndjson skips blank lines",
+			format: FormatNDJSON,
+			body:   "{\"amount\": 1, \"source_account\": \"1\", \"destination_account\": \"2\"}\n\n  {\"amount\": 2, \"source_account\": \"2\", \"destination_account\": \"3\"}\n",
+			want: []types.TransactionRequest{
+				{Amount: 1, SourceAccount: "1", DestinationAccount: "2"},
+				{Amount: 2, SourceAccount: "2", DestinationAccount: "3"},
+			},
+		},
+		{
+			name:    "ndjson names the bad line",
+			format:  FormatNDJSON,
+			body:    "{\"amount\": 1, \"source_account\": \"1\", \"destination_account\": \"2\"}\n{oops}\n",
+			wantErr: "line 2",
+		},
+		{
+			name:   "csv columns in any order and case",
+			format: FormatCSV,
+			body:   "Destination_Account, amount, source_account, transaction_type\n2, 10.50, 1, payment\n3,4,1,\n",
+			want: []types.TransactionRequest{
+				{Amount: 10.5, SourceAccount: "1", DestinationAccount: "2", TransactionType: "payment"},
+				{Amount: 4, SourceAccount: "1", DestinationAccount: "3"},
+			},
+		},
+		{
+			name:    "csv unknown column",
+			format:  FormatCSV,
+			body:    "amount,source_account,destination_account,currency\n1,1,2,EUR\n",
+			wantErr: `unknown column "currency"`,
+		},
+		{
+			name:    "csv missing column",
+			format:  FormatCSV,
+			body:    "amount,source_account\n1,1\n",
+			wantErr: `missing column "destination_account"`,
+		},
+		{
+			name:    "csv bad amount",
+			format:  FormatCSV,
+			body:    "amount,source_account,destination_account\n1,1,2\nten,1,2\n",
+			wantErr: `line 3: invalid amount "ten"`,
+		},
+		{
+			name:    "empty",
+			format:  FormatJSON,
+			body:    `[]`,
+			wantErr: "no items",
+		},
+		{
+			name:     "too many",
+			format:   FormatNDJSON,
+			body:     strings.Repeat("{\"amount\": 1, \"source_account\": \"1\", \"destination_account\": \"2\"}\n", 3),
+			maxItems: 2,
+			wantErr:  "more than 2 items",
+		},
+		{
+			name:    "unsupported format",
+			format:  "xml",
+			body:    `<batch/>`,
+			wantErr: `unsupported format "xml"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxItems := tt.maxItems
+			if maxItems == 0 {
+				maxItems = 100
+			}
+			got, err := ParseBatch(tt.format, strings.NewReader(tt.body), maxItems)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidBatch) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want invalid batch with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBatch: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("items = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	valid := types.TransactionRequest{Amount: 5, SourceAccount: "1", DestinationAccount: "2"}
+	scheduled := valid
+	scheduled.Recurrence = &types.Recurrence{Frequency: types.FrequencyMonthly}
+	tests := []struct {
+		name string
+		item types.TransactionRequest
+		want string
+	}{
+		{name: "valid", item: valid},
+		{name: "zero amount", item: types.TransactionRequest{SourceAccount: "1", DestinationAccount: "2"}, want: "amount must be positive"},
+		{name: "negative amount", item: types.TransactionRequest{Amount: -1, SourceAccount: "1", DestinationAccount: "2"}, want: "amount must be positive"},
+		{name: "missing destination", item: types.TransactionRequest{Amount: 5, SourceAccount: "1"}, want: "source_account and destination_account are required"},
+		{name: "same account", item: types.TransactionRequest{Amount: 5, SourceAccount: "1", DestinationAccount: "1"}, want: "source and destination accounts cannot be the same"},
+		{name: "scheduled", item: scheduled, want: "execute_at and recurrence are not supported in batches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The item sits behind a valid one so its index is checked too.
+			problems := ValidateBatch([]types.TransactionRequest{valid, tt.item})
+			var want []types.BatchItemError
+			if tt.want != "" {
+				want = []types.BatchItemError{{Index: 1, Error: tt.want}}
+			}
+			if !slices.Equal(problems, want) {
+				t.Errorf("problems = %+v, want %+v", problems, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"txsystem/pkg/common/export"
+)
+
+// exportBatchSize is how many transactions an export reads at a time.
+const exportBatchSize = 1000
+
+var transactionColumns = []export.Column{
+	{Name: "id", Kind: export.Int64},
+	{Name: "transaction_id", Kind: export.String},
+	{Name: "created_at", Kind: export.Time},
+	{Name: "updated_at", Kind: export.Time},
+	{Name: "transaction_type", Kind: export.String},
+	{Name: "source_account", Kind: export.String},
+	{Name: "destination_account", Kind: export.String},
+	{Name: "amount", Kind: export.Float64},
+	{Name: "currency", Kind: export.String},
+	{Name: "fee", Kind: export.Float64},
+	{Name: "fee_schedule_version", Kind: export.Int64},
+	{Name: "status", Kind: export.String},
+	{Name: "failure_reason", Kind: export.String},
+	{Name: "description", Kind: export.String},
+	{Name: "batch_id", Kind: export.String},
+	{Name: "batch_index", Kind: export.Int64},
+}
+
+// Export writes the transactions matching filter to w in ID order, reading
+// them in batches. Dates are UTC days of creation, and the account may be
+// either side of a transfer.
+func (s *TransactionService) Export(ctx context.Context, filter export.Filter, format export.Format, w io.Writer) (int, error) {
+	out, err := export.NewWriter(format, w, transactionColumns)
+	if err != nil {
+		return 0, err
+	}
+	to := filter.To
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	n := 0
+	var last uint
+	for {
+		batch, err := s.repo.ListCreatedAfter(ctx, filter.From, to, filter.AccountID, last, exportBatchSize)
+		if err != nil {
+			return n, fmt.Errorf("failed to list transactions: %w", err)
+		}
+		for _, t := range batch {
+			err := out.Write([]any{
+				int64(t.ID), t.TransactionID, t.CreatedAt, t.UpdatedAt, t.TransactionType,
+				t.SourceAccount, t.DestinationAccount, t.Amount, t.Currency, t.Fee,
+				int64(t.FeeScheduleVersion), string(t.Status), t.FailureReason, t.Description,
+				t.BatchID, int64(t.BatchIndex),
+			})
+			if err != nil {
+				return n, fmt.Errorf("failed to write transaction %d: %w", t.ID, err)
+			}
+			n++
+		}
+		if len(batch) < exportBatchSize {
+			break
+		}
+		last = batch[len(batch)-1].ID
+	}
+	if err := out.Close(); err != nil {
+		return n, fmt.Errorf("failed to finish export: %w", err)
+	}
+	return n, nil
+}
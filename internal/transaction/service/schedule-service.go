@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"txsystem/internal/transaction/models"
+	"txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/types"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleState    = errors.New("schedule cannot change to the requested state")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+// ScheduleService manages future and recurring transfers. The Scheduler
+// turns due occurrences into transactions.
+type ScheduleService struct {
+	repo repository.ScheduleRepository
+	now  func() time.Time
+}
+
+func NewScheduleService(repo repository.ScheduleRepository) *ScheduleService {
+	return &ScheduleService{repo: repo, now: time.Now}
+}
+
+// IsScheduled reports whether req asks for a deferred or recurring transfer
+// rather than an immediate one.
+func IsScheduled(req *types.TransactionRequest) bool {
+	return req.ExecuteAt != nil || req.Recurrence != nil
+}
+
+func toScheduleResponse(m *models.Schedule) *types.ScheduleResponse {
+	resp := &types.ScheduleResponse{
+		ID:                 uint64(m.ID),
+		Amount:             m.Amount,
+		Description:        m.Description,
+		SourceAccount:      m.SourceAccount,
+		DestinationAccount: m.DestinationAccount,
+		TransactionType:    m.TransactionType,
+		ExecuteAt:          m.ExecuteAt.Format(time.RFC3339),
+		Status:             string(m.Status),
+		Occurrences:        m.Occurrences,
+		CreatedAt:          m.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:          m.UpdatedAt.Format(time.RFC3339),
+	}
+	if m.Frequency != "" {
+		resp.Recurrence = &types.Recurrence{Frequency: m.Frequency, Until: m.Until, Count: m.MaxOccurrences}
+	}
+	if m.NextRunAt != nil {
+		resp.NextRunAt = m.NextRunAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// CreateSchedule stores req as a schedule whose first occurrence runs at
+// ExecuteAt, or straight away when it is not set.
+func (ss *ScheduleService) CreateSchedule(ctx context.Context, req *types.TransactionRequest) (*types.ScheduleResponse, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidSchedule)
+	}
+
+	s := &models.Schedule{
+		Amount:             req.Amount,
+		Description:        req.Description,
+		SourceAccount:      req.SourceAccount,
+		DestinationAccount: req.DestinationAccount,
+		TransactionType:    req.TransactionType,
+		ExecuteAt:          ss.now().UTC(),
+		Status:             models.ScheduleActive,
+	}
+	if req.ExecuteAt != nil {
+		s.ExecuteAt = req.ExecuteAt.UTC()
+	}
+
+	if r := req.Recurrence; r != nil {
+		switch r.Frequency {
+		case types.FrequencyDaily, types.FrequencyWeekly, types.FrequencyMonthly:
+		default:
+			return nil, fmt.Errorf("%w: frequency must be daily, weekly or monthly", ErrInvalidSchedule)
+		}
+		if r.Count < 0 {
+			return nil, fmt.Errorf("%w: count must not be negative", ErrInvalidSchedule)
+		}
+		if r.Until != nil && r.Until.Before(s.ExecuteAt) {
+			return nil, fmt.Errorf("%w: until is before the first occurrence", ErrInvalidSchedule)
+		}
+		s.Frequency = r.Frequency
+		s.Until = r.Until
+		s.MaxOccurrences = r.Count
+	}
+
+	next := s.ExecuteAt
+	s.NextRunAt = &next
+	if err := ss.repo.Create(ctx, s); err != nil {
+		return nil, fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return toScheduleResponse(s), nil
+}
+
+// GetSchedule returns the schedule, or ErrScheduleNotFound.
+func (ss *ScheduleService) GetSchedule(ctx context.Context, id uint) (*types.ScheduleResponse, error) {
+	s, err := ss.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(s), nil
+}
+
+func (ss *ScheduleService) GetSchedules(ctx context.Context) ([]*types.ScheduleResponse, error) {
+	list, err := ss.repo.List(ctx, 100, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponses(list), nil
+}
+
+// GetSchedulesForAccounts lists the schedules debiting one of accounts.
+func (ss *ScheduleService) GetSchedulesForAccounts(ctx context.Context, accounts []string) ([]*types.ScheduleResponse, error) {
+	list, err := ss.repo.ListByAccounts(ctx, accounts, 100, 0)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponses(list), nil
+}
+
+// Pause stops an active schedule from running until it is resumed.
+func (ss *ScheduleService) Pause(ctx context.Context, id uint) (*types.ScheduleResponse, error) {
+	return ss.transition(ctx, id, models.ScheduleActive, func(s *models.Schedule) {
+		s.Status = models.SchedulePaused
+	})
+}
+
+// Resume reactivates a paused schedule. Occurrences that fell due while it
+// was paused are skipped rather than run late.
+func (ss *ScheduleService) Resume(ctx context.Context, id uint) (*types.ScheduleResponse, error) {
+	return ss.transition(ctx, id, models.SchedulePaused, func(s *models.Schedule) {
+		s.Status = models.ScheduleActive
+		now := ss.now()
+		for s.NextRunAt != nil && s.Frequency != "" && s.NextRunAt.Before(now) {
+			advance(s, false)
+		}
+	})
+}
+
+// Cancel stops an active or paused schedule for good.
+func (ss *ScheduleService) Cancel(ctx context.Context, id uint) (*types.ScheduleResponse, error) {
+	s, err := ss.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != models.ScheduleActive && s.Status != models.SchedulePaused {
+		return nil, ErrScheduleState
+	}
+	s.Status = models.ScheduleCancelled
+	s.NextRunAt = nil
+	if err := ss.repo.Update(ctx, s); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return toScheduleResponse(s), nil
+}
+
+func (ss *ScheduleService) transition(ctx context.Context, id uint, from models.ScheduleStatus, apply func(s *models.Schedule)) (*types.ScheduleResponse, error) {
+	s, err := ss.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != from {
+		return nil, ErrScheduleState
+	}
+	apply(s)
+	if err := ss.repo.Update(ctx, s); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return toScheduleResponse(s), nil
+}
+
+func (ss *ScheduleService) get(ctx context.Context, id uint) (*models.Schedule, error) {
+	s, err := ss.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	if s == nil {
+		return nil, ErrScheduleNotFound
+	}
+	return s, nil
+}
+
+func toScheduleResponses(list []models.Schedule) []*types.ScheduleResponse {
+	var resp []*types.ScheduleResponse
+	for i := range list {
+		resp = append(resp, toScheduleResponse(&list[i]))
+	}
+	return resp
+}
+
+// occurrenceAt returns when occurrence n runs. Monthly occurrences keep the
+// day of the first one, clamped to the end of shorter months, instead of
+// drifting after a 31st.
+func occurrenceAt(s *models.Schedule, n int) time.Time {
+	switch s.Frequency {
+	case types.FrequencyDaily:
+		return s.ExecuteAt.AddDate(0, 0, n)
+	case types.FrequencyWeekly:
+		return s.ExecuteAt.AddDate(0, 0, 7*n)
+	case types.FrequencyMonthly:
+		t := s.ExecuteAt
+		firstOfMonth := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+		return firstOfMonth.AddDate(0, 0, min(t.Day(), lastDay)-1)
+	}
+	return s.ExecuteAt
+}
+
+// advance moves s past its current occurrence, counting it when ran is set,
+// and completes the schedule once no occurrence is left.
+func advance(s *models.Schedule, ran bool) {
+	if ran {
+		s.Occurrences++
+	}
+	s.NextIndex++
+
+	done := s.Frequency == "" || (s.MaxOccurrences > 0 && s.Occurrences >= s.MaxOccurrences)
+	next := occurrenceAt(s, s.NextIndex)
+	if s.Until != nil && next.After(*s.Until) {
+		done = true
+	}
+	if done {
+		s.Status = models.ScheduleCompleted
+		s.NextRunAt = nil
+		return
+	}
+	s.NextRunAt = &next
+}
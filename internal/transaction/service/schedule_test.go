@@ -0,0 +1,158 @@
+package service
+
+import (
+	"testing"
+	"time"
+	"txsystem/internal/transaction/models"
+	"txsystem/pkg/common/types"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestOccurrenceAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency types.Frequency
+		start     string
+		want      []string
+	}{
+		{
+			name:      "monthly from the 31st clamps and comes back",
+			frequency: types.FrequencyMonthly,
+			start:     "2024-01-31T09:00:00Z",
+			want: []string{
+				"2024-01-31T09:00:00Z",
+				"2024-02-29T09:00:00Z",
+				"2024-03-31T09:00:00Z",
+				"2024-04-30T09:00:00Z",
+				"2024-05-31T09:00:00Z",
+			},
+		},
+		{
+			name:      "monthly from the 31st in a common year",
+			frequency: types.FrequencyMonthly,
+			start:     "2023-01-31T23:59:59Z",
+			want:      []string{"2023-01-31T23:59:59Z", "2023-02-28T23:59:59Z", "2023-03-31T23:59:59Z"},
+		},
+		{
+			name:      "monthly from the 30th",
+			frequency: types.FrequencyMonthly,
+			start:     "2024-01-30T09:00:00Z",
+			want:      []string{"2024-01-30T09:00:00Z", "2024-02-29T09:00:00Z", "2024-03-30T09:00:00Z"},
+		},
+		{
+			name:      "monthly from the 29th of February",
+			frequency: types.FrequencyMonthly,
+			start:     "2024-02-29T09:00:00Z",
+			want:      []string{"2024-02-29T09:00:00Z", "2024-03-29T09:00:00Z"},
+		},
+		{
+			name:      "monthly across the year end",
+			frequency: types.FrequencyMonthly,
+			start:     "2024-11-30T09:00:00Z",
+			want:      []string{"2024-11-30T09:00:00Z", "2024-12-30T09:00:00Z", "2025-01-30T09:00:00Z", "2025-02-28T09:00:00Z"},
+		},
+		{
+			name:      "monthly keeps the zone offset",
+			frequency: types.FrequencyMonthly,
+			start:     "2024-01-31T08:00:00+02:00",
+			want:      []string{"2024-01-31T08:00:00+02:00", "2024-02-29T08:00:00+02:00"},
+		},
+		{
+			name:      "daily",
+			frequency: types.FrequencyDaily,
+			start:     "2024-02-28T09:00:00Z",
+			want:      []string{"2024-02-28T09:00:00Z", "2024-02-29T09:00:00Z", "2024-03-01T09:00:00Z"},
+		},
+		{
+			name:      "weekly",
+			frequency: types.FrequencyWeekly,
+			start:     "2024-12-25T09:00:00Z",
+			want:      []string{"2024-12-25T09:00:00Z", "2025-01-01T09:00:00Z", "2025-01-08T09:00:00Z"},
+		},
+		{
+			name:  "one-off",
+			start: "2024-01-31T09:00:00Z",
+			want:  []string{"2024-01-31T09:00:00Z", "2024-01-31T09:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &models.Schedule{Frequency: tt.frequency, ExecuteAt: date(tt.start)}
+			for n, want := range tt.want {
+				if got := occurrenceAt(s, n); !got.Equal(date(want)) {
+					t.Errorf("occurrence %d = %s, want %s", n, got.Format(time.RFC3339), want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	until := date("2024-03-31T09:00:00Z")
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		runs     int
+		wantDone bool
+		wantNext string
+	}{
+		{
+			name:     "count reached",
+			schedule: models.Schedule{Frequency: types.FrequencyMonthly, ExecuteAt: date("2024-01-31T09:00:00Z"), MaxOccurrences: 2},
+			runs:     2,
+			wantDone: true,
+		},
+		{
+			name:     "count not reached",
+			schedule: models.Schedule{Frequency: types.FrequencyMonthly, ExecuteAt: date("2024-01-31T09:00:00Z"), MaxOccurrences: 3},
+			runs:     2,
+			wantNext: "2024-03-31T09:00:00Z",
+		},
+		{
+			name:     "until is inclusive",
+			schedule: models.Schedule{Frequency: types.FrequencyMonthly, ExecuteAt: date("2024-01-31T09:00:00Z"), Until: &until},
+			runs:     1,
+			wantNext: "2024-02-29T09:00:00Z",
+		},
+		{
+			name:     "past until",
+			schedule: models.Schedule{Frequency: types.FrequencyMonthly, ExecuteAt: date("2024-01-31T09:00:00Z"), Until: &until},
+			runs:     3,
+			wantDone: true,
+		},
+		{
+			name:     "one-off",
+			schedule: models.Schedule{ExecuteAt: date("2024-01-31T09:00:00Z")},
+			runs:     1,
+			wantDone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.schedule
+			s.Status = models.ScheduleActive
+			for range tt.runs {
+				advance(&s, true)
+			}
+			if done := s.Status == models.ScheduleCompleted; done != tt.wantDone {
+				t.Fatalf("completed = %v, want %v", done, tt.wantDone)
+			}
+			if tt.wantDone {
+				if s.NextRunAt != nil {
+					t.Errorf("completed schedule has next run %s", s.NextRunAt)
+				}
+				return
+			}
+			if s.NextRunAt == nil || !s.NextRunAt.Equal(date(tt.wantNext)) {
+				t.Errorf("next run = %v, want %s", s.NextRunAt, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"txsystem/internal/transaction/models"
+	"txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+)
+
+var schedulerLogger = logging.For("scheduler")
+
+// Scheduler materialises due schedule occurrences as transactions through
+// TransactionService. Each occurrence is created under the key
+// schedule-<id>-<n>, so a run interrupted before the schedule was advanced
+// does not create the transfer twice.
+type Scheduler struct {
+	schedules    repository.ScheduleRepository
+	transactions *TransactionService
+	cfg          config.Scheduler
+	now          func() time.Time
+}
+
+func NewScheduler(schedules repository.ScheduleRepository, transactions *TransactionService, cfg config.Scheduler) *Scheduler {
+	return &Scheduler{schedules: schedules, transactions: transactions, cfg: cfg, now: time.Now}
+}
+
+// Run polls for due schedules every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RunDue(ctx)
+			if err != nil {
+				schedulerLogger.Error("failed to run due schedules", "error", err)
+			}
+			if n > 0 {
+				schedulerLogger.Info("ran due schedules", "count", n)
+			}
+		}
+	}
+}
+
+// RunDue creates every occurrence due now for up to one batch of schedules.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	now := s.now()
+	return s.schedules.WithDue(ctx, now, s.cfg.BatchSize, func(sch *models.Schedule) error {
+		// Catch up on every occurrence missed while the scheduler was down.
+		for sch.Status == models.ScheduleActive && sch.NextRunAt != nil && !sch.NextRunAt.After(now) {
+			key := fmt.Sprintf("schedule-%d-%d", sch.ID, sch.NextIndex)
+			req := &types.TransactionRequest{
+				Amount:             sch.Amount,
+				Description:        sch.Description,
+				SourceAccount:      sch.SourceAccount,
+				DestinationAccount: sch.DestinationAccount,
+				TransactionType:    sch.TransactionType,
+			}
+			if _, err := s.transactions.CreateTransactionOnce(ctx, req, key); err != nil {
+				return fmt.Errorf("schedule %d occurrence %d: %w", sch.ID, sch.NextIndex, err)
+			}
+			advance(sch, true)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/messaging"
+	"txsystem/pkg/common/types"
+)
+
+// EventTransactionCreated is the stream event for a transaction as it is
+// recorded; settlement outcomes follow as types.EventTransactionCompleted or
+// types.EventTransactionFailed.
+const EventTransactionCreated = "transaction.created"
+
+var (
+	streamLogger = logging.For("stream")
+
+	ErrStreamBusy = errors.New("too many open streams")
+)
+
+// StreamFilter selects the events sent to one stream.
+type StreamFilter struct {
+	// Visible limits events to transfers touching these accounts; nil means
+	// every transfer.
+	Visible []string
+	Account string
+	Status  types.TransactionStatus
+}
+
+func (f *StreamFilter) matches(ev *types.TransactionStatusEvent) bool {
+	touches := func(accounts []string) bool {
+		return slices.Contains(accounts, ev.SourceAccount) || slices.Contains(accounts, ev.DestinationAccount)
+	}
+	if f.Visible != nil && !touches(f.Visible) {
+		return false
+	}
+	if f.Account != "" && !touches([]string{f.Account}) {
+		return false
+	}
+	return f.Status == "" || ev.Status == string(f.Status)
+}
+
+// StreamEvent is one server-sent event. ID is the Kafka cursor just after
+// it, for resuming.
+type StreamEvent struct {
+	ID   string
+	Type string
+	Data []byte
+}
+
+// StreamService tails the transactions and status topics for live
+// subscribers. Each subscriber reads Kafka independently.
+type StreamService struct {
+	brokers []string
+	topics  []string
+	slots   chan struct{}
+}
+
+func NewStreamService(kafka config.Kafka, cfg config.Stream) *StreamService {
+	return &StreamService{
+		brokers: kafka.Brokers,
+		topics:  []string{kafka.TransactionsTopic, kafka.StatusTopic},
+		slots:   make(chan struct{}, cfg.MaxClients),
+	}
+}
+
+// Acquire reserves a stream slot. Call release when the stream ends.
+func (ss *StreamService) Acquire() (release func(), err error) {
+	select {
+	case ss.slots <- struct{}{}:
+		return func() { <-ss.slots }, nil
+	default:
+		return nil, ErrStreamBusy
+	}
+}
+
+// Stream sends matching events from cursor from (or from now when it is
+// empty) until ctx is done or send fails.
+func (ss *StreamService) Stream(ctx context.Context, from messaging.Cursor, filter StreamFilter, send func(StreamEvent) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	consumer, err := messaging.NewKafkaTail(ctx, ss.brokers, ss.topics, from)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	sub := &subscriber{ss: ss, filter: filter, send: send, stop: cancel}
+	consumer.StartRecordConsumer(ctx, sub)
+	<-ctx.Done()
+	return sub.err
+}
+
+// subscriber turns the records of one stream into events. The first send
+// error is kept and ends the stream.
+type subscriber struct {
+	ss     *StreamService
+	filter StreamFilter
+	send   func(StreamEvent) error
+	stop   context.CancelFunc
+	err    error
+}
+
+func (s *subscriber) ProcessRecord(ctx context.Context, topic, message, position string) error {
+	ev, err := s.ss.decode(topic, []byte(message))
+	if err != nil {
+		streamLogger.WarnContext(ctx, "skipping undecodable stream record", "topic", topic, "position", position, "error", err)
+		return nil
+	}
+	if !s.filter.matches(ev) {
+		return nil
+	}
+	data, err := json.Marshal(ev)
+	if err == nil {
+		err = s.send(StreamEvent{ID: position, Type: ev.Type, Data: data})
+	} else {
+		err = fmt.Errorf("failed to encode stream event: %w", err)
+	}
+	if err != nil {
+		s.err = err
+		s.stop()
+	}
+	return err
+}
+
+// decode reads either a transaction event or a status event into the
+// status event shape, so every stream event has the same fields.
+func (ss *StreamService) decode(topic string, value []byte) (*types.TransactionStatusEvent, error) {
+	if topic != ss.topics[0] {
+		var ev types.TransactionStatusEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return nil, err
+		}
+		return &ev, nil
+	}
+
+	var tx types.TransactionResponse
+	if err := json.Unmarshal(value, &tx); err != nil {
+		return nil, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, tx.CreatedAt)
+	return &types.TransactionStatusEvent{
+		EventID:            fmt.Sprintf("tx-%d-created", tx.ID),
+		Type:               EventTransactionCreated,
+		TransactionID:      tx.ID,
+		Reference:          tx.TransactionID,
+		Status:             tx.Status,
+		Amount:             tx.Amount,
+		SourceAccount:      tx.SourceAccount,
+		DestinationAccount: tx.DestinationAccount,
+		TransactionType:    tx.TransactionType,
+		OccurredAt:         createdAt,
+	}, nil
+}
@@ -3,21 +3,38 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"txsystem/internal/transaction/fees"
 	"txsystem/internal/transaction/models"
 	"txsystem/internal/transaction/repository"
+	"txsystem/pkg/common/metrics"
 	"txsystem/pkg/common/types"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key already names a
+	// transaction from another source account.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used for another account")
+	// ErrIdempotencyKeyConflict is returned when a key already names a
+	// transaction from the same source account that moves a different
+	// amount, to a different account or of a different type.
+	ErrIdempotencyKeyConflict = errors.New("idempotency key already used for a different transfer")
 )
 
 type TransactionService struct {
 	kc   types.ProducerConnection
 	repo repository.TransactionRepository
+	fees *fees.Engine
 }
 
-func NewTransactionService(kc types.ProducerConnection, repo repository.TransactionRepository) *TransactionService {
+func NewTransactionService(kc types.ProducerConnection, repo repository.TransactionRepository, fe *fees.Engine) *TransactionService {
 	return &TransactionService{
 		kc:   kc,
 		repo: repo,
+		fees: fe,
 	}
 }
 
@@ -34,41 +51,142 @@ func toTransactionModel(req *types.TransactionRequest) *models.Transaction {
 	}
 }
 
-// toTransactionResponse maps a persistence model to the response DTO.
-func toTransactionResponse(m *models.Transaction) *types.TransactionResponse {
+// newTransaction maps the request and prices it, so the fee is fixed when
+// the transaction is created.
+func newTransaction(ctx context.Context, fe *fees.Engine, req *types.TransactionRequest) (*models.Transaction, error) {
+	model := toTransactionModel(req)
+	quote, err := fe.Quote(ctx, model.TransactionType, model.SourceAccount, model.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price transaction: %w", err)
+	}
+	model.Currency = quote.Currency
+	model.Fee = quote.Fee
+	model.FeeScheduleVersion = quote.ScheduleVersion
+	return model, nil
+}
+
+// ToTransactionResponse maps a persistence model to the response DTO, which is
+// also the event published on the transactions topic.
+func ToTransactionResponse(m *models.Transaction) *types.TransactionResponse {
 	return &types.TransactionResponse{
 		ID:                 uint64(m.ID),
 		Amount:             m.Amount,
+		Currency:           m.Currency,
+		Fee:                m.Fee,
+		FeeScheduleVersion: m.FeeScheduleVersion,
 		Description:        m.Description,
 		SourceAccount:      m.SourceAccount,
 		DestinationAccount: m.DestinationAccount,
 		TransactionType:    m.TransactionType,
 		Status:             string(m.Status),
+		FailureReason:      m.FailureReason,
 		CreatedAt:          m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:          m.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		TransactionID:      m.TransactionID,
 	}
 }
 
-// CreateTransaction persists a new transaction and sends an event.
+// CreateTransaction prices and persists a new transaction, sends an event
+// and returns the transaction.
 func (ts *TransactionService) CreateTransaction(
 	ctx context.Context,
 	req *types.TransactionRequest,
-) error {
-	model := toTransactionModel(req)
+) (*types.TransactionResponse, error) {
+	model, err := newTransaction(ctx, ts.fees, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := ts.repo.Create(ctx, model); err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := ts.publish(ctx, model); err != nil {
+		return nil, err
+	}
+
+	metrics.TransactionsCreated.WithLabelValues(model.TransactionType).Inc()
+	return ToTransactionResponse(model), nil
+}
+
+// CreateTransactionOnce is CreateTransaction keyed by an idempotency key
+// stored as the transaction ID, and returns the transaction. When one with
+// the key already exists nothing is created; it is published again while
+// still pending, in case the first attempt stopped before its event was sent.
+// A key already used from another source account fails with
+// ErrIdempotencyKeyReused, so the existing transaction is not disclosed, and
+// one used for a different transfer fails with ErrIdempotencyKeyConflict.
+func (ts *TransactionService) CreateTransactionOnce(
+	ctx context.Context,
+	req *types.TransactionRequest,
+	key string,
+) (*types.TransactionResponse, error) {
+	existing, err := ts.repo.GetByTransactionID(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up transaction %s: %w", key, err)
+	}
+	if existing != nil {
+		if err := checkReuse(existing, req); err != nil {
+			return nil, err
+		}
+		if existing.Status == types.StatusPending {
+			if err := ts.publish(ctx, existing); err != nil {
+				return nil, err
+			}
+		}
+		return ToTransactionResponse(existing), nil
+	}
+
+	model, err := newTransaction(ctx, ts.fees, req)
+	if err != nil {
+		return nil, err
+	}
+	model.TransactionID = key
 	if err := ts.repo.Create(ctx, model); err != nil {
-		return fmt.Errorf("failed to create transaction: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			// Created concurrently under the same key.
+			existing, err := ts.repo.GetByTransactionID(ctx, key)
+			if err != nil || existing == nil {
+				return nil, fmt.Errorf("failed to look up transaction %s: %w", key, err)
+			}
+			if err := checkReuse(existing, req); err != nil {
+				return nil, err
+			}
+			return ToTransactionResponse(existing), nil
+		}
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	if err := ts.publish(ctx, model); err != nil {
+		return nil, err
 	}
 
-	resp := toTransactionResponse(model)
+	metrics.TransactionsCreated.WithLabelValues(model.TransactionType).Inc()
+	return ToTransactionResponse(model), nil
+}
+
+// checkReuse reports whether req repeats the transaction already created
+// under its idempotency key. The description is free text and may differ.
+func checkReuse(existing *models.Transaction, req *types.TransactionRequest) error {
+	if existing.SourceAccount != req.SourceAccount {
+		return ErrIdempotencyKeyReused
+	}
+	if existing.Amount != req.Amount ||
+		existing.DestinationAccount != req.DestinationAccount ||
+		existing.TransactionType != req.TransactionType {
+		return ErrIdempotencyKeyConflict
+	}
+	return nil
+}
+
+func (ts *TransactionService) publish(ctx context.Context, model *models.Transaction) error {
+	resp := ToTransactionResponse(model)
 	payload, err := json.Marshal(resp)
 	if err != nil {
 		return fmt.Errorf("failed to marshal transaction response: %w", err)
 	}
 
-	if err := ts.kc.Produce(string(payload)); err != nil {
+	if err := ts.kc.Produce(ctx, string(payload)); err != nil {
 		return fmt.Errorf("failed to produce kafka event: %w", err)
 	}
-
 	return nil
 }
 
@@ -83,7 +201,25 @@ func (ts *TransactionService) GetTransactions(
 
 	var respList []*types.TransactionResponse
 	for _, m := range modelsList {
-		respList = append(respList, toTransactionResponse(&m))
+		respList = append(respList, ToTransactionResponse(&m))
+	}
+	return respList, nil
+}
+
+// GetTransactionsForAccounts is GetTransactions restricted to transfers that
+// touch one of accounts.
+func (ts *TransactionService) GetTransactionsForAccounts(
+	ctx context.Context,
+	accounts []string,
+) ([]*types.TransactionResponse, error) {
+	modelsList, err := ts.repo.ListByAccounts(ctx, accounts, 100, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var respList []*types.TransactionResponse
+	for _, m := range modelsList {
+		respList = append(respList, ToTransactionResponse(&m))
 	}
 	return respList, nil
 }
@@ -100,5 +236,32 @@ func (ts *TransactionService) GetTransaction(
 	if m == nil {
 		return nil, nil
 	}
-	return toTransactionResponse(m), nil
+	return ToTransactionResponse(m), nil
+}
+
+// EachTransaction calls fn for every transaction after afterID in ID order,
+// reading them in pages. accounts and status filter as in ListAfter.
+func (ts *TransactionService) EachTransaction(
+	ctx context.Context,
+	accounts []string,
+	status types.TransactionStatus,
+	afterID uint,
+	fn func(*types.TransactionResponse) error,
+) error {
+	const pageSize = 500
+	for {
+		page, err := ts.repo.ListAfter(ctx, accounts, status, afterID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list transactions: %w", err)
+		}
+		for i := range page {
+			if err := fn(ToTransactionResponse(&page[i])); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
 }
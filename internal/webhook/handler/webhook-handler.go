@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"txsystem/internal/webhook/models"
+	"txsystem/internal/webhook/repository"
+	"txsystem/internal/webhook/service"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type Handler struct {
+	service   *service.WebhookService
+	ownership *auth.Ownership
+}
+
+func NewHandler(s *service.WebhookService, ownership *auth.Ownership) *Handler {
+	return &Handler{service: s, ownership: ownership}
+}
+
+// @Summary Register a webhook
+// @Description CreateWebhook registers an endpoint for transaction status events on the caller's accounts. The response carries the signing secret, which is not shown again.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body types.WebhookRequest true "Webhook details"
+// @Success 201 {object} types.WebhookResponse "Registered webhook"
+// @Failure 400 {object} map[string]string "error:invalid webhook"
+// @Failure 403 {object} map[string]string "error:account filter includes an account you do not own"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [post]
+func (h *Handler) CreateWebhook(c echo.Context) error {
+	var req types.WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request"})
+	}
+
+	ctx := c.Request().Context()
+	p, _ := auth.FromContext(ctx)
+	if p == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing bearer token"})
+	}
+	for _, account := range req.Accounts {
+		allowed, err := h.ownership.CanAccess(ctx, account)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to register webhook"})
+		}
+		if !allowed {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "account filter includes an account you do not own"})
+		}
+	}
+
+	webhook, err := h.service.Register(ctx, p.Subject, &req)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+// @Summary List webhooks
+// @Description GetWebhooks lists the webhooks registered by the caller.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} types.WebhookResponse "List of webhooks"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [get]
+func (h *Handler) GetWebhooks(c echo.Context) error {
+	ctx := c.Request().Context()
+	p, _ := auth.FromContext(ctx)
+	if p == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing bearer token"})
+	}
+	webhooks, err := h.service.List(ctx, p.Subject)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch webhooks"})
+	}
+	return c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} types.WebhookResponse "Webhook details"
+// @Failure 404 {object} map[string]string "error:webhook not found"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [get]
+func (h *Handler) GetWebhook(c echo.Context) error {
+	id, ok, err := h.authorize(c)
+	if !ok {
+		return err
+	}
+	webhook, err := h.service.Get(c.Request().Context(), id)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// @Summary Delete a webhook
+// @Description DeleteWebhook removes the webhook and its delivery log.
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 204 "Deleted"
+// @Failure 404 {object} map[string]string "error:webhook not found"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c echo.Context) error {
+	id, ok, err := h.authorize(c)
+	if !ok {
+		return err
+	}
+	if err := h.service.Delete(c.Request().Context(), id); err != nil {
+		return webhookError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary List webhook deliveries
+// @Description GetDeliveries returns the latest 100 deliveries of a webhook, newest first.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Filter by status (pending, succeeded, failed)"
+// @Success 200 {array} types.WebhookDeliveryResponse "Delivery log"
+// @Failure 400 {object} map[string]string "error:invalid status"
+// @Failure 404 {object} map[string]string "error:webhook not found"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (h *Handler) GetDeliveries(c echo.Context) error {
+	status := models.DeliveryStatus(c.QueryParam("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid status"})
+	}
+
+	id, ok, err := h.authorize(c)
+	if !ok {
+		return err
+	}
+	deliveries, err := h.service.Deliveries(c.Request().Context(), id, status)
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Redeliver a webhook delivery
+// @Description Redeliver queues a finished delivery again with a fresh retry budget.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 202 {object} types.WebhookDeliveryResponse "Queued delivery"
+// @Failure 404 {object} map[string]string "error:delivery not found"
+// @Failure 409 {object} map[string]string "error:delivery is still pending"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *Handler) Redeliver(c echo.Context) error {
+	deliveryID, err := strconv.ParseUint(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid delivery ID"})
+	}
+
+	id, ok, err := h.authorize(c)
+	if !ok {
+		return err
+	}
+	delivery, err := h.service.Redeliver(c.Request().Context(), id, uint(deliveryID))
+	if err != nil {
+		return webhookError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+// authorize parses the webhook ID and checks the caller registered it or is
+// an admin. When ok is false the response has been written and err is what
+// the handler should return.
+func (h *Handler) authorize(c echo.Context) (id uint, ok bool, err error) {
+	parsed, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, false, c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid webhook ID"})
+	}
+
+	ctx := c.Request().Context()
+	owner, err := h.service.Owner(ctx, uint(parsed))
+	if err != nil {
+		return 0, false, webhookError(c, err)
+	}
+	p, _ := auth.FromContext(ctx)
+	if p == nil || (p.Subject != owner && !p.IsAdmin()) {
+		return 0, false, c.JSON(http.StatusNotFound, map[string]string{"error": service.ErrWebhookNotFound.Error()})
+	}
+	return uint(parsed), true, nil
+}
+
+func webhookError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrDeliveryNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrDeliveryPending):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidWebhook):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to process webhook request"})
+}
+
+func InitRoutes(e *echo.Echo, db *gorm.DB, allowed config.WebhookTargets) {
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), repository.NewDeliveryRepository(db), allowed)
+	h := NewHandler(webhookService, auth.NewOwnership(db))
+	logging.For("http").Info("initializing webhook routes")
+	g := e.Group("/api/v1/webhooks")
+	g.POST("", h.CreateWebhook)
+	g.GET("", h.GetWebhooks)
+	g.GET("/:id", h.GetWebhook)
+	g.DELETE("/:id", h.DeleteWebhook)
+	g.GET("/:id/deliveries", h.GetDeliveries)
+	g.POST("/:id/deliveries/:deliveryId/redeliver", h.Redeliver)
+}
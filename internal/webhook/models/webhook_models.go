@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Webhook is an endpoint registered by Owner. Empty Events or Accounts
+// filters match everything the owner can see.
+type Webhook struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	Owner     string
+	URL       string
+	Secret    string
+	Events    []string `gorm:"serializer:json"`
+	Accounts  []string `gorm:"serializer:json"`
+	Active    bool
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is one event queued for one webhook, with the outcome of its
+// latest attempt.
+type Delivery struct {
+	ID             uint `gorm:"primaryKey;autoIncrement"`
+	WebhookID      uint
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         DeliveryStatus
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
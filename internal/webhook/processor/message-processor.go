@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"txsystem/internal/webhook/service"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+)
+
+var logger = logging.For("webhooks")
+
+type messageProcessor struct {
+	dispatcher *service.Dispatcher
+}
+
+func NewMessageProcessor(dispatcher *service.Dispatcher) types.MessageProcessor {
+	return &messageProcessor{dispatcher: dispatcher}
+}
+
+// ProcessMessage queues a status event for the webhooks subscribed to it.
+// The event is delivered as received.
+func (mp *messageProcessor) ProcessMessage(ctx context.Context, message string) error {
+	var event types.TransactionStatusEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		return fmt.Errorf("failed to decode status event: %w", err)
+	}
+	if event.EventID == "" {
+		logger.WarnContext(ctx, "status event without ID, skipping")
+		return nil
+	}
+
+	n, err := mp.dispatcher.Enqueue(ctx, &event, []byte(message))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		logger.DebugContext(ctx, "queued webhook deliveries", "event_id", event.EventID, "deliveries", n)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"txsystem/internal/webhook/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, w *models.Webhook) error
+	GetByID(ctx context.Context, id uint) (*models.Webhook, error)
+	ListByOwner(ctx context.Context, owner string) ([]models.Webhook, error)
+	Delete(ctx context.Context, id uint) error
+	// ActiveForOwners returns the active webhooks registered by any of owners.
+	ActiveForOwners(ctx context.Context, owners []string) ([]models.Webhook, error)
+	// OwnersOf returns the distinct owners of the given accounts.
+	OwnersOf(ctx context.Context, accounts []string) ([]string, error)
+}
+
+type DeliveryRepository interface {
+	// Enqueue stores deliveries, skipping any already queued for the same
+	// webhook and event.
+	Enqueue(ctx context.Context, ds []models.Delivery) error
+	GetByID(ctx context.Context, id uint) (*models.Delivery, error)
+	ListByWebhook(ctx context.Context, webhookID uint, status models.DeliveryStatus, limit, offset int) ([]models.Delivery, error)
+	// Claim leases up to limit pending deliveries due at now by pushing
+	// their next attempt to now+lease, so concurrent workers skip them.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Delivery, error)
+	Update(ctx context.Context, d *models.Delivery) error
+}
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepo{db: db}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, w *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(w).Error
+}
+
+func (r *webhookRepo) GetByID(ctx context.Context, id uint) (*models.Webhook, error) {
+	var w models.Webhook
+	result := r.db.WithContext(ctx).First(&w, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &w, result.Error
+}
+
+func (r *webhookRepo) ListByOwner(ctx context.Context, owner string) ([]models.Webhook, error) {
+	var ws []models.Webhook
+	result := r.db.WithContext(ctx).Where("owner = ?", owner).Order("id").Find(&ws)
+	return ws, result.Error
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Webhook{}, id).Error
+}
+
+func (r *webhookRepo) ActiveForOwners(ctx context.Context, owners []string) ([]models.Webhook, error) {
+	var ws []models.Webhook
+	if len(owners) == 0 {
+		return ws, nil
+	}
+	result := r.db.WithContext(ctx).Where("active AND owner IN ?", owners).Order("id").Find(&ws)
+	return ws, result.Error
+}
+
+func (r *webhookRepo) OwnersOf(ctx context.Context, accounts []string) ([]string, error) {
+	var owners []string
+	// Ledger accounts, such as gl-1000, have no owner.
+	var ids []uint64
+	for _, a := range accounts {
+		if id, err := strconv.ParseUint(a, 10, 63); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return owners, nil
+	}
+	result := r.db.WithContext(ctx).Table("accounts").
+		Where("id IN ?", ids).
+		Distinct().Pluck("owner", &owners)
+	return owners, result.Error
+}
+
+type deliveryRepo struct {
+	db *gorm.DB
+}
+
+func NewDeliveryRepository(db *gorm.DB) DeliveryRepository {
+	return &deliveryRepo{db: db}
+}
+
+func (r *deliveryRepo) Enqueue(ctx context.Context, ds []models.Delivery) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "webhook_id"}, {Name: "event_id"}}, DoNothing: true}).
+		Create(&ds).Error
+}
+
+func (r *deliveryRepo) GetByID(ctx context.Context, id uint) (*models.Delivery, error) {
+	var d models.Delivery
+	result := r.db.WithContext(ctx).First(&d, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &d, result.Error
+}
+
+func (r *deliveryRepo) ListByWebhook(ctx context.Context, webhookID uint, status models.DeliveryStatus, limit, offset int) ([]models.Delivery, error) {
+	var ds []models.Delivery
+	q := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	result := q.Order("id DESC").Limit(limit).Offset(offset).Find(&ds)
+	return ds, result.Error
+}
+
+func (r *deliveryRepo) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Delivery, error) {
+	var ds []models.Delivery
+	result := r.db.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		now.Add(lease), models.DeliveryPending, now, limit,
+	).Scan(&ds)
+	return ds, result.Error
+}
+
+func (r *deliveryRepo) Update(ctx context.Context, d *models.Delivery) error {
+	return r.db.WithContext(ctx).Save(d).Error
+}
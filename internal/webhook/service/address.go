@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"txsystem/pkg/common/config"
+)
+
+// ErrPrivateAddress is returned for webhook endpoints that resolve to an
+// address inside the network, such as loopback, private ranges or the cloud
+// metadata service at 169.254.169.254, unless an allowed network covers it.
+var ErrPrivateAddress = errors.New("webhook endpoint resolves to a non-public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// report as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether addr is a public unicast address.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// targets decides which addresses webhooks may reach: public addresses, and
+// the configured allowed networks, which are empty by default.
+type targets struct {
+	allowed []netip.Prefix
+}
+
+// newTargets parses the allowed networks; config validation has already
+// rejected malformed ones.
+func newTargets(cfg config.WebhookTargets) targets {
+	var t targets
+	for _, network := range cfg.AllowedNetworks {
+		if prefix, err := netip.ParsePrefix(network); err == nil {
+			t.allowed = append(t.allowed, prefix.Masked())
+		}
+	}
+	return t
+}
+
+// permits reports whether addr may be the target of a webhook.
+func (t targets) permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if publicAddr(addr) {
+		return true
+	}
+	for _, prefix := range t.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost resolves host and fails unless every address is permitted.
+func (t targets) checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !t.permits(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+	}
+	return nil
+}
+
+// checkDial refuses connections to addresses that are not permitted. It runs
+// on the address actually dialled, so a name re-pointed after registration
+// is caught too.
+func (t targets) checkDial(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %s: %w", address, err)
+	}
+	if !t.permits(addrPort.Addr()) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
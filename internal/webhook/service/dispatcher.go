@@ -0,0 +1,294 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"txsystem/internal/webhook/models"
+	"txsystem/internal/webhook/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/types"
+)
+
+// Headers set on every delivery.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// maxErrorLen bounds the error kept in the delivery log.
+const maxErrorLen = 512
+
+var (
+	logger = logging.For("webhooks")
+
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Sign returns the signature header value for body sent at ts: the
+// timestamp and the hex HMAC-SHA256 of "<unix ts>.<body>" keyed by secret.
+func Sign(secret string, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + unix + ",v1=" + signature(secret, unix, body)
+}
+
+func signature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header produced by Sign and rejects timestamps
+// further than tolerance from now. Receivers written in Go can use it as is.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var unix, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			unix = v
+		case "v1":
+			sig = v
+		}
+	}
+	ts, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, unix, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Dispatcher fans status events out to matching webhooks and delivers them,
+// retrying failures with exponential backoff.
+type Dispatcher struct {
+	hooks      repository.WebhookRepository
+	deliveries repository.DeliveryRepository
+	client     *http.Client
+	cfg        config.Webhooks
+	now        func() time.Time
+}
+
+func NewDispatcher(hooks repository.WebhookRepository, deliveries repository.DeliveryRepository, cfg config.Webhooks, allowed config.WebhookTargets) *Dispatcher {
+	return &Dispatcher{
+		hooks:      hooks,
+		deliveries: deliveries,
+		client:     newClient(cfg.Timeout, newTargets(allowed)),
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// newClient returns a client that only connects to addresses t permits,
+// ignores proxies, which would dial on its behalf, and does not follow
+// redirects.
+func newClient(timeout time.Duration, t targets) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: t.checkDial}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Matches reports whether w subscribes to event.
+func Matches(w *models.Webhook, event *types.TransactionStatusEvent) bool {
+	if len(w.Events) > 0 && !slices.Contains(w.Events, event.Type) {
+		return false
+	}
+	if len(w.Accounts) > 0 &&
+		!slices.Contains(w.Accounts, event.SourceAccount) &&
+		!slices.Contains(w.Accounts, event.DestinationAccount) {
+		return false
+	}
+	return true
+}
+
+// Enqueue queues payload for every webhook whose owner holds one of the
+// event's accounts and whose filters match. Events already queued for a
+// webhook are skipped.
+func (d *Dispatcher) Enqueue(ctx context.Context, event *types.TransactionStatusEvent, payload []byte) (int, error) {
+	owners, err := d.hooks.OwnersOf(ctx, []string{event.SourceAccount, event.DestinationAccount})
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve account owners: %w", err)
+	}
+	hooks, err := d.hooks.ActiveForOwners(ctx, owners)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load webhooks: %w", err)
+	}
+
+	now := d.now().UTC()
+	var ds []models.Delivery
+	for i := range hooks {
+		if !Matches(&hooks[i], event) {
+			continue
+		}
+		ds = append(ds, models.Delivery{
+			WebhookID:     hooks[i].ID,
+			EventID:       event.EventID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := d.deliveries.Enqueue(ctx, ds); err != nil {
+		return 0, fmt.Errorf("failed to enqueue deliveries: %w", err)
+	}
+	return len(ds), nil
+}
+
+// Backoff is the wait after the given number of failed attempts:
+// InitialBackoff doubled per attempt, capped at MaxBackoff.
+func Backoff(cfg config.Webhooks, attempts int) time.Duration {
+	wait := cfg.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= cfg.MaxBackoff {
+			return cfg.MaxBackoff
+		}
+	}
+	return min(wait, cfg.MaxBackoff)
+}
+
+// Run delivers due webhooks every PollInterval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Keep draining while full batches come back.
+		for {
+			n, err := d.RunDue(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "webhook delivery run failed", "error", err)
+			}
+			if err != nil || n < d.cfg.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// RunDue claims up to BatchSize due deliveries and attempts them
+// concurrently. It returns how many were claimed.
+func (d *Dispatcher) RunDue(ctx context.Context) (int, error) {
+	// The lease outlasts one attempt, so a worker that dies mid-batch only
+	// delays its deliveries.
+	due, err := d.deliveries.Claim(ctx, d.now().UTC(), d.cfg.Timeout+30*time.Second, d.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(del *models.Delivery) {
+			defer wg.Done()
+			if err := d.attempt(ctx, del); err != nil {
+				logger.ErrorContext(ctx, "failed to record delivery attempt", "delivery_id", del.ID, "error", err)
+			}
+		}(&due[i])
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, del *models.Delivery) error {
+	hook, err := d.hooks.GetByID(ctx, del.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	del.Attempts++
+	var status int
+	if hook == nil || !hook.Active {
+		err = errors.New("webhook is no longer active")
+		del.Attempts = d.cfg.MaxAttempts
+	} else {
+		status, err = d.send(ctx, hook, del)
+	}
+	del.LastStatusCode = status
+
+	now := d.now().UTC()
+	switch {
+	case err == nil:
+		del.Status = models.DeliverySucceeded
+		del.LastError = ""
+		del.DeliveredAt = &now
+		metrics.WebhookDeliveries.WithLabelValues("succeeded").Inc()
+	case del.Attempts >= d.cfg.MaxAttempts:
+		del.Status = models.DeliveryFailed
+		del.LastError = err.Error()
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+		logger.WarnContext(ctx, "webhook delivery failed", "delivery_id", del.ID, "webhook_id", del.WebhookID, "attempts", del.Attempts, "error", err)
+	default:
+		del.LastError = err.Error()
+		del.NextAttemptAt = now.Add(Backoff(d.cfg, del.Attempts))
+		metrics.WebhookDeliveries.WithLabelValues("retrying").Inc()
+	}
+	return d.deliveries.Update(ctx, del)
+}
+
+// send POSTs the delivery and returns the response status. Any non-2xx
+// response, redirects included, is an error. The response body is not kept.
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, del *models.Delivery) (int, error) {
+	body := []byte(del.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "txsystem-webhooks/1")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(del.ID), 10))
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, d.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func truncate(msg string) error {
+	if len(msg) > maxErrorLen {
+		msg = msg[:maxErrorLen]
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"txsystem/internal/webhook/models"
+	"txsystem/internal/webhook/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/types"
+)
+
+type fakeHooks struct {
+	repository.WebhookRepository
+	hooks map[uint]*models.Webhook
+}
+
+func (f *fakeHooks) GetByID(_ context.Context, id uint) (*models.Webhook, error) {
+	return f.hooks[id], nil
+}
+
+type fakeDeliveries struct {
+	repository.DeliveryRepository
+	updated []models.Delivery
+}
+
+func (f *fakeDeliveries) Update(_ context.Context, d *models.Delivery) error {
+	f.updated = append(f.updated, *d)
+	return nil
+}
+
+// receiver is a local endpoint that checks every delivery's signature.
+type receiver struct {
+	*httptest.Server
+	status     int
+	hits       atomic.Int32
+	redirected atomic.Int32
+	badSig     atomic.Int32
+}
+
+func newReceiver(t *testing.T, secret string, status int) *receiver {
+	r := &receiver{status: status}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, req *http.Request) {
+		r.hits.Add(1)
+		body, _ := io.ReadAll(req.Body)
+		if Verify(secret, req.Header.Get(HeaderSignature), body, time.Now(), time.Minute) != nil ||
+			req.Header.Get(HeaderEvent) != "transaction.completed" {
+			r.badSig.Add(1)
+		}
+		if r.status == http.StatusFound {
+			http.Redirect(w, req, "/elsewhere", http.StatusFound)
+			return
+		}
+		w.WriteHeader(r.status)
+	})
+	mux.HandleFunc("/elsewhere", func(w http.ResponseWriter, _ *http.Request) {
+		r.redirected.Add(1)
+	})
+	r.Server = httptest.NewServer(mux)
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestDispatcherAttempt(t *testing.T) {
+	cfg := config.Webhooks{MaxAttempts: 3, InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute, Timeout: 2 * time.Second}
+	loopback := config.WebhookTargets{AllowedNetworks: []string{"127.0.0.0/8", "::1/128"}}
+	tests := []struct {
+		name         string
+		allowed      config.WebhookTargets
+		status       int
+		attempts     int
+		wantStatus   models.DeliveryStatus
+		wantCode     int
+		wantError    string
+		wantHits     int32
+		wantNextWait time.Duration
+	}{
+		{name: "delivered", allowed: loopback, status: http.StatusNoContent, wantStatus: models.DeliverySucceeded, wantCode: http.StatusNoContent, wantHits: 1},
+		{name: "loopback refused by default", status: http.StatusOK, wantStatus: models.DeliveryPending, wantError: ErrPrivateAddress.Error(), wantNextWait: 10 * time.Second},
+		{name: "other network allowed only", allowed: config.WebhookTargets{AllowedNetworks: []string{"10.0.0.0/8"}}, status: http.StatusOK, wantStatus: models.DeliveryPending, wantError: ErrPrivateAddress.Error(), wantNextWait: 10 * time.Second},
+		{name: "server error retried", allowed: loopback, status: http.StatusInternalServerError, attempts: 1, wantStatus: models.DeliveryPending, wantCode: 500, wantError: "endpoint returned 500", wantHits: 1, wantNextWait: 20 * time.Second},
+		{name: "redirect not followed", allowed: loopback, status: http.StatusFound, wantStatus: models.DeliveryPending, wantCode: 302, wantError: "endpoint returned 302", wantHits: 1, wantNextWait: 10 * time.Second},
+		{name: "last attempt fails", allowed: loopback, status: http.StatusBadGateway, attempts: 2, wantStatus: models.DeliveryFailed, wantCode: 502, wantError: "endpoint returned 502", wantHits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const secret = "whsec_test"
+			rcv := newReceiver(t, secret, tt.status)
+			hooks := &fakeHooks{hooks: map[uint]*models.Webhook{
+				1: {ID: 1, URL: rcv.URL + "/hook", Secret: secret, Active: true},
+			}}
+			deliveries := &fakeDeliveries{}
+			d := NewDispatcher(hooks, deliveries, cfg, tt.allowed)
+
+			del := &models.Delivery{ID: 7, WebhookID: 1, EventType: "transaction.completed", Payload: `{"id":1}`, Attempts: tt.attempts, Status: models.DeliveryPending}
+			start := time.Now()
+			if err := d.attempt(context.Background(), del); err != nil {
+				t.Fatalf("attempt: %v", err)
+			}
+
+			if len(deliveries.updated) != 1 {
+				t.Fatalf("delivery updated %d times, want once", len(deliveries.updated))
+			}
+			got := deliveries.updated[0]
+			if got.Status != tt.wantStatus || got.LastStatusCode != tt.wantCode || got.Attempts != tt.attempts+1 {
+				t.Errorf("delivery = %s, code %d, attempts %d; want %s, code %d, attempts %d",
+					got.Status, got.LastStatusCode, got.Attempts, tt.wantStatus, tt.wantCode, tt.attempts+1)
+			}
+			if !strings.Contains(got.LastError, tt.wantError) || (tt.wantError == "" && got.LastError != "") {
+				t.Errorf("last error = %q, want %q", got.LastError, tt.wantError)
+			}
+			if (got.DeliveredAt != nil) != (tt.wantStatus == models.DeliverySucceeded) {
+				t.Errorf("delivered at = %v with status %s", got.DeliveredAt, got.Status)
+			}
+			if tt.wantNextWait > 0 {
+				if wait := got.NextAttemptAt.Sub(start); wait < tt.wantNextWait || wait > tt.wantNextWait+time.Minute {
+					t.Errorf("next attempt in %s, want %s", wait, tt.wantNextWait)
+				}
+			}
+			if hits := rcv.hits.Load(); hits != tt.wantHits {
+				t.Errorf("endpoint hit %d times, want %d", hits, tt.wantHits)
+			}
+			if rcv.badSig.Load() != 0 {
+				t.Error("endpoint received a delivery with a bad signature or event header")
+			}
+			if rcv.redirected.Load() != 0 {
+				t.Error("redirect was followed")
+			}
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed config.WebhookTargets
+		url     string
+		wantErr bool
+	}{
+		{name: "loopback refused", url: "http://127.0.0.1:8080/hook", wantErr: true},
+		{name: "metadata service refused", url: "http://169.254.169.254/latest", wantErr: true},
+		{name: "private range refused", url: "http://10.1.2.3/hook", wantErr: true},
+		{name: "shared address space refused", url: "http://100.64.0.1/hook", wantErr: true},
+		{name: "mapped loopback refused", url: "http://[::ffff:127.0.0.1]/hook", wantErr: true},
+		{name: "loopback allowed", allowed: config.WebhookTargets{AllowedNetworks: []string{"127.0.0.0/8"}}, url: "http://127.0.0.1:8080/hook"},
+		{name: "allowlist is exact", allowed: config.WebhookTargets{AllowedNetworks: []string{"10.1.0.0/16"}}, url: "http://10.2.0.1/hook", wantErr: true},
+		{name: "public address", url: "https://93.184.216.34/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTargets(tt.allowed).checkHost(context.Background(), hostOf(t, tt.url))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkHost(%s) = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func hostOf(t *testing.T, raw string) string {
+	req, err := http.NewRequest(http.MethodGet, raw, nil)
+	if err != nil {
+		t.Fatalf("bad URL %s: %v", raw, err)
+	}
+	return req.URL.Hostname()
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":1}`)
+	sent := time.Unix(1700000000, 0)
+	header := Sign(secret, sent, body)
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "valid", secret: secret, header: header, body: body, now: sent},
+		{name: "within tolerance", secret: secret, header: header, body: body, now: sent.Add(5 * time.Minute)},
+		{name: "clock behind within tolerance", secret: secret, header: header, body: body, now: sent.Add(-5 * time.Minute)},
+		{name: "too old", secret: secret, header: header, body: body, now: sent.Add(5*time.Minute + time.Second), wantErr: true},
+		{name: "wrong secret", secret: "whsec_other", header: header, body: body, now: sent, wantErr: true},
+		{name: "altered body", secret: secret, header: header, body: []byte(`{"id":2}`), now: sent, wantErr: true},
+		{name: "altered timestamp", secret: secret, header: strings.Replace(header, "t=1700000000", "t=1700000001", 1), body: body, now: sent, wantErr: true},
+		{name: "no signature", secret: secret, header: "t=1700000000", body: body, now: sent, wantErr: true},
+		{name: "no timestamp", secret: secret, header: header[strings.Index(header, ",")+1:], body: body, now: sent, wantErr: true},
+		{name: "empty", secret: secret, body: body, now: sent, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.now, 5*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify(%q) = %v, want error %v", tt.header, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("error %v is not ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	event := &types.TransactionStatusEvent{Type: types.EventTransactionCompleted, SourceAccount: "1", DestinationAccount: "2"}
+	tests := []struct {
+		name string
+		hook models.Webhook
+		want bool
+	}{
+		{name: "no filters", want: true},
+		{name: "event listed", hook: models.Webhook{Events: []string{types.EventTransactionFailed, types.EventTransactionCompleted}}, want: true},
+		{name: "event not listed", hook: models.Webhook{Events: []string{types.EventTransactionFailed}}},
+		{name: "source account", hook: models.Webhook{Accounts: []string{"1"}}, want: true},
+		{name: "destination account", hook: models.Webhook{Accounts: []string{"3", "2"}}, want: true},
+		{name: "other account", hook: models.Webhook{Accounts: []string{"3"}}},
+		{name: "both filters", hook: models.Webhook{Events: []string{types.EventTransactionCompleted}, Accounts: []string{"2"}}, want: true},
+		{name: "account but not event", hook: models.Webhook{Events: []string{types.EventTransactionFailed}, Accounts: []string{"2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(&tt.hook, event); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cfg := config.Webhooks{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{50, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := Backoff(cfg, tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+	"txsystem/internal/webhook/models"
+	"txsystem/internal/webhook/repository"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/types"
+)
+
+var (
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrDeliveryPending  = errors.New("delivery is still pending")
+)
+
+// EventTypes are the events a webhook can subscribe to.
+var EventTypes = []string{types.EventTransactionCompleted, types.EventTransactionFailed}
+
+// WebhookService manages webhook registrations and their delivery log.
+type WebhookService struct {
+	hooks      repository.WebhookRepository
+	deliveries repository.DeliveryRepository
+	targets    targets
+	now        func() time.Time
+}
+
+func NewWebhookService(hooks repository.WebhookRepository, deliveries repository.DeliveryRepository, allowed config.WebhookTargets) *WebhookService {
+	return &WebhookService{hooks: hooks, deliveries: deliveries, targets: newTargets(allowed), now: time.Now}
+}
+
+func toWebhookResponse(m *models.Webhook) *types.WebhookResponse {
+	return &types.WebhookResponse{
+		ID:        uint64(m.ID),
+		URL:       m.URL,
+		Events:    nonNil(m.Events),
+		Accounts:  nonNil(m.Accounts),
+		Active:    m.Active,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func toDeliveryResponse(m *models.Delivery) *types.WebhookDeliveryResponse {
+	resp := &types.WebhookDeliveryResponse{
+		ID:             uint64(m.ID),
+		WebhookID:      uint64(m.WebhookID),
+		EventID:        m.EventID,
+		EventType:      m.EventType,
+		Status:         string(m.Status),
+		Attempts:       m.Attempts,
+		LastStatusCode: m.LastStatusCode,
+		LastError:      m.LastError,
+		CreatedAt:      m.CreatedAt.Format(time.RFC3339),
+	}
+	if m.Status == models.DeliveryPending {
+		resp.NextAttemptAt = m.NextAttemptAt.Format(time.RFC3339)
+	}
+	if m.DeliveredAt != nil {
+		resp.DeliveredAt = m.DeliveredAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// Register stores a webhook for owner and returns it with its signing
+// secret, which is not shown again. The URL's host must resolve to public
+// addresses or allowed networks only.
+func (ws *WebhookService) Register(ctx context.Context, owner string, req *types.WebhookRequest) (*types.WebhookResponse, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if err := ws.targets.checkHost(ctx, u.Hostname()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidWebhook, err)
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(EventTypes, event) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &models.Webhook{
+		Owner:    owner,
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   req.Events,
+		Accounts: req.Accounts,
+		Active:   true,
+	}
+	if err := ws.hooks.Create(ctx, w); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	resp := toWebhookResponse(w)
+	resp.Secret = secret
+	return resp, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func (ws *WebhookService) List(ctx context.Context, owner string) ([]*types.WebhookResponse, error) {
+	hooks, err := ws.hooks.ListByOwner(ctx, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	resp := make([]*types.WebhookResponse, 0, len(hooks))
+	for i := range hooks {
+		resp = append(resp, toWebhookResponse(&hooks[i]))
+	}
+	return resp, nil
+}
+
+// Owner returns who registered webhook id, for authorization.
+func (ws *WebhookService) Owner(ctx context.Context, id uint) (string, error) {
+	w, err := ws.hooks.GetByID(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if w == nil {
+		return "", ErrWebhookNotFound
+	}
+	return w.Owner, nil
+}
+
+func (ws *WebhookService) Get(ctx context.Context, id uint) (*types.WebhookResponse, error) {
+	w, err := ws.hooks.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if w == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return toWebhookResponse(w), nil
+}
+
+// Delete removes the webhook and its delivery log.
+func (ws *WebhookService) Delete(ctx context.Context, id uint) error {
+	if err := ws.hooks.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// Deliveries lists the webhook's latest deliveries, newest first, optionally
+// filtered by status.
+func (ws *WebhookService) Deliveries(ctx context.Context, webhookID uint, status models.DeliveryStatus) ([]*types.WebhookDeliveryResponse, error) {
+	ds, err := ws.deliveries.ListByWebhook(ctx, webhookID, status, 100, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	resp := make([]*types.WebhookDeliveryResponse, 0, len(ds))
+	for i := range ds {
+		resp = append(resp, toDeliveryResponse(&ds[i]))
+	}
+	return resp, nil
+}
+
+// Redeliver queues a finished delivery of webhookID again with a fresh
+// attempt budget.
+func (ws *WebhookService) Redeliver(ctx context.Context, webhookID, id uint) (*types.WebhookDeliveryResponse, error) {
+	d, err := ws.deliveries.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	if d == nil || d.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+	if d.Status == models.DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+
+	d.Status = models.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = ws.now().UTC()
+	if err := ws.deliveries.Update(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to requeue delivery: %w", err)
+	}
+	return toDeliveryResponse(d), nil
+}
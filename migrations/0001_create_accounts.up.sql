@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS accounts (
+    id         BIGSERIAL PRIMARY KEY,
+    owner      TEXT NOT NULL DEFAULT '',
+    balance    DECIMAL NOT NULL DEFAULT 0,
+    currency   TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_owner ON accounts (owner);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id                  BIGSERIAL PRIMARY KEY,
+    amount              DECIMAL NOT NULL DEFAULT 0,
+    currency            TEXT NOT NULL DEFAULT '',
+    description         TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    source_account      TEXT NOT NULL DEFAULT '',
+    destination_account TEXT NOT NULL DEFAULT '',
+    transaction_type    TEXT NOT NULL DEFAULT '',
+    status              TEXT NOT NULL DEFAULT 'pending',
+    transaction_id      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_source_account ON transactions (source_account);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_account ON transactions (destination_account);
@@ -0,0 +1,18 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS held_balance DECIMAL NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id                  BIGSERIAL PRIMARY KEY,
+    account_id          BIGINT NOT NULL REFERENCES accounts (id),
+    destination_account BIGINT NOT NULL REFERENCES accounts (id),
+    amount              DECIMAL NOT NULL,
+    captured_amount     DECIMAL NOT NULL DEFAULT 0,
+    currency            TEXT NOT NULL DEFAULT '',
+    description         TEXT NOT NULL DEFAULT '',
+    status              TEXT NOT NULL DEFAULT 'active',
+    expires_at          TIMESTAMPTZ NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_account_id ON holds (account_id);
+CREATE INDEX IF NOT EXISTS idx_holds_active_expires_at ON holds (expires_at) WHERE status = 'active';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_transaction_id;
+
+DROP TABLE IF EXISTS schedules;
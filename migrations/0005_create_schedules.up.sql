@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS schedules (
+    id                  BIGSERIAL PRIMARY KEY,
+    amount              DECIMAL NOT NULL,
+    description         TEXT NOT NULL DEFAULT '',
+    source_account      TEXT NOT NULL,
+    destination_account TEXT NOT NULL,
+    transaction_type    TEXT NOT NULL DEFAULT '',
+    execute_at          TIMESTAMPTZ NOT NULL,
+    frequency           TEXT NOT NULL DEFAULT '',
+    until               TIMESTAMPTZ,
+    max_occurrences     INTEGER NOT NULL DEFAULT 0,
+    next_index          INTEGER NOT NULL DEFAULT 0,
+    occurrences         INTEGER NOT NULL DEFAULT 0,
+    next_run_at         TIMESTAMPTZ,
+    status              TEXT NOT NULL DEFAULT 'active',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_source_account ON schedules (source_account);
+CREATE INDEX IF NOT EXISTS idx_schedules_due ON schedules (next_run_at) WHERE status = 'active';
+
+-- Occurrence keys make materialising a scheduled transfer idempotent.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions (transaction_id) WHERE transaction_id <> '';
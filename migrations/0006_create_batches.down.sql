@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_transactions_batch_id;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS batch_index;
+ALTER TABLE transactions DROP COLUMN IF EXISTS batch_id;
+
+DROP TABLE IF EXISTS batches;
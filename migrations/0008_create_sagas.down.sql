@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS transfer_records;
+DROP TABLE IF EXISTS sagas;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS sagas (
+    id                  BIGSERIAL PRIMARY KEY,
+    transaction_id      BIGINT NOT NULL REFERENCES transactions (id),
+    amount              DECIMAL NOT NULL,
+    source_account      TEXT NOT NULL DEFAULT '',
+    destination_account TEXT NOT NULL DEFAULT '',
+    transaction_type    TEXT NOT NULL DEFAULT '',
+    status              TEXT NOT NULL DEFAULT 'running',
+    step                TEXT NOT NULL DEFAULT '',
+    attempts            INTEGER NOT NULL DEFAULT 0,
+    failure_reason      TEXT NOT NULL DEFAULT '',
+    deadline_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- One saga per transaction, so redelivered transaction events start nothing.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sagas_transaction_id ON sagas (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_sagas_deadline_at ON sagas (deadline_at) WHERE status IN ('running', 'compensating');
+
+-- Balance moves made by the account participant, keyed by transaction so a
+-- repeated command moves nothing twice and a reversal undoes exactly one move.
+CREATE TABLE IF NOT EXISTS transfer_records (
+    transaction_id      BIGINT PRIMARY KEY,
+    source_account      BIGINT NOT NULL DEFAULT 0,
+    destination_account BIGINT NOT NULL DEFAULT 0,
+    amount              DECIMAL NOT NULL DEFAULT 0,
+    status              TEXT NOT NULL,
+    reason              TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
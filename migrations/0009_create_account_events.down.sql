@@ -0,0 +1,3 @@
+ALTER TABLE accounts DROP COLUMN IF EXISTS version;
+DROP TABLE IF EXISTS account_snapshots;
+DROP TABLE IF EXISTS account_events;
@@ -0,0 +1,38 @@
+-- Every change to an account balance, in order. The accounts table is a
+-- projection of these events and can be rebuilt from them.
+CREATE TABLE IF NOT EXISTS account_events (
+    id             BIGSERIAL PRIMARY KEY,
+    account_id     BIGINT NOT NULL REFERENCES accounts (id),
+    version        BIGINT NOT NULL,
+    type           TEXT NOT NULL,
+    amount         DECIMAL NOT NULL DEFAULT 0,
+    owner          TEXT NOT NULL DEFAULT '',
+    currency       TEXT NOT NULL DEFAULT '',
+    transaction_id BIGINT,
+    hold_id        BIGINT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_events_account_version ON account_events (account_id, version);
+
+CREATE TABLE IF NOT EXISTS account_snapshots (
+    account_id   BIGINT NOT NULL REFERENCES accounts (id),
+    version      BIGINT NOT NULL,
+    owner        TEXT NOT NULL DEFAULT '',
+    currency     TEXT NOT NULL DEFAULT '',
+    balance      DECIMAL NOT NULL DEFAULT 0,
+    held_balance DECIMAL NOT NULL DEFAULT 0,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, version)
+);
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
+
+-- Existing accounts start their history with what they hold today.
+INSERT INTO account_events (account_id, version, type, amount, owner, currency)
+SELECT id, 1, 'account_opened', balance, owner, currency FROM accounts WHERE version = 0;
+
+INSERT INTO account_events (account_id, version, type, amount)
+SELECT id, 2, 'hold_placed', held_balance FROM accounts WHERE version = 0 AND held_balance <> 0;
+
+UPDATE accounts SET version = CASE WHEN held_balance <> 0 THEN 2 ELSE 1 END WHERE version = 0;
@@ -0,0 +1,9 @@
+-- Messages a consumer has handled, with the answer it gave, so a redelivered
+-- message gets the same answer without being handled again.
+CREATE TABLE IF NOT EXISTS inbox_messages (
+    consumer     TEXT NOT NULL,
+    message_key  TEXT NOT NULL,
+    result       TEXT NOT NULL DEFAULT '',
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (consumer, message_key)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE transfer_records DROP COLUMN IF EXISTS fee;
+ALTER TABLE sagas DROP COLUMN IF EXISTS fee;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fee_schedule_version;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fee;
+ALTER TABLE accounts DROP COLUMN IF EXISTS tier;
+DROP TABLE IF EXISTS fee_schedules;
@@ -0,0 +1,16 @@
+-- Every version of the fee schedules ever loaded, so a transaction can be
+-- traced to the schedules it was charged under.
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    version    INTEGER PRIMARY KEY,
+    document   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS tier TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_schedule_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE sagas ADD COLUMN IF NOT EXISTS fee DECIMAL NOT NULL DEFAULT 0;
+
+ALTER TABLE transfer_records ADD COLUMN IF NOT EXISTS fee DECIMAL NOT NULL DEFAULT 0;
@@ -0,0 +1,44 @@
+-- Interest terms of the accounts that earn interest. annual_rate is a
+-- percentage.
+CREATE TABLE IF NOT EXISTS interest_configs (
+    account_id  BIGINT PRIMARY KEY REFERENCES accounts (id),
+    annual_rate DECIMAL NOT NULL DEFAULT 0,
+    day_count   TEXT NOT NULL DEFAULT 'actual/365',
+    compounding TEXT NOT NULL DEFAULT 'monthly',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Interest earned by an account on one day, at most once per day. posted_on
+-- is the date of the posting that paid it out.
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    account_id   BIGINT NOT NULL REFERENCES accounts (id),
+    accrual_date DATE NOT NULL,
+    balance      DECIMAL NOT NULL,
+    annual_rate  DECIMAL NOT NULL,
+    day_count    TEXT NOT NULL,
+    amount       DECIMAL NOT NULL,
+    posted_on    DATE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, accrual_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_interest_accruals_unposted ON interest_accruals (account_id, accrual_date) WHERE posted_on IS NULL;
+
+-- Accrued interest credited to an account, at most once per posting date.
+CREATE TABLE IF NOT EXISTS interest_postings (
+    account_id     BIGINT NOT NULL REFERENCES accounts (id),
+    posting_date   DATE NOT NULL,
+    amount         DECIMAL NOT NULL,
+    transaction_id BIGINT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, posting_date)
+);
+
+-- Dates the accrual and posting jobs have completed.
+CREATE TABLE IF NOT EXISTS interest_runs (
+    job        TEXT NOT NULL,
+    run_date   DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (job, run_date)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE transfer_records DROP COLUMN IF EXISTS overdraft_fee;
+ALTER TABLE transfer_records DROP COLUMN IF EXISTS overdraft_drawn;
+ALTER TABLE accounts DROP COLUMN IF EXISTS overdraft_fee;
+ALTER TABLE accounts DROP COLUMN IF EXISTS overdraft_limit;
@@ -0,0 +1,8 @@
+-- How far an account may go below zero, and the fee charged on a transfer
+-- that draws on it.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_limit DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS overdraft_fee DECIMAL NOT NULL DEFAULT 0;
+
+-- The overdraft each transfer drew and the overdraft fee it was charged.
+ALTER TABLE transfer_records ADD COLUMN IF NOT EXISTS overdraft_drawn DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE transfer_records ADD COLUMN IF NOT EXISTS overdraft_fee DECIMAL NOT NULL DEFAULT 0;
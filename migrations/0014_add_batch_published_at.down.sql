@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_batches_unpublished;
+ALTER TABLE batches DROP COLUMN IF EXISTS published_at;
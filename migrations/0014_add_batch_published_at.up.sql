@@ -0,0 +1,7 @@
+-- When a batch's events were published. The rows commit first, so a batch
+-- left unpublished by a failed publish is published again by the
+-- transaction service. Earlier batches were published as they committed.
+ALTER TABLE batches ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+UPDATE batches SET published_at = created_at WHERE published_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_batches_unpublished ON batches (created_at) WHERE published_at IS NULL;
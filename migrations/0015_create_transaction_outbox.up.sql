@@ -0,0 +1,11 @@
+-- Transactions the account service settles itself, such as captures,
+-- interest postings and opening balances, written with the balance change.
+-- published_at is set once the transaction is on the transactions topic, so
+-- one left unpublished by a failed publish is published again.
+CREATE TABLE IF NOT EXISTS transaction_outbox (
+    transaction_id BIGINT PRIMARY KEY REFERENCES transactions (id),
+    published_at   TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_outbox_unpublished ON transaction_outbox (created_at) WHERE published_at IS NULL;
@@ -0,0 +1,8 @@
+// Package migrations embeds the versioned Postgres schema shared by the
+// account and transaction services. Apply it with `go run ./cmd/migrate up`.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
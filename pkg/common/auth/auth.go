@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"txsystem/pkg/common/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// Principal is the authenticated caller. Subject matches models.Account.Owner.
+type Principal struct {
+	Subject string
+	Roles   []string
+	admin   string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(p.admin)
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the caller set by the middleware, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Verifier validates bearer tokens signed with HS256 or RS256.
+type Verifier struct {
+	cfg     config.Auth
+	hmacKey []byte
+	rsaKeys map[string]*rsa.PublicKey
+}
+
+func NewVerifier(cfg config.Auth) (*Verifier, error) {
+	v := &Verifier{cfg: cfg}
+	switch cfg.Algorithm {
+	case "HS256":
+		v.hmacKey = []byte(cfg.HMACSecret)
+	case "RS256":
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys = keys
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	return v, nil
+}
+
+// Verify parses token and returns the caller it identifies.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{v.cfg.Algorithm}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.cfg.Issuer))
+	}
+	if v.cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.cfg.Audience))
+	}
+
+	var c claims
+	if _, err := jwt.ParseWithClaims(token, &c, v.key, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return &Principal{Subject: c.Subject, Roles: c.Roles, admin: v.cfg.AdminRole}, nil
+}
+
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	if v.hmacKey != nil {
+		return v.hmacKey, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	if key, ok := v.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	// A single key set may be used without kid headers.
+	if kid == "" && len(v.rsaKeys) == 1 {
+		for _, key := range v.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads RSA public keys from a local JWKS file, used in development
+// in place of fetching the identity provider's key set.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no RSA keys", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"txsystem/pkg/common/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func hsConfig() config.Auth {
+	return config.Auth{Algorithm: "HS256", HMACSecret: testSecret, Issuer: "txsystem", Audience: "api", AdminRole: "admin"}
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "alice",
+		"iss":   "txsystem",
+		"aud":   "api",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+	}
+}
+
+func hsToken(t *testing.T, claims jwt.MapClaims, secret string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v, err := NewVerifier(hsConfig())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	with := func(key string, value any) jwt.MapClaims {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: hsToken(t, validClaims(), testSecret)},
+		{name: "wrong secret", token: hsToken(t, validClaims(), "another-secret-another-secret-xx"), wantErr: true},
+		{name: "expired", token: hsToken(t, with("exp", time.Now().Add(-time.Minute).Unix()), testSecret), wantErr: true},
+		{name: "no expiry", token: hsToken(t, with("exp", nil), testSecret), wantErr: true},
+		{name: "other issuer", token: hsToken(t, with("iss", "someone-else"), testSecret), wantErr: true},
+		{name: "other audience", token: hsToken(t, with("aud", "admin-ui"), testSecret), wantErr: true},
+		{name: "no subject", token: hsToken(t, with("sub", nil), testSecret), wantErr: true},
+		{name: "unsigned", token: unsignedToken(t, validClaims()), wantErr: true},
+		{name: "garbage", token: "not.a.token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("Verify = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if p.Subject != "alice" || !p.IsAdmin() {
+				t.Errorf("principal = %+v, want admin alice", p)
+			}
+		})
+	}
+}
+
+func unsignedToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	rsToken := func(k *rsa.PrivateKey, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(k)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		keys    map[string]*rsa.PublicKey
+		token   string
+		wantErr bool
+	}{
+		{name: "matching kid", keys: map[string]*rsa.PublicKey{"a": &other.PublicKey, "b": &key.PublicKey}, token: rsToken(key, "b")},
+		{name: "kid of another key", keys: map[string]*rsa.PublicKey{"a": &other.PublicKey, "b": &key.PublicKey}, token: rsToken(key, "a"), wantErr: true},
+		{name: "unknown kid", keys: map[string]*rsa.PublicKey{"a": &key.PublicKey}, token: rsToken(key, "z"), wantErr: true},
+		{name: "no kid with a single key", keys: map[string]*rsa.PublicKey{"a": &key.PublicKey}, token: rsToken(key, "")},
+		{name: "no kid with several keys", keys: map[string]*rsa.PublicKey{"a": &key.PublicKey, "b": &other.PublicKey}, token: rsToken(key, ""), wantErr: true},
+		{name: "HS256 token", keys: map[string]*rsa.PublicKey{"a": &key.PublicKey}, token: hsToken(t, validClaims(), testSecret), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := hsConfig()
+			cfg.Algorithm, cfg.HMACSecret, cfg.JWKSFile = "RS256", "", writeJWKS(t, tt.keys)
+			v, err := NewVerifier(cfg)
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+			_, err = v.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// writeJWKS stores the keys as a JWKS file, with an EC entry that must be
+// skipped, and returns its path.
+func writeJWKS(t *testing.T, keys map[string]*rsa.PublicKey) string {
+	t.Helper()
+	set := []map[string]string{{"kty": "EC", "kid": "ec", "crv": "P-256"}}
+	for kid, k := range keys {
+		set = append(set, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(map[string]any{"keys": set})
+	if err != nil {
+		t.Fatalf("encode JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write JWKS: %v", err)
+	}
+	return path
+}
+
+func TestMiddleware(t *testing.T) {
+	v, err := NewVerifier(hsConfig())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	user := validClaims()
+	user["roles"] = []string{"viewer"}
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		admin    bool
+		wantCode int
+	}{
+		{name: "public path", path: "/healthz", wantCode: http.StatusOK},
+		{name: "docs", path: "/swagger/index.html", wantCode: http.StatusOK},
+		{name: "under a public path", path: "/healthz/details", wantCode: http.StatusUnauthorized},
+		{name: "no token", path: "/api/v1/accounts", wantCode: http.StatusUnauthorized},
+		{name: "not bearer", path: "/api/v1/accounts", header: "Basic YWxpY2U6cHc=", wantCode: http.StatusUnauthorized},
+		{name: "invalid token", path: "/api/v1/accounts", header: "Bearer nope", wantCode: http.StatusUnauthorized},
+		{name: "valid token", path: "/api/v1/accounts", header: "Bearer " + hsToken(t, user, testSecret), wantCode: http.StatusOK},
+		{name: "admin route without the role", path: "/api/v1/admin", header: "Bearer " + hsToken(t, user, testSecret), admin: true, wantCode: http.StatusForbidden},
+		{name: "admin route", path: "/api/v1/admin", header: "Bearer " + hsToken(t, validClaims(), testSecret), admin: true, wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(Middleware(v))
+			handler := func(c echo.Context) error {
+				if p, ok := FromContext(c.Request().Context()); ok {
+					return c.String(http.StatusOK, p.Subject)
+				}
+				return c.NoContent(http.StatusOK)
+			}
+			if tt.admin {
+				e.GET(tt.path, handler, RequireAdmin())
+			} else {
+				e.GET(tt.path, handler)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.header != "" && rec.Code == http.StatusOK && rec.Body.String() != "alice" {
+				t.Errorf("principal = %q, want alice", rec.Body)
+			}
+		})
+	}
+}
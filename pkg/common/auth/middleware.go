@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// publicPaths are reachable without a token: probes and scraping. They are
+// matched exactly, so routes added under them still need a token.
+var publicPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// publicPrefixes are reachable without a token: the API docs.
+var publicPrefixes = []string{"/swagger/"}
+
+// Middleware authenticates every request except the public ones and stores
+// the caller in the request context.
+func Middleware(v *Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if publicPaths[path] {
+				return next(c)
+			}
+			for _, prefix := range publicPrefixes {
+				if strings.HasPrefix(path, prefix) {
+					return next(c)
+				}
+			}
+
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || token == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": ErrMissingToken.Error()})
+			}
+
+			principal, err := v.Verify(token)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": ErrInvalidToken.Error()})
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(WithPrincipal(req.Context(), principal)))
+			return next(c)
+		}
+	}
+}
+
+// RequireAdmin rejects callers without the admin role.
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, ok := FromContext(c.Request().Context())
+			if !ok || !p.IsAdmin() {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": ErrForbidden.Error()})
+			}
+			return next(c)
+		}
+	}
+}
+
+// AdminHandler serves h only to callers presenting a valid token with the
+// admin role. It guards admin endpoints on plain HTTP listeners, such as the
+// consumers' health port.
+func AdminHandler(v *Verifier, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, ErrMissingToken)
+			return
+		}
+		principal, err := v.Verify(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, ErrInvalidToken)
+			return
+		}
+		if !principal.IsAdmin() {
+			writeError(w, http.StatusForbidden, ErrForbidden)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
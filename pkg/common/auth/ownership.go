@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Ownership answers which accounts belong to a caller by reading the owner
+// column of the shared accounts table.
+type Ownership struct {
+	db *gorm.DB
+}
+
+func NewOwnership(db *gorm.DB) *Ownership {
+	return &Ownership{db: db}
+}
+
+// Owns reports whether accountID belongs to owner. An ID that is not a
+// number, such as a ledger account, belongs to nobody.
+func (o *Ownership) Owns(ctx context.Context, owner, accountID string) (bool, error) {
+	id, err := strconv.ParseUint(accountID, 10, 63)
+	if err != nil {
+		return false, nil
+	}
+	var count int64
+	err = o.db.WithContext(ctx).
+		Table("accounts").
+		Where("id = ? AND owner = ?", id, owner).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check account owner: %w", err)
+	}
+	return count > 0, nil
+}
+
+// AccountsOf lists the IDs of the accounts owned by owner.
+func (o *Ownership) AccountsOf(ctx context.Context, owner string) ([]string, error) {
+	var ids []uint64
+	err := o.db.WithContext(ctx).
+		Table("accounts").
+		Where("owner = ?", owner).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	accounts := make([]string, len(ids))
+	for i, id := range ids {
+		accounts[i] = strconv.FormatUint(id, 10)
+	}
+	return accounts, nil
+}
+
+// CanAccess reports whether the caller may act on accountID: admins may act
+// on any account, everyone else only on their own.
+func (o *Ownership) CanAccess(ctx context.Context, accountID string) (bool, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return false, nil
+	}
+	if p.IsAdmin() {
+		return true, nil
+	}
+	return o.Owns(ctx, p.Subject, accountID)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Options controls where configuration is read from.
+type Options struct {
+	// File is an optional YAML file. Values from the environment override it.
+	File string
+	// EnvFile is an optional dotenv file. It is skipped when missing.
+	EnvFile string
+	// PrintConfig asks the caller to dump the resolved configuration and exit.
+	PrintConfig bool
+}
+
+// ParseFlags reads the common command line flags shared by every binary.
+func ParseFlags() Options {
+	opts := Options{EnvFile: ".env"}
+	flag.StringVar(&opts.File, "config", os.Getenv("CONFIG_FILE"), "path to an optional YAML config file")
+	flag.StringVar(&opts.EnvFile, "env-file", opts.EnvFile, "path to an optional .env file")
+	flag.BoolVar(&opts.PrintConfig, "print-config", false, "print the resolved configuration with secrets redacted and exit")
+	flag.Parse()
+	return opts
+}
+
+// Load fills cfg from defaults, the YAML file, the .env file and the process
+// environment, in increasing order of precedence, then validates it.
+func Load(cfg any, opts Options) error {
+	if err := applyDefaults(cfg); err != nil {
+		return err
+	}
+
+	if opts.File != "" {
+		data, err := os.ReadFile(opts.File)
+		if err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", opts.File, err)
+		}
+	}
+
+	if opts.EnvFile != "" {
+		// godotenv never overrides variables that are already set, which keeps
+		// the real environment ahead of the file.
+		if err := godotenv.Load(opts.EnvFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load env file %s: %w", opts.EnvFile, err)
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return err
+	}
+
+	return Validate(cfg)
+}
+
+// MustLoad parses the command line, loads cfg and handles --print-config. It
+// exits the process with a readable report when the configuration is invalid.
+func MustLoad(cfg any) {
+	opts := ParseFlags()
+
+	err := Load(cfg, opts)
+	if opts.PrintConfig {
+		if printErr := Print(os.Stdout, cfg); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Print writes cfg as YAML with every field tagged secret replaced.
+func Print(w io.Writer, cfg any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(redact(cfg)); err != nil {
+		return fmt.Errorf("failed to render config: %w", err)
+	}
+	return enc.Close()
+}
+
+// ValidationError aggregates every problem found in a configuration so they
+// can be fixed in one go rather than one restart at a time.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// Validator is implemented by sections that need checks beyond required fields.
+type Validator interface {
+	Validate() []string
+}
+
+// Validate checks required fields and runs section validators.
+func Validate(cfg any) error {
+	problems := validate(cfg)
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
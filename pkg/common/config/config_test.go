@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSection struct {
+	Limit int `yaml:"limit" env:"CFGTEST_LIMIT" default:"5"`
+}
+
+func (s *testSection) Validate() []string {
+	if s.Limit > 10 {
+		return []string{"CFGTEST_LIMIT must be at most 10"}
+	}
+	return nil
+}
+
+type testConfig struct {
+	Name     string        `yaml:"name" env:"CFGTEST_NAME,CFGTEST_LEGACY_NAME" default:"svc"`
+	Host     string        `yaml:"host" env:"CFGTEST_HOST" required:"true"`
+	Password string        `yaml:"password" env:"CFGTEST_PASSWORD" secret:"true"`
+	Timeout  time.Duration `yaml:"timeout" env:"CFGTEST_TIMEOUT" default:"2s"`
+	Ratio    float64       `yaml:"ratio" env:"CFGTEST_RATIO"`
+	Enabled  bool          `yaml:"enabled" env:"CFGTEST_ENABLED" default:"true"`
+	Brokers  []string      `yaml:"brokers" env:"CFGTEST_BROKERS"`
+	Section  testSection   `yaml:"section"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		envFile      string
+		env          map[string]string
+		want         testConfig
+		wantProblems []string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"CFGTEST_HOST": "db"},
+			want: testConfig{Name: "svc", Host: "db", Timeout: 2 * time.Second, Enabled: true, Section: testSection{Limit: 5}},
+		},
+		{
+			name: "file over defaults",
+			yaml: "name: file\nhost: db\ntimeout: 1m\nenabled: false\nsection:\n  limit: 7\n",
+			want: testConfig{Name: "file", Host: "db", Timeout: time.Minute, Section: testSection{Limit: 7}},
+		},
+		{
+			name:    "env file over file",
+			yaml:    "name: file\nhost: db\n",
+			envFile: "CFGTEST_NAME=dotenv\nCFGTEST_RATIO=0.5\n",
+			want:    testConfig{Name: "dotenv", Host: "db", Ratio: 0.5, Timeout: 2 * time.Second, Enabled: true, Section: testSection{Limit: 5}},
+		},
+		{
+			name:    "environment over env file",
+			yaml:    "host: db\n",
+			envFile: "CFGTEST_NAME=dotenv\n",
+			env:     map[string]string{"CFGTEST_NAME": "env", "CFGTEST_BROKERS": "a:9092, b:9092,,", "CFGTEST_LIMIT": "9"},
+			want:    testConfig{Name: "env", Host: "db", Timeout: 2 * time.Second, Enabled: true, Brokers: []string{"a:9092", "b:9092"}, Section: testSection{Limit: 9}},
+		},
+		{
+			name: "legacy name",
+			env:  map[string]string{"CFGTEST_HOST": "db", "CFGTEST_LEGACY_NAME": "old"},
+			want: testConfig{Name: "old", Host: "db", Timeout: 2 * time.Second, Enabled: true, Section: testSection{Limit: 5}},
+		},
+		{
+			name: "empty variable is unset",
+			env:  map[string]string{"CFGTEST_HOST": "db", "CFGTEST_NAME": ""},
+			want: testConfig{Name: "svc", Host: "db", Timeout: 2 * time.Second, Enabled: true, Section: testSection{Limit: 5}},
+		},
+		{
+			name:         "required and section problems together",
+			env:          map[string]string{"CFGTEST_LIMIT": "11"},
+			wantProblems: []string{"CFGTEST_HOST is required", "CFGTEST_LIMIT must be at most 10"},
+		},
+		{
+			name:         "bad values together",
+			env:          map[string]string{"CFGTEST_HOST": "db", "CFGTEST_TIMEOUT": "soon", "CFGTEST_ENABLED": "maybe", "CFGTEST_LIMIT": "x"},
+			wantProblems: []string{`CFGTEST_TIMEOUT: time: invalid duration "soon"`, `CFGTEST_ENABLED: expected a boolean, got "maybe"`, `CFGTEST_LIMIT: expected an integer, got "x"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"CFGTEST_NAME", "CFGTEST_LEGACY_NAME", "CFGTEST_HOST", "CFGTEST_PASSWORD", "CFGTEST_TIMEOUT", "CFGTEST_RATIO", "CFGTEST_ENABLED", "CFGTEST_BROKERS", "CFGTEST_LIMIT"} {
+				t.Setenv(name, tt.env[name])
+				if _, ok := tt.env[name]; !ok {
+					os.Unsetenv(name)
+				}
+			}
+			opts := Options{EnvFile: filepath.Join(t.TempDir(), "missing.env")}
+			if tt.yaml != "" {
+				opts.File = writeFile(t, "config.yaml", tt.yaml)
+			}
+			if tt.envFile != "" {
+				opts.EnvFile = writeFile(t, ".env", tt.envFile)
+			}
+
+			var cfg testConfig
+			err := Load(&cfg, opts)
+			if tt.wantProblems != nil {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("Load = %v, want a validation error", err)
+				}
+				if !slices.Equal(invalid.Problems, tt.wantProblems) {
+					t.Errorf("problems = %q, want %q", invalid.Problems, tt.wantProblems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Name != tt.want.Name || cfg.Host != tt.want.Host || cfg.Timeout != tt.want.Timeout ||
+				cfg.Ratio != tt.want.Ratio || cfg.Enabled != tt.want.Enabled ||
+				!slices.Equal(cfg.Brokers, tt.want.Brokers) || cfg.Section != tt.want.Section {
+				t.Errorf("config = %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{name: "set", password: "hunter2", want: "password: '" + redacted + "'"},
+		{name: "empty stays empty", want: `password: ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &testConfig{Name: "svc", Password: tt.password}
+			var out bytes.Buffer
+			if err := Print(&out, cfg); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if !strings.Contains(out.String(), tt.want) || (tt.password != "" && strings.Contains(out.String(), tt.password)) {
+				t.Errorf("printed config:\n%s\nwant %q", out.String(), tt.want)
+			}
+			if cfg.Password != tt.password {
+				t.Errorf("Print changed the config: password = %q", cfg.Password)
+			}
+		})
+	}
+}
+
+func TestPostgresValidate(t *testing.T) {
+	base := Postgres{Port: "5432", SSLMode: "disable", MaxOpenConns: 20, MaxIdleConns: 5, ConnectAttempts: 1}
+	tests := []struct {
+		name   string
+		change func(p *Postgres)
+		want   []string
+	}{
+		{name: "valid", change: func(*Postgres) {}},
+		{name: "bad port", change: func(p *Postgres) { p.Port = "70000" }, want: []string{`POSTGRES_PORT "70000" is not a valid port`}},
+		{name: "unknown ssl mode", change: func(p *Postgres) { p.SSLMode = "on" }, want: []string{`POSTGRES_SSLMODE "on" must be one of disable, allow, prefer, require, verify-ca, verify-full`}},
+		{name: "verify without root cert", change: func(p *Postgres) { p.SSLMode = "verify-full" }, want: []string{"POSTGRES_SSLROOTCERT is required with POSTGRES_SSLMODE=verify-full"}},
+		{name: "cert without key", change: func(p *Postgres) { p.SSLCert = "client.crt" }, want: []string{"POSTGRES_SSLCERT and POSTGRES_SSLKEY must be set together"}},
+		{name: "more idle than open", change: func(p *Postgres) { p.MaxIdleConns = 30 }, want: []string{"POSTGRES_MAX_IDLE_CONNS cannot exceed POSTGRES_MAX_OPEN_CONNS"}},
+		{name: "no attempts", change: func(p *Postgres) { p.ConnectAttempts = 0 }, want: []string{"POSTGRES_CONNECT_ATTEMPTS must be at least 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base
+			tt.change(&p)
+			if got := p.Validate(); !slices.Equal(got, tt.want) {
+				t.Errorf("Validate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
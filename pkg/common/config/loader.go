@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags understood by the loader:
+//
+//	env:"NAME,LEGACY_NAME"  environment variables, the first one set wins
+//	default:"value"         used when neither the file nor the env sets it
+//	required:"true"         reported by Validate when left empty
+//	secret:"true"           replaced by Print
+const redacted = "******"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walk calls fn for every leaf field in the struct pointed to by cfg.
+func walk(cfg any, fn func(field reflect.StructField, value reflect.Value) error) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be a pointer to a struct, got %T", cfg)
+	}
+	return walkStruct(v.Elem(), fn)
+}
+
+func walkStruct(v reflect.Value, fn func(field reflect.StructField, value reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct && value.Type() != durationType {
+			if err := walkStruct(value, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyDefaults(cfg any) error {
+	return walk(cfg, func(field reflect.StructField, value reflect.Value) error {
+		def, ok := field.Tag.Lookup("default")
+		if !ok || !value.IsZero() {
+			return nil
+		}
+		if err := setValue(value, def); err != nil {
+			return fmt.Errorf("invalid default for %s: %w", field.Name, err)
+		}
+		return nil
+	})
+}
+
+func applyEnv(cfg any) error {
+	var problems []string
+	err := walk(cfg, func(field reflect.StructField, value reflect.Value) error {
+		for _, name := range envNames(field) {
+			raw, ok := os.LookupEnv(name)
+			if !ok || raw == "" {
+				continue
+			}
+			if err := setValue(value, raw); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			}
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validate(cfg any) []string {
+	var problems []string
+	collect := func(v reflect.Value) {
+		if v.CanAddr() {
+			if validator, ok := v.Addr().Interface().(Validator); ok {
+				problems = append(problems, validator.Validate()...)
+			}
+		}
+	}
+
+	root := reflect.ValueOf(cfg)
+	if root.Kind() == reflect.Pointer {
+		collect(root.Elem())
+	}
+
+	var visit func(v reflect.Value)
+	visit = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			value := v.Field(i)
+			if value.Kind() == reflect.Struct && value.Type() != durationType {
+				collect(value)
+				visit(value)
+				continue
+			}
+			if field.Tag.Get("required") == "true" && value.IsZero() {
+				problems = append(problems, fmt.Sprintf("%s is required", displayName(field)))
+			}
+		}
+	}
+	if root.Kind() == reflect.Pointer && root.Elem().Kind() == reflect.Struct {
+		visit(root.Elem())
+	}
+	return problems
+}
+
+// redact returns a copy of cfg with secret fields masked.
+func redact(cfg any) any {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	cp := reflect.New(v.Type())
+	cp.Elem().Set(v)
+	_ = walkStruct(cp.Elem(), func(field reflect.StructField, value reflect.Value) error {
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			value.SetString(redacted)
+		}
+		return nil
+	})
+	return cp.Interface()
+}
+
+func envNames(field reflect.StructField) []string {
+	tag := field.Tag.Get("env")
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func displayName(field reflect.StructField) string {
+	if names := envNames(field); len(names) > 0 {
+		return names[0]
+	}
+	return field.Name
+}
+
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected a boolean, got %q", raw)
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", value.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validPort reports a problem when port is set but is not a usable TCP port.
+func validPort(name, port string) []string {
+	if port == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return []string{fmt.Sprintf("%s %q is not a valid port", name, port)}
+	}
+	return nil
+}
+
+type Postgres struct {
+	Host     string `yaml:"host" env:"POSTGRES_HOST" default:"localhost" required:"true"`
+	Port     string `yaml:"port" env:"POSTGRES_PORT" default:"5432" required:"true"`
+	User     string `yaml:"user" env:"POSTGRES_USER" required:"true"`
+	Password string `yaml:"password" env:"POSTGRES_PASSWORD" secret:"true"`
+	DB       string `yaml:"db" env:"POSTGRES_DB" required:"true"`
+
+	SSLMode     string `yaml:"ssl_mode" env:"POSTGRES_SSLMODE" default:"disable"`
+	SSLRootCert string `yaml:"ssl_root_cert" env:"POSTGRES_SSLROOTCERT"`
+	SSLCert     string `yaml:"ssl_cert" env:"POSTGRES_SSLCERT"`
+	SSLKey      string `yaml:"ssl_key" env:"POSTGRES_SSLKEY"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" default:"20"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" default:"5"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" default:"5m"`
+
+	ConnectAttempts int           `yaml:"connect_attempts" env:"POSTGRES_CONNECT_ATTEMPTS" default:"10"`
+	InitialBackoff  time.Duration `yaml:"initial_backoff" env:"POSTGRES_INITIAL_BACKOFF" default:"500ms"`
+	MaxBackoff      time.Duration `yaml:"max_backoff" env:"POSTGRES_MAX_BACKOFF" default:"15s"`
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+func (p *Postgres) Validate() []string {
+	problems := validPort("POSTGRES_PORT", p.Port)
+	if !slices.Contains(sslModes, p.SSLMode) {
+		problems = append(problems, fmt.Sprintf("POSTGRES_SSLMODE %q must be one of %s", p.SSLMode, strings.Join(sslModes, ", ")))
+	}
+	if (p.SSLMode == "verify-ca" || p.SSLMode == "verify-full") && p.SSLRootCert == "" {
+		problems = append(problems, fmt.Sprintf("POSTGRES_SSLROOTCERT is required with POSTGRES_SSLMODE=%s", p.SSLMode))
+	}
+	if (p.SSLCert == "") != (p.SSLKey == "") {
+		problems = append(problems, "POSTGRES_SSLCERT and POSTGRES_SSLKEY must be set together")
+	}
+	if p.MaxOpenConns > 0 && p.MaxIdleConns > p.MaxOpenConns {
+		problems = append(problems, "POSTGRES_MAX_IDLE_CONNS cannot exceed POSTGRES_MAX_OPEN_CONNS")
+	}
+	if p.ConnectAttempts < 1 {
+		problems = append(problems, "POSTGRES_CONNECT_ATTEMPTS must be at least 1")
+	}
+	return problems
+}
+
+// DSN builds the connection string understood by the pgx driver.
+func (p *Postgres) DSN() string {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		p.Host, p.Port, p.User, p.Password, p.DB, p.SSLMode,
+	)
+	if p.SSLRootCert != "" {
+		dsn += " sslrootcert=" + p.SSLRootCert
+	}
+	if p.SSLCert != "" {
+		dsn += fmt.Sprintf(" sslcert=%s sslkey=%s", p.SSLCert, p.SSLKey)
+	}
+	return dsn
+}
+
+type Mongo struct {
+	URI      string `yaml:"uri" env:"MONGODB_URI" default:"mongodb://localhost:27017" required:"true" secret:"true"`
+	Database string `yaml:"database" env:"MONGODB_DATABASE" default:"ledger_db" required:"true"`
+}
+
+type Kafka struct {
+	Brokers []string `yaml:"brokers" env:"KAFKA_BROKERS,KAFKA_BROKER" required:"true"`
+	// KAFKA_TOPIC_TRANSCATIONS is the historical misspelling, still accepted
+	// so existing .env files keep working.
+	TransactionsTopic string `yaml:"transactions_topic" env:"KAFKA_TOPIC_TRANSACTIONS,KAFKA_TOPIC_TRANSCATIONS" default:"transactions" required:"true"`
+	// StatusTopic carries settlement outcomes for the webhook dispatcher.
+	StatusTopic string `yaml:"status_topic" env:"KAFKA_TOPIC_TRANSACTION_STATUS" default:"transaction-status" required:"true"`
+	// The saga topics carry commands to the account and ledger participants
+	// and their replies to the coordinator.
+	SagaAccountTopic string `yaml:"saga_account_topic" env:"KAFKA_TOPIC_SAGA_ACCOUNT" default:"saga-account-commands" required:"true"`
+	SagaLedgerTopic  string `yaml:"saga_ledger_topic" env:"KAFKA_TOPIC_SAGA_LEDGER" default:"saga-ledger-commands" required:"true"`
+	SagaReplyTopic   string `yaml:"saga_reply_topic" env:"KAFKA_TOPIC_SAGA_REPLIES" default:"saga-replies" required:"true"`
+}
+
+type Health struct {
+	Timeout    time.Duration `yaml:"timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	MaxPollAge time.Duration `yaml:"max_poll_age" env:"HEALTH_MAX_POLL_AGE" default:"1m"`
+	// MaxLag fails readiness once total consumer lag exceeds it, zero disables the limit.
+	MaxLag int64 `yaml:"max_lag" env:"HEALTH_MAX_LAG"`
+}
+
+type Tracing struct {
+	// Exporter is one of none, stdout or otlp.
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER" default:"none"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT" default:"http://localhost:4318/v1/traces"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+func (t *Tracing) Validate() []string {
+	var problems []string
+	if !slices.Contains([]string{"none", "stdout", "otlp"}, t.Exporter) {
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER %q must be one of none, stdout, otlp", t.Exporter))
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		problems = append(problems, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	return problems
+}
+
+type Logging struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+	// Levels overrides the level per component, e.g. "kafka=warn,http=debug".
+	Levels string `yaml:"levels" env:"LOG_LEVELS"`
+	// Format is json or text.
+	Format string `yaml:"format" env:"LOG_FORMAT" default:"json"`
+}
+
+type Auth struct {
+	// Algorithm is HS256 with HMACSecret, or RS256 with keys from JWKSFile.
+	Algorithm  string `yaml:"algorithm" env:"JWT_ALGORITHM" default:"HS256"`
+	HMACSecret string `yaml:"hmac_secret" env:"JWT_HMAC_SECRET" secret:"true"`
+	JWKSFile   string `yaml:"jwks_file" env:"JWT_JWKS_FILE"`
+	Issuer     string `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience   string `yaml:"audience" env:"JWT_AUDIENCE"`
+	AdminRole  string `yaml:"admin_role" env:"JWT_ADMIN_ROLE" default:"admin"`
+}
+
+func (a *Auth) Validate() []string {
+	switch a.Algorithm {
+	case "HS256":
+		if len(a.HMACSecret) < 32 {
+			return []string{"JWT_HMAC_SECRET must be at least 32 bytes with JWT_ALGORITHM=HS256"}
+		}
+	case "RS256":
+		if a.JWKSFile == "" {
+			return []string{"JWT_JWKS_FILE is required with JWT_ALGORITHM=RS256"}
+		}
+	default:
+		return []string{fmt.Sprintf("JWT_ALGORITHM %q must be HS256 or RS256", a.Algorithm)}
+	}
+	return nil
+}
+
+// Rules points at the transfer rules file. It is watched and reloaded on
+// change; leaving it empty disables the rules.
+type Rules struct {
+	File string `yaml:"file" env:"TRANSFER_RULES_FILE"`
+}
+
+// Fees points at the fee schedules file, read at startup. Leaving it empty
+// charges no fees.
+type Fees struct {
+	File string `yaml:"file" env:"FEE_SCHEDULES_FILE"`
+}
+
+// Holds bounds how long authorization holds may reserve funds.
+type Holds struct {
+	DefaultTTL    time.Duration `yaml:"default_ttl" env:"HOLD_DEFAULT_TTL" default:"168h"`
+	MaxTTL        time.Duration `yaml:"max_ttl" env:"HOLD_MAX_TTL" default:"720h"`
+	SweepInterval time.Duration `yaml:"sweep_interval" env:"HOLD_SWEEP_INTERVAL" default:"1m"`
+}
+
+func (h *Holds) Validate() []string {
+	var problems []string
+	if h.DefaultTTL <= 0 || h.MaxTTL <= 0 || h.SweepInterval <= 0 {
+		problems = append(problems, "HOLD_DEFAULT_TTL, HOLD_MAX_TTL and HOLD_SWEEP_INTERVAL must be positive")
+	}
+	if h.DefaultTTL > h.MaxTTL {
+		problems = append(problems, "HOLD_DEFAULT_TTL must not exceed HOLD_MAX_TTL")
+	}
+	return problems
+}
+
+// Outbox controls the republishing of transactions the account service
+// settles itself. Those still unpublished RepublishAfter after they committed
+// are published again; the check runs as often.
+type Outbox struct {
+	RepublishAfter time.Duration `yaml:"republish_after" env:"OUTBOX_REPUBLISH_AFTER" default:"1m"`
+}
+
+func (o *Outbox) Validate() []string {
+	if o.RepublishAfter <= 0 {
+		return []string{"OUTBOX_REPUBLISH_AFTER must be positive"}
+	}
+	return nil
+}
+
+// Interest controls the worker that accrues and posts account interest.
+type Interest struct {
+	Enabled  bool          `yaml:"enabled" env:"INTEREST_ENABLED" default:"true"`
+	Interval time.Duration `yaml:"interval" env:"INTEREST_INTERVAL" default:"1h"`
+}
+
+func (i *Interest) Validate() []string {
+	if i.Interval <= 0 {
+		return []string{"INTEREST_INTERVAL must be positive"}
+	}
+	return nil
+}
+
+// EOD sets when the ledger closes each business date. Entries made at or
+// after Cutoff past midnight in Timezone belong to the next date; the
+// default of 24h closes at midnight. Once Delay has passed after the
+// cut-off, the ledger service closes the date unless Enabled is false.
+// The ledger service and consumer must agree on Cutoff and Timezone.
+type EOD struct {
+	Cutoff   time.Duration `yaml:"cutoff" env:"EOD_CUTOFF" default:"24h"`
+	Timezone string        `yaml:"timezone" env:"EOD_TIMEZONE" default:"UTC"`
+	Enabled  bool          `yaml:"enabled" env:"EOD_ENABLED" default:"true"`
+	Delay    time.Duration `yaml:"delay" env:"EOD_CLOSE_DELAY" default:"5m"`
+	Interval time.Duration `yaml:"interval" env:"EOD_INTERVAL" default:"1m"`
+}
+
+func (e *EOD) Validate() []string {
+	var problems []string
+	if e.Cutoff <= 0 || e.Cutoff > 24*time.Hour || e.Cutoff%time.Minute != 0 {
+		problems = append(problems, "EOD_CUTOFF must be whole minutes between 1m and 24h")
+	}
+	if _, err := time.LoadLocation(e.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("EOD_TIMEZONE %q is not a known timezone", e.Timezone))
+	}
+	if e.Delay < 0 || e.Interval <= 0 {
+		problems = append(problems, "EOD_CLOSE_DELAY must not be negative and EOD_INTERVAL must be positive")
+	}
+	return problems
+}
+
+// Scheduler controls the worker that runs scheduled transfers.
+type Scheduler struct {
+	Enabled   bool          `yaml:"enabled" env:"SCHEDULER_ENABLED" default:"true"`
+	Interval  time.Duration `yaml:"interval" env:"SCHEDULER_INTERVAL" default:"15s"`
+	BatchSize int           `yaml:"batch_size" env:"SCHEDULER_BATCH_SIZE" default:"100"`
+}
+
+func (s *Scheduler) Validate() []string {
+	if s.Interval <= 0 || s.BatchSize <= 0 {
+		return []string{"SCHEDULER_INTERVAL and SCHEDULER_BATCH_SIZE must be positive"}
+	}
+	return nil
+}
+
+// Batch bounds batch transaction submissions. Batches whose events are
+// still unpublished RepublishAfter after their rows committed are published
+// again; the check runs as often.
+type Batch struct {
+	MaxItems       int           `yaml:"max_items" env:"BATCH_MAX_ITEMS" default:"10000"`
+	MaxBodyBytes   int64         `yaml:"max_body_bytes" env:"BATCH_MAX_BODY_BYTES" default:"16777216"`
+	RepublishAfter time.Duration `yaml:"republish_after" env:"BATCH_REPUBLISH_AFTER" default:"1m"`
+}
+
+func (b *Batch) Validate() []string {
+	if b.MaxItems <= 0 || b.MaxBodyBytes <= 0 || b.RepublishAfter <= 0 {
+		return []string{"BATCH_MAX_ITEMS, BATCH_MAX_BODY_BYTES and BATCH_REPUBLISH_AFTER must be positive"}
+	}
+	return nil
+}
+
+// Stream bounds the live transaction stream.
+type Stream struct {
+	MaxClients int           `yaml:"max_clients" env:"STREAM_MAX_CLIENTS" default:"100"`
+	Heartbeat  time.Duration `yaml:"heartbeat" env:"STREAM_HEARTBEAT" default:"15s"`
+}
+
+func (s *Stream) Validate() []string {
+	if s.MaxClients <= 0 || s.Heartbeat <= 0 {
+		return []string{"STREAM_MAX_CLIENTS and STREAM_HEARTBEAT must be positive"}
+	}
+	return nil
+}
+
+// Webhooks controls delivery of webhook notifications.
+type Webhooks struct {
+	MaxAttempts    int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" default:"8"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"WEBHOOK_INITIAL_BACKOFF" default:"10s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF" default:"1h"`
+	Timeout        time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" default:"10s"`
+	PollInterval   time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" default:"1s"`
+	BatchSize      int           `yaml:"batch_size" env:"WEBHOOK_BATCH_SIZE" default:"50"`
+}
+
+func (w *Webhooks) Validate() []string {
+	if w.MaxAttempts <= 0 || w.BatchSize <= 0 {
+		return []string{"WEBHOOK_MAX_ATTEMPTS and WEBHOOK_BATCH_SIZE must be positive"}
+	}
+	if w.InitialBackoff <= 0 || w.MaxBackoff < w.InitialBackoff {
+		return []string{"WEBHOOK_INITIAL_BACKOFF must be positive and not above WEBHOOK_MAX_BACKOFF"}
+	}
+	if w.Timeout <= 0 || w.PollInterval <= 0 {
+		return []string{"WEBHOOK_TIMEOUT and WEBHOOK_POLL_INTERVAL must be positive"}
+	}
+	return nil
+}
+
+// WebhookTargets lists networks, in CIDR notation, that webhook endpoints may
+// reach besides public addresses, such as 127.0.0.0/8 for a receiver on the
+// same host. It is empty by default and should stay empty in production.
+type WebhookTargets struct {
+	AllowedNetworks []string `yaml:"allowed_networks" env:"WEBHOOK_ALLOWED_NETWORKS"`
+}
+
+func (w *WebhookTargets) Validate() []string {
+	var problems []string
+	for _, network := range w.AllowedNetworks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			problems = append(problems, fmt.Sprintf("WEBHOOK_ALLOWED_NETWORKS entry %q is not a CIDR prefix", network))
+		}
+	}
+	return problems
+}
+
+// AccountCheck controls the synchronous account check made before a
+// transaction is accepted. Token is a bearer token with the admin role, used
+// to read accounts the caller does not own.
+type AccountCheck struct {
+	Enabled         bool          `yaml:"enabled" env:"ACCOUNT_CHECK_ENABLED" default:"false"`
+	Addr            string        `yaml:"addr" env:"ACCOUNT_CHECK_ADDR" default:"account-service:9101"`
+	Token           string        `yaml:"token" env:"ACCOUNT_CHECK_TOKEN" secret:"true"`
+	Timeout         time.Duration `yaml:"timeout" env:"ACCOUNT_CHECK_TIMEOUT" default:"500ms"`
+	BreakerFailures int           `yaml:"breaker_failures" env:"ACCOUNT_CHECK_BREAKER_FAILURES" default:"5"`
+	BreakerCooldown time.Duration `yaml:"breaker_cooldown" env:"ACCOUNT_CHECK_BREAKER_COOLDOWN" default:"30s"`
+}
+
+func (a *AccountCheck) Validate() []string {
+	if !a.Enabled {
+		return nil
+	}
+	var problems []string
+	if a.Addr == "" || a.Token == "" {
+		problems = append(problems, "ACCOUNT_CHECK_ADDR and ACCOUNT_CHECK_TOKEN are required with ACCOUNT_CHECK_ENABLED=true")
+	}
+	if a.Timeout <= 0 || a.BreakerFailures <= 0 || a.BreakerCooldown <= 0 {
+		problems = append(problems, "ACCOUNT_CHECK_TIMEOUT, ACCOUNT_CHECK_BREAKER_FAILURES and ACCOUNT_CHECK_BREAKER_COOLDOWN must be positive")
+	}
+	return problems
+}
+
+// Saga controls the transfer saga coordinator.
+type Saga struct {
+	// StepTimeout is how long a step may wait for its reply before the
+	// command is sent again.
+	StepTimeout time.Duration `yaml:"step_timeout" env:"SAGA_STEP_TIMEOUT" default:"30s"`
+	// MaxAttempts bounds the sends of a forward step before the saga is
+	// compensated. Compensating steps are retried until they succeed.
+	MaxAttempts  int           `yaml:"max_attempts" env:"SAGA_MAX_ATTEMPTS" default:"3"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"SAGA_POLL_INTERVAL" default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env:"SAGA_BATCH_SIZE" default:"100"`
+}
+
+func (s *Saga) Validate() []string {
+	if s.StepTimeout <= 0 || s.PollInterval <= 0 {
+		return []string{"SAGA_STEP_TIMEOUT and SAGA_POLL_INTERVAL must be positive"}
+	}
+	if s.MaxAttempts <= 0 || s.BatchSize <= 0 {
+		return []string{"SAGA_MAX_ATTEMPTS and SAGA_BATCH_SIZE must be positive"}
+	}
+	return nil
+}
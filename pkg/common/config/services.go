@@ -0,0 +1,149 @@
+package config
+
+type AccountService struct {
+	Auth     Auth     `yaml:"auth"`
+	Logging  Logging  `yaml:"logging"`
+	Port     string   `yaml:"port" env:"ACCOUNT_SERVICE_PORT" default:"9001" required:"true"`
+	GRPCPort string   `yaml:"grpc_port" env:"ACCOUNT_GRPC_PORT" default:"9101" required:"true"`
+	Postgres Postgres `yaml:"postgres"`
+	Kafka    Kafka    `yaml:"kafka"`
+	Health   Health   `yaml:"health"`
+	Tracing  Tracing  `yaml:"tracing"`
+	Holds    Holds    `yaml:"holds"`
+	Rules    Rules    `yaml:"rules"`
+	Fees     Fees     `yaml:"fees"`
+	Interest Interest `yaml:"interest"`
+	Outbox   Outbox   `yaml:"outbox"`
+}
+
+func (c *AccountService) Validate() []string {
+	return append(validPort("ACCOUNT_SERVICE_PORT", c.Port), validPort("ACCOUNT_GRPC_PORT", c.GRPCPort)...)
+}
+
+type TransactionService struct {
+	Auth         Auth         `yaml:"auth"`
+	Logging      Logging      `yaml:"logging"`
+	Port         string       `yaml:"port" env:"TRANSACTION_SERVICE_PORT" default:"9002" required:"true"`
+	GRPCPort     string       `yaml:"grpc_port" env:"TRANSACTION_GRPC_PORT" default:"9102" required:"true"`
+	Postgres     Postgres     `yaml:"postgres"`
+	Kafka        Kafka        `yaml:"kafka"`
+	Health       Health       `yaml:"health"`
+	Tracing      Tracing      `yaml:"tracing"`
+	Scheduler    Scheduler    `yaml:"scheduler"`
+	Batch        Batch        `yaml:"batch"`
+	Stream       Stream       `yaml:"stream"`
+	AccountCheck AccountCheck `yaml:"account_check"`
+	Fees         Fees         `yaml:"fees"`
+	// WebhookTargets is checked when webhooks are registered.
+	WebhookTargets WebhookTargets `yaml:"webhook_targets"`
+}
+
+func (c *TransactionService) Validate() []string {
+	return append(validPort("TRANSACTION_SERVICE_PORT", c.Port), validPort("TRANSACTION_GRPC_PORT", c.GRPCPort)...)
+}
+
+type TransactionConsumer struct {
+	// Auth guards the admin endpoints on the health port.
+	Auth       Auth     `yaml:"auth"`
+	Logging    Logging  `yaml:"logging"`
+	HealthPort string   `yaml:"health_port" env:"TRANSACTION_CONSUMER_HEALTH_PORT" default:"9012" required:"true"`
+	Postgres   Postgres `yaml:"postgres"`
+	Kafka      Kafka    `yaml:"kafka"`
+	Health     Health   `yaml:"health"`
+	Tracing    Tracing  `yaml:"tracing"`
+	Rules      Rules    `yaml:"rules"`
+}
+
+func (c *TransactionConsumer) Validate() []string {
+	return validPort("TRANSACTION_CONSUMER_HEALTH_PORT", c.HealthPort)
+}
+
+type WebhookDispatcher struct {
+	// Auth guards the admin endpoints on the health port.
+	Auth       Auth     `yaml:"auth"`
+	Logging    Logging  `yaml:"logging"`
+	HealthPort string   `yaml:"health_port" env:"WEBHOOK_DISPATCHER_HEALTH_PORT" default:"9014" required:"true"`
+	Postgres   Postgres `yaml:"postgres"`
+	Kafka      Kafka    `yaml:"kafka"`
+	Health     Health   `yaml:"health"`
+	Tracing    Tracing  `yaml:"tracing"`
+	Webhooks   Webhooks `yaml:"webhooks"`
+	// WebhookTargets is checked again on every connection.
+	WebhookTargets WebhookTargets `yaml:"webhook_targets"`
+}
+
+func (c *WebhookDispatcher) Validate() []string {
+	return validPort("WEBHOOK_DISPATCHER_HEALTH_PORT", c.HealthPort)
+}
+
+type SagaCoordinator struct {
+	// Auth guards the admin endpoints on the health port.
+	Auth       Auth     `yaml:"auth"`
+	Logging    Logging  `yaml:"logging"`
+	HealthPort string   `yaml:"health_port" env:"SAGA_COORDINATOR_HEALTH_PORT" default:"9015" required:"true"`
+	Postgres   Postgres `yaml:"postgres"`
+	Kafka      Kafka    `yaml:"kafka"`
+	Health     Health   `yaml:"health"`
+	Tracing    Tracing  `yaml:"tracing"`
+	Saga       Saga     `yaml:"saga"`
+}
+
+func (c *SagaCoordinator) Validate() []string {
+	return validPort("SAGA_COORDINATOR_HEALTH_PORT", c.HealthPort)
+}
+
+type LedgerService struct {
+	Auth Auth `yaml:"auth"`
+	// Postgres is read to resolve account ownership for authorization.
+	Postgres Postgres `yaml:"postgres"`
+	Logging  Logging  `yaml:"logging"`
+	Port     string   `yaml:"port" env:"LEDGER_SERVICE_PORT" default:"9003" required:"true"`
+	Mongo    Mongo    `yaml:"mongo"`
+	Health   Health   `yaml:"health"`
+	Tracing  Tracing  `yaml:"tracing"`
+	EOD      EOD      `yaml:"eod"`
+}
+
+func (c *LedgerService) Validate() []string {
+	return validPort("LEDGER_SERVICE_PORT", c.Port)
+}
+
+type LedgerConsumer struct {
+	// Auth guards the admin endpoints on the health port.
+	Auth       Auth    `yaml:"auth"`
+	Logging    Logging `yaml:"logging"`
+	HealthPort string  `yaml:"health_port" env:"LEDGER_CONSUMER_HEALTH_PORT" default:"9013" required:"true"`
+	Mongo      Mongo   `yaml:"mongo"`
+	Kafka      Kafka   `yaml:"kafka"`
+	Health     Health  `yaml:"health"`
+	Tracing    Tracing `yaml:"tracing"`
+	EOD        EOD     `yaml:"eod"`
+}
+
+func (c *LedgerConsumer) Validate() []string {
+	return validPort("LEDGER_CONSUMER_HEALTH_PORT", c.HealthPort)
+}
+
+type Migrate struct {
+	Logging  Logging  `yaml:"logging"`
+	Postgres Postgres `yaml:"postgres"`
+}
+
+type AccountRebuild struct {
+	Logging  Logging  `yaml:"logging"`
+	Postgres Postgres `yaml:"postgres"`
+}
+
+type LedgerVerify struct {
+	Logging Logging `yaml:"logging"`
+	Mongo   Mongo   `yaml:"mongo"`
+}
+
+// Export reads transactions from Postgres and ledger entries from MongoDB;
+// EOD must match the ledger's so business dates line up.
+type Export struct {
+	Logging  Logging  `yaml:"logging"`
+	Postgres Postgres `yaml:"postgres"`
+	Mongo    Mongo    `yaml:"mongo"`
+	EOD      EOD      `yaml:"eod"`
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"txsystem/pkg/common/config"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/tracing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var logger = logging.For("database")
+
+// Open connects to Postgres, retrying with exponential backoff until the
+// database answers a ping or the configured attempts run out, and applies the
+// pool settings.
+func Open(ctx context.Context, cfg config.Postgres) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+
+	backoff := cfg.InitialBackoff
+	for attempt := 1; attempt <= cfg.ConnectAttempts; attempt++ {
+		db, err = connect(ctx, cfg)
+		if err == nil {
+			logger.Info("connected to database", "db", cfg.DB, "host", cfg.Host, "port", cfg.Port)
+			return db, nil
+		}
+
+		logger.Error("failed to connect to database", "attempt", attempt, "max_attempts", cfg.ConnectAttempts, "error", err)
+		if attempt == cfg.ConnectAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+
+	return nil, fmt.Errorf("could not connect to database after %d attempts: %w", cfg.ConnectAttempts, err)
+}
+
+func connect(ctx context.Context, cfg config.Postgres) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := sqlDB.PingContext(pingCtx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close releases the underlying connection pool.
+func Close(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		logger.Error("error closing database", "error", err)
+	}
+}
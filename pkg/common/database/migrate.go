@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockID is the advisory lock key held while migrations run so two
+// migrate commands started together cannot interleave.
+const migrationLockID = 72170413
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change with its reverse.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies versioned SQL migrations and records them in the
+// schema_migrations table.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator reads NNNN_name.up.sql / NNNN_name.down.sql pairs from fsys.
+func NewMigrator(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			logger.Info("applying migration", "version", mig.Version, "name", mig.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&appliedMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Down rolls back the most recently applied steps migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+			}
+			logger.Info("reverting migration", "version", mig.Version, "name", mig.Name)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&appliedMigration{}, mig.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&appliedMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]time.Time, error) {
+	var rows []appliedMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	applied := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// locked runs fn on a single connection holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID).Error; err != nil {
+				logger.Error("failed to release migration lock", "error", err)
+			}
+		}()
+
+		if err := conn.AutoMigrate(&appliedMigration{}); err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		return fn(conn)
+	})
+}
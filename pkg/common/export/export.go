@@ -0,0 +1,147 @@
+// Package export writes rows of records as CSV, NDJSON or Parquet one row at
+// a time, so exports never hold the whole data set in memory.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+var ErrUnknownFormat = errors.New("format must be csv, ndjson or parquet")
+
+// ParseFormat returns the named format, CSV when name is empty.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case "":
+		return CSV, nil
+	case CSV, NDJSON, Parquet:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case NDJSON:
+		return "application/x-ndjson"
+	case Parquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+// Trailers of an export streamed over HTTP. The status is sent before the
+// first row, so it cannot report a failure: a complete export ends with
+// TrailerRows and a failed one with TrailerError instead.
+const (
+	TrailerRows  = "X-Export-Rows"
+	TrailerError = "X-Export-Error"
+)
+
+// DeclareTrailers announces the export trailers. It must be called before
+// the status is written.
+func DeclareTrailers(h http.Header) {
+	h.Set("Trailer", TrailerRows+", "+TrailerError)
+}
+
+// SetTrailer reports how the export ended, once every row is written.
+func SetTrailer(h http.Header, rows int, err error) {
+	if err != nil {
+		h.Set(TrailerError, "export failed after "+strconv.Itoa(rows)+" rows")
+		return
+	}
+	h.Set(TrailerRows, strconv.Itoa(rows))
+}
+
+// Kind is the type of a column's values.
+type Kind int
+
+const (
+	String  Kind = iota // string
+	Int64               // int64
+	Float64             // float64
+	Time                // time.Time, written in UTC at millisecond precision
+)
+
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Writer writes rows holding one value per column, of the column's kind.
+// Close finishes the output; a Parquet file is unreadable without it.
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+// NewWriter returns a writer of the format to w. The CSV header and the
+// Parquet schema are taken from columns.
+func NewWriter(format Format, w io.Writer, columns []Column) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case NDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case Parquet:
+		return newParquetWriter(w, columns)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Filter selects the records to export. From and To are dates, both
+// included; zero leaves that end open. An empty AccountID matches every
+// account.
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	AccountID string
+}
+
+// ParseFilter reads dates given as YYYY-MM-DD; empty values are left open.
+func ParseFilter(from, to, accountID string) (Filter, error) {
+	f := Filter{AccountID: accountID}
+	var err error
+	if from != "" {
+		if f.From, err = time.Parse(time.DateOnly, from); err != nil {
+			return Filter{}, fmt.Errorf("invalid from date %q, please use YYYY-MM-DD", from)
+		}
+	}
+	if to != "" {
+		if f.To, err = time.Parse(time.DateOnly, to); err != nil {
+			return Filter{}, fmt.Errorf("invalid to date %q, please use YYYY-MM-DD", to)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return Filter{}, errors.New("to date is before from date")
+	}
+	return f, nil
+}
+
+// FileName names an export of the data set, such as
+// transactions-2024-01-01-2024-01-31.csv.
+func (f Filter) FileName(dataset string, format Format) string {
+	name := dataset
+	if f.AccountID != "" {
+		name += "-" + f.AccountID
+	}
+	if !f.From.IsZero() {
+		name += "-" + f.From.Format(time.DateOnly)
+	}
+	if !f.To.IsZero() {
+		name += "-" + f.To.Format(time.DateOnly)
+	}
+	return name + "." + string(format)
+}
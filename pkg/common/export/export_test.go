@@ -0,0 +1,193 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var columns = []Column{
+	{Name: "id", Kind: Int64},
+	{Name: "description", Kind: String},
+	{Name: "amount", Kind: Float64},
+	{Name: "created_at", Kind: Time},
+}
+
+var rows = [][]any{
+	{int64(1), "rent, march", 1250.5, time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+	{int64(2), `say "hi"` + "\nnext line", 0.1, time.Date(2024, 3, 1, 12, 0, 0, 250_000_000, time.FixedZone("CET", 3600))},
+	{int64(-3), "", 1e21, time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC)},
+}
+
+func write(t *testing.T, format Format) string {
+	t.Helper()
+	var buf strings.Builder
+	w, err := NewWriter(format, &buf, columns)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.String()
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(write(t, CSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	want := [][]string{
+		{"id", "description", "amount", "created_at"},
+		{"1", "rent, march", "1250.5", "2024-03-01T09:30:00Z"},
+		{"2", `say "hi"` + "\nnext line", "0.1", "2024-03-01T11:00:00.25Z"},
+		{"-3", "", "1000000000000000000000", "2024-02-29T23:59:59Z"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("record %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	out := write(t, NDJSON)
+	want := []string{
+		`{"id":1,"description":"rent, march","amount":1250.5,"created_at":"2024-03-01T09:30:00Z"}`,
+		`{"id":2,"description":"say \"hi\"\nnext line","amount":0.1,"created_at":"2024-03-01T11:00:00.25Z"}`,
+		`{"id":-3,"description":"","amount":1e+21,"created_at":"2024-02-29T23:59:59Z"}`,
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	i := 0
+	for ; scanner.Scan(); i++ {
+		line := scanner.Text()
+		if i < len(want) && line != want[i] {
+			t.Errorf("line %d = %s, want %s", i, line, want[i])
+		}
+		if !json.Valid([]byte(line)) {
+			t.Errorf("line %d is not valid JSON: %s", i, line)
+		}
+	}
+	if i != len(want) {
+		t.Errorf("got %d lines, want %d", i, len(want))
+	}
+}
+
+func TestWritersRejectUnsupportedValues(t *testing.T) {
+	for _, format := range []Format{CSV, NDJSON, Parquet} {
+		w, err := NewWriter(format, io.Discard, columns[:1])
+		if err != nil {
+			t.Fatalf("%s: NewWriter: %v", format, err)
+		}
+		if format == NDJSON {
+			// Any JSON-encodable value is accepted.
+			continue
+		}
+		if err := w.Write([]any{1}); err == nil {
+			t.Errorf("%s: int accepted", format)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{in: "", want: CSV},
+		{in: "csv", want: CSV},
+		{in: "ndjson", want: NDJSON},
+		{in: "parquet", want: Parquet},
+		{in: "CSV", wantErr: true},
+		{in: "json", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		account  string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "open", wantName: "transactions.csv"},
+		{name: "range", from: "2024-01-01", to: "2024-01-31", wantName: "transactions-2024-01-01-2024-01-31.csv"},
+		{name: "same day", from: "2024-01-01", to: "2024-01-01", wantName: "transactions-2024-01-01-2024-01-01.csv"},
+		{name: "account", account: "42", from: "2024-01-01", wantName: "transactions-42-2024-01-01.csv"},
+		{name: "reversed", from: "2024-02-01", to: "2024-01-31", wantErr: true},
+		{name: "bad from", from: "01/02/2024", wantErr: true},
+		{name: "bad to", to: "2024-02-30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFilter(tt.from, tt.to, tt.account)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && f.FileName("transactions", CSV) != tt.wantName {
+				t.Errorf("FileName = %q, want %q", f.FileName("transactions", CSV), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantRows  string
+		wantError string
+	}{
+		{name: "complete", wantRows: "3"},
+		{name: "failed", err: errors.New("cursor closed"), wantError: "export failed after 3 rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				DeclareTrailers(w.Header())
+				w.WriteHeader(http.StatusOK)
+				io.WriteString(w, "a\nb\nc\n")
+				SetTrailer(w.Header(), 3, tt.err)
+			}))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer resp.Body.Close()
+			if _, err := io.ReadAll(resp.Body); err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if got := resp.Trailer.Get(TrailerRows); got != tt.wantRows {
+				t.Errorf("%s = %q, want %q", TrailerRows, got, tt.wantRows)
+			}
+			if got := resp.Trailer.Get(TrailerError); got != tt.wantError {
+				t.Errorf("%s = %q, want %q", TrailerError, got, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package export
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// parquetRowGroupSize bounds the bytes of values a Parquet export buffers
+// before writing them out as a row group.
+const parquetRowGroupSize = 16 << 20
+
+// Parquet enum values, from the parquet-format Thrift definitions.
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired        = 0
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// parquetWriter writes a flat file of required columns. Each row group
+// holds one plain-encoded, uncompressed data page per column, which every
+// Parquet reader understands and keeps the writer free of dependencies.
+type parquetWriter struct {
+	w       io.Writer
+	columns []Column
+	// rowGroupSize is parquetRowGroupSize outside tests.
+	rowGroupSize int
+	offset       int64
+	// values holds the encoded values of each column of the current row
+	// group.
+	values    [][]byte
+	buffered  int
+	rows      int64
+	numRows   int64
+	rowGroups []parquetRowGroup
+}
+
+type parquetRowGroup struct {
+	rows   int64
+	size   int64
+	chunks []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	p := &parquetWriter{w: w, columns: columns, rowGroupSize: parquetRowGroupSize, values: make([][]byte, len(columns))}
+	if err := p.write(parquetMagic); err != nil {
+		return nil, fmt.Errorf("failed to start parquet file: %w", err)
+	}
+	return p, nil
+}
+
+func (p *parquetWriter) Write(row []any) error {
+	if len(row) != len(p.columns) {
+		return fmt.Errorf("row has %d values for %d columns", len(row), len(p.columns))
+	}
+	for i, v := range row {
+		before := len(p.values[i])
+		switch v := v.(type) {
+		case string:
+			p.values[i] = binary.LittleEndian.AppendUint32(p.values[i], uint32(len(v)))
+			p.values[i] = append(p.values[i], v...)
+		case int64:
+			p.values[i] = binary.LittleEndian.AppendUint64(p.values[i], uint64(v))
+		case float64:
+			p.values[i] = binary.LittleEndian.AppendUint64(p.values[i], math.Float64bits(v))
+		case time.Time:
+			p.values[i] = binary.LittleEndian.AppendUint64(p.values[i], uint64(v.UnixMilli()))
+		default:
+			return fmt.Errorf("unsupported value %T", v)
+		}
+		p.buffered += len(p.values[i]) - before
+	}
+	p.rows++
+	if p.buffered >= p.rowGroupSize {
+		return p.flush()
+	}
+	return nil
+}
+
+// Close writes the last row group and the footer.
+func (p *parquetWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	footer := p.footer()
+	if err := p.write(footer); err != nil {
+		return fmt.Errorf("failed to write parquet footer: %w", err)
+	}
+	if err := p.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return fmt.Errorf("failed to write parquet footer: %w", err)
+	}
+	if err := p.write(parquetMagic); err != nil {
+		return fmt.Errorf("failed to write parquet footer: %w", err)
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (p *parquetWriter) flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: p.rows}
+	for i := range p.columns {
+		var h compactWriter
+		h.begin()
+		h.i32(1, parquetDataPage)
+		h.i32(2, int32(len(p.values[i])))
+		h.i32(3, int32(len(p.values[i])))
+		h.beginStruct(5)
+		h.i32(1, int32(p.rows))
+		h.i32(2, parquetPlain)
+		h.i32(3, parquetRLE)
+		h.i32(4, parquetRLE)
+		h.endStruct()
+		h.end()
+
+		chunk := parquetChunk{offset: p.offset, size: int64(len(h.buf) + len(p.values[i]))}
+		if err := p.write(h.buf); err != nil {
+			return fmt.Errorf("failed to write parquet page: %w", err)
+		}
+		if err := p.write(p.values[i]); err != nil {
+			return fmt.Errorf("failed to write parquet page: %w", err)
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+		p.values[i] = p.values[i][:0]
+	}
+	p.rowGroups = append(p.rowGroups, group)
+	p.numRows += p.rows
+	p.rows, p.buffered = 0, 0
+	return nil
+}
+
+// footer encodes the FileMetaData of the file.
+func (p *parquetWriter) footer() []byte {
+	var f compactWriter
+	f.begin()
+	f.i32(1, 1)
+
+	f.list(2, compactStruct, len(p.columns)+1)
+	f.beginElem()
+	f.str(4, "schema")
+	f.i32(5, int32(len(p.columns)))
+	f.endStruct()
+	for _, c := range p.columns {
+		f.beginElem()
+		f.i32(1, parquetType(c.Kind))
+		f.i32(3, parquetRequired)
+		f.str(4, c.Name)
+		switch c.Kind {
+		case String:
+			f.i32(6, parquetUTF8)
+		case Time:
+			f.i32(6, parquetTimestampMillis)
+		}
+		f.endStruct()
+	}
+
+	f.i64(3, p.numRows)
+
+	f.list(4, compactStruct, len(p.rowGroups))
+	for _, g := range p.rowGroups {
+		f.beginElem()
+		f.list(1, compactStruct, len(g.chunks))
+		for i, chunk := range g.chunks {
+			f.beginElem()
+			f.i64(2, chunk.offset)
+			f.beginStruct(3)
+			f.i32(1, parquetType(p.columns[i].Kind))
+			f.list(2, compactI32, 2)
+			f.elemI32(parquetPlain)
+			f.elemI32(parquetRLE)
+			f.list(3, compactBinary, 1)
+			f.elemStr(p.columns[i].Name)
+			f.i32(4, parquetUncompressed)
+			f.i64(5, g.rows)
+			f.i64(6, chunk.size)
+			f.i64(7, chunk.size)
+			f.i64(9, chunk.offset)
+			f.endStruct()
+			f.endStruct()
+		}
+		f.i64(2, g.size)
+		f.i64(3, g.rows)
+		f.endStruct()
+	}
+
+	f.str(6, "txsystem export")
+	f.end()
+	return f.buf
+}
+
+func (p *parquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func parquetType(k Kind) int32 {
+	switch k {
+	case String:
+		return parquetByteArray
+	case Float64:
+		return parquetDouble
+	}
+	return parquetInt64
+}
+
+// Thrift compact protocol types.
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol, which
+// Parquet uses for its page headers and footer.
+type compactWriter struct {
+	buf []byte
+	// last holds the last field ID of each open struct.
+	last []int16
+}
+
+func (c *compactWriter) begin() { c.last = append(c.last, 0) }
+
+func (c *compactWriter) end() { c.endStruct() }
+
+func (c *compactWriter) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.buf = binary.AppendVarint(c.buf, int64(id))
+	}
+	*last = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.buf = binary.AppendVarint(c.buf, int64(v))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.buf = binary.AppendVarint(c.buf, v)
+}
+
+func (c *compactWriter) str(id int16, s string) {
+	c.field(id, compactBinary)
+	c.elemStr(s)
+}
+
+func (c *compactWriter) beginStruct(id int16) {
+	c.field(id, compactStruct)
+	c.last = append(c.last, 0)
+}
+
+// endStruct closes the innermost struct or list element.
+func (c *compactWriter) endStruct() {
+	c.buf = append(c.buf, 0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compactWriter) list(id int16, elem byte, n int) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|elem)
+		return
+	}
+	c.buf = append(c.buf, 0xf0|elem)
+	c.buf = binary.AppendUvarint(c.buf, uint64(n))
+}
+
+// beginElem starts a struct element of a list.
+func (c *compactWriter) beginElem() { c.last = append(c.last, 0) }
+
+func (c *compactWriter) elemI32(v int32) { c.buf = binary.AppendVarint(c.buf, int64(v)) }
+
+func (c *compactWriter) elemStr(s string) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetRow struct {
+	ID     string    `parquet:"id"`
+	Amount float64   `parquet:"amount"`
+	Seq    int64     `parquet:"seq"`
+	At     time.Time `parquet:"at,timestamp(millisecond)"`
+}
+
+var parquetColumns = []Column{
+	{Name: "id", Kind: String},
+	{Name: "amount", Kind: Float64},
+	{Name: "seq", Kind: Int64},
+	{Name: "at", Kind: Time},
+}
+
+func writeParquet(t *testing.T, rowGroupSize int, rows []parquetRow) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newParquetWriter(&buf, parquetColumns)
+	if err != nil {
+		t.Fatalf("newParquetWriter: %v", err)
+	}
+	w.rowGroupSize = rowGroupSize
+	for _, r := range rows {
+		if err := w.Write([]any{r.ID, r.Amount, r.Seq, r.At}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	base := time.Date(2024, 2, 29, 23, 59, 59, 123_000_000, time.UTC)
+	tests := []struct {
+		name         string
+		rows         int
+		rowGroupSize int
+		wantGroups   int
+	}{
+		{name: "empty", rows: 0, rowGroupSize: parquetRowGroupSize, wantGroups: 0},
+		{name: "single row group", rows: 10, rowGroupSize: parquetRowGroupSize, wantGroups: 1},
+		// A row encodes to 36 bytes: 4 for the length of the 8 byte ID and
+		// 8 for each other value. 100 bytes close a group every 3 rows.
+		{name: "many row groups", rows: 250, rowGroupSize: 100, wantGroups: 84},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]parquetRow, tt.rows)
+			for i := range want {
+				want[i] = parquetRow{
+					ID:     fmt.Sprintf("tx-%05d", i),
+					Amount: float64(i) * 1.25,
+					Seq:    int64(i) - 100,
+					At:     base.Add(time.Duration(i) * time.Hour),
+				}
+			}
+			data := writeParquet(t, tt.rowGroupSize, want)
+
+			f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("OpenFile: %v", err)
+			}
+			if got := len(f.RowGroups()); got != tt.wantGroups {
+				t.Errorf("row groups = %d, want %d", got, tt.wantGroups)
+			}
+			if got := f.NumRows(); got != int64(tt.rows) {
+				t.Errorf("NumRows = %d, want %d", got, tt.rows)
+			}
+
+			r := parquet.NewGenericReader[parquetRow](f)
+			defer r.Close()
+			got := make([]parquetRow, tt.rows+1)
+			n, err := r.Read(got)
+			if err != nil && err != io.EOF {
+				t.Fatalf("Read: %v", err)
+			}
+			if n != tt.rows {
+				t.Fatalf("read %d rows, want %d", n, tt.rows)
+			}
+			for i := range want {
+				if got[i].ID != want[i].ID || got[i].Amount != want[i].Amount ||
+					got[i].Seq != want[i].Seq || !got[i].At.Equal(want[i].At) {
+					t.Fatalf("row %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParquetSchema(t *testing.T) {
+	data := writeParquet(t, parquetRowGroupSize, nil)
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+	fields := f.Schema().Fields()
+	if len(fields) != len(parquetColumns) {
+		t.Fatalf("schema has %d fields, want %d", len(fields), len(parquetColumns))
+	}
+	for i, c := range parquetColumns {
+		if fields[i].Name() != c.Name {
+			t.Errorf("field %d = %q, want %q", i, fields[i].Name(), c.Name)
+		}
+		if !fields[i].Required() {
+			t.Errorf("field %q is not required", c.Name)
+		}
+	}
+}
+
+func TestParquetRejectsBadRows(t *testing.T) {
+	w, err := newParquetWriter(io.Discard, parquetColumns)
+	if err != nil {
+		t.Fatalf("newParquetWriter: %v", err)
+	}
+	if err := w.Write([]any{"a", 1.0}); err == nil {
+		t.Error("short row accepted")
+	}
+	if err := w.Write([]any{"a", 1.0, 2, time.Now()}); err == nil {
+		t.Error("int value accepted for an int64 column")
+	}
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (c *csvWriter) Write(row []any) error {
+	record := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case string:
+			record[i] = v
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339Nano)
+		default:
+			return fmt.Errorf("unsupported value %T", v)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+	buf  []byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []Column) *ndjsonWriter {
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		// Column names are plain identifiers, so they need no escaping.
+		keys[i] = []byte(strconv.Quote(c.Name) + ":")
+	}
+	return &ndjsonWriter{w: bufio.NewWriter(w), keys: keys}
+}
+
+// Write writes the row as one JSON object, with keys in column order.
+func (n *ndjsonWriter) Write(row []any) error {
+	n.buf = append(n.buf[:0], '{')
+	for i, v := range row {
+		if i > 0 {
+			n.buf = append(n.buf, ',')
+		}
+		n.buf = append(n.buf, n.keys[i]...)
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", n.keys[i], err)
+		}
+		n.buf = append(n.buf, value...)
+	}
+	n.buf = append(n.buf, '}', '\n')
+	_, err := n.w.Write(n.buf)
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
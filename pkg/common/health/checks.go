@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"txsystem/pkg/common/types"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"gorm.io/gorm"
+)
+
+// Postgres pings the database and reports pool usage.
+func Postgres(db *gorm.DB) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		stats := sqlDB.Stats()
+		details := map[string]int{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		}
+		return details, sqlDB.PingContext(ctx)
+	}
+}
+
+// Mongo pings the primary.
+func Mongo(client *mongo.Client) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		return nil, client.Ping(ctx, readpref.Primary())
+	}
+}
+
+// Kafka checks broker connectivity through the connection's IsConnected.
+func Kafka(conn types.Connection) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		if !conn.IsConnected() {
+			return nil, fmt.Errorf("kafka brokers unreachable")
+		}
+		return nil, nil
+	}
+}
+
+// Consumer fails when the poll loop has stalled for longer than maxPollAge or
+// when total lag exceeds maxLag. A maxLag of zero only reports lag.
+func Consumer(conn types.ConsumerConnection, maxPollAge time.Duration, maxLag int64) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		stats := conn.Stats()
+
+		var total int64
+		for _, lag := range stats.Lag {
+			total += lag
+		}
+		details := map[string]any{
+			"last_poll":     stats.LastPoll,
+			"lag":           stats.Lag,
+			"total_lag":     total,
+			"since_poll_ms": time.Since(stats.LastPoll).Milliseconds(),
+		}
+
+		if stats.LastPoll.IsZero() {
+			return details, fmt.Errorf("consumer has not polled yet")
+		}
+		if age := time.Since(stats.LastPoll); age > maxPollAge {
+			return details, fmt.Errorf("last poll was %s ago", age.Round(time.Second))
+		}
+		if maxLag > 0 && total > maxLag {
+			return details, fmt.Errorf("consumer lag %d exceeds %d", total, maxLag)
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,173 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+	"txsystem/pkg/common/logging"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc probes one dependency. Details, when non-nil, are reported
+// alongside the status whether the check passes or not.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+// Result is the outcome of a single dependency check.
+type Result struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// Report is the body returned by the readiness endpoint.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the readiness checks registered for a service.
+type Checker struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  map[string]CheckFunc
+	extra   map[string]http.Handler
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  map[string]CheckFunc{},
+		extra:   map[string]http.Handler{},
+	}
+}
+
+// Add registers a named readiness check.
+func (c *Checker) Add(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Handle adds a route to the standalone listener started by Serve, so
+// consumers can expose e.g. /metrics next to their probes.
+func (c *Checker) Handle(pattern string, handler http.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.extra[pattern] = handler
+}
+
+// Run executes every check concurrently, each bounded by the checker timeout.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make(map[string]CheckFunc, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check CheckFunc) {
+			defer wg.Done()
+			result := c.runOne(ctx, check)
+			mu.Lock()
+			report.Checks[name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) runOne(ctx context.Context, check CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check(ctx)
+	result := Result{
+		Status:    StatusUp,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler reports that the process is up. It never touches
+// dependencies so a slow database cannot get the process restarted.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+	})
+}
+
+// ReadinessHandler runs every check and answers 503 when any is down.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		code := http.StatusOK
+		if report.Status != StatusUp {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+// Register mounts /healthz and /readyz on an Echo server.
+func (c *Checker) Register(e *echo.Echo) {
+	e.GET("/healthz", echo.WrapHandler(c.LivenessHandler()))
+	e.GET("/readyz", echo.WrapHandler(c.ReadinessHandler()))
+}
+
+// Serve runs a standalone health listener for processes without an HTTP API,
+// such as the Kafka consumers. It stops when ctx is cancelled.
+func (c *Checker) Serve(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", c.LivenessHandler())
+	mux.Handle("/readyz", c.ReadinessHandler())
+	c.mu.RLock()
+	for pattern, handler := range c.extra {
+		mux.Handle(pattern, handler)
+	}
+	c.mu.RUnlock()
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		logging.For("health").Info("health listener started", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logging.For("health").Error("health listener failed", "error", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,55 @@
+// Package inbox remembers, in Postgres, which messages a consumer has
+// handled and the answer it gave.
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type message struct {
+	Consumer    string `gorm:"primaryKey"`
+	MessageKey  string `gorm:"primaryKey"`
+	Result      string
+	ProcessedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (message) TableName() string {
+	return "inbox_messages"
+}
+
+// Inbox holds the handled messages of one consumer. Message keys must be
+// derived from the message content, so a redelivery has the same key.
+type Inbox struct {
+	db       *gorm.DB
+	consumer string
+}
+
+func New(db *gorm.DB, consumer string) *Inbox {
+	return &Inbox{db: db, consumer: consumer}
+}
+
+// Lookup returns the result stored for the message and whether there was
+// one.
+func (i *Inbox) Lookup(ctx context.Context, key string) (string, bool, error) {
+	var m message
+	found := i.db.WithContext(ctx).Where("consumer = ? AND message_key = ?", i.consumer, key).Limit(1).Find(&m)
+	if found.Error != nil {
+		return "", false, fmt.Errorf("failed to look up inbox message: %w", found.Error)
+	}
+	return m.Result, found.RowsAffected == 1, nil
+}
+
+// Store records the result of the message. Storing it again keeps the first
+// result.
+func (i *Inbox) Store(ctx context.Context, key, result string) error {
+	m := message{Consumer: i.consumer, MessageKey: key, Result: result}
+	if err := i.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&m).Error; err != nil {
+		return fmt.Errorf("failed to store inbox message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package logging
+
+import "context"
+
+type requestIDKey struct{}
+
+// RequestIDHeader carries the request ID over HTTP and in Kafka record headers.
+const RequestIDHeader = "X-Request-ID"
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// Middleware assigns every request an ID, taken from X-Request-ID when the
+// caller sent one, echoes it back, stores it in the request context and logs
+// the completed request.
+func Middleware() echo.MiddlewareFunc {
+	logger := For("http")
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(RequestIDHeader)
+			if id == "" {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(RequestIDHeader, id)
+			ctx := WithRequestID(req.Context(), id)
+			c.SetRequest(req.WithContext(ctx))
+
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			logger.InfoContext(ctx, "request completed",
+				"method", req.Method,
+				"route", c.Path(),
+				"path", req.URL.Path,
+				"status", c.Response().Status,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+			return nil
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// LevelHandler lists component levels on GET and changes one on PUT, e.g.
+// PUT /admin/log-level?component=messaging&level=debug. Without a component
+// the default level changes.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			q := r.URL.Query()
+			if err := SetLevel(q.Get("component"), q.Get("level")); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		json.NewEncoder(w).Encode(Levels())
+	})
+}
+
+// Recover turns handler panics into 500s and logs them with the stack.
+func Recover() echo.MiddlewareFunc {
+	logger := For("http")
+	return middleware.RecoverWithConfig(middleware.RecoverConfig{
+		StackSize: 1 << 10,
+		LogErrorFunc: func(c echo.Context, err error, stack []byte) error {
+			logger.ErrorContext(c.Request().Context(), "panic recovered", "error", err, "stack", string(stack))
+			return err
+		},
+	})
+}
+
+// Register installs request logging and the runtime level endpoint on an
+// Echo server. mw guards the level endpoint, e.g. with an admin check.
+func Register(e *echo.Echo, mw ...echo.MiddlewareFunc) {
+	e.HideBanner = true
+	e.Use(Middleware())
+	e.Any("/admin/log-level", echo.WrapHandler(LevelHandler()), mw...)
+}
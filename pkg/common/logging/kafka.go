@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// KafkaLogger routes franz-go client logs through the "kafka" component.
+type KafkaLogger struct {
+	logger *slog.Logger
+}
+
+func NewKafkaLogger() *KafkaLogger {
+	return &KafkaLogger{logger: For("kafka")}
+}
+
+func (l *KafkaLogger) Level() kgo.LogLevel {
+	ctx := context.Background()
+	switch {
+	case l.logger.Enabled(ctx, slog.LevelDebug):
+		return kgo.LogLevelDebug
+	case l.logger.Enabled(ctx, slog.LevelInfo):
+		return kgo.LogLevelInfo
+	case l.logger.Enabled(ctx, slog.LevelWarn):
+		return kgo.LogLevelWarn
+	default:
+		return kgo.LogLevelError
+	}
+}
+
+func (l *KafkaLogger) Log(level kgo.LogLevel, msg string, keyvals ...any) {
+	var sl slog.Level
+	switch level {
+	case kgo.LogLevelError:
+		sl = slog.LevelError
+	case kgo.LogLevelWarn:
+		sl = slog.LevelWarn
+	case kgo.LogLevelInfo:
+		sl = slog.LevelInfo
+	default:
+		sl = slog.LevelDebug
+	}
+	l.logger.Log(context.Background(), sl, msg, keyvals...)
+}
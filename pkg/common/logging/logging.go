@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"txsystem/pkg/common/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	root atomic.Pointer[slog.Handler]
+
+	mu           sync.Mutex
+	defaultLevel = new(slog.LevelVar)
+	levels       = map[string]*slog.LevelVar{}
+	overrides    = map[string]slog.Level{}
+)
+
+func init() {
+	var h slog.Handler = newHandler(os.Stdout, "json", "")
+	root.Store(&h)
+}
+
+// Setup installs the process-wide JSON logger for service and applies the
+// configured default and per-component levels.
+func Setup(service string, cfg config.Logging) error {
+	level, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	componentLevels := map[string]slog.Level{}
+	for _, pair := range strings.Split(cfg.Levels, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		component, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid LOG_LEVELS entry %q, expected component=level", pair)
+		}
+		l, err := parseLevel(value)
+		if err != nil {
+			return err
+		}
+		componentLevels[strings.TrimSpace(component)] = l
+	}
+
+	h := newHandler(os.Stdout, cfg.Format, service)
+	root.Store(&h)
+
+	mu.Lock()
+	defaultLevel.Set(level)
+	for component, l := range componentLevels {
+		overrides[component] = l
+	}
+	for component, lv := range levels {
+		lv.Set(levelFor(component))
+	}
+	mu.Unlock()
+
+	slog.SetDefault(For("main"))
+	return nil
+}
+
+func newHandler(w io.Writer, format, service string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		// Components filter on their own level, so the base handler lets everything through.
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redactAttr,
+	}
+	var h slog.Handler
+	if format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	if service != "" {
+		h = h.WithAttrs([]slog.Attr{slog.String("service", service)})
+	}
+	return h
+}
+
+// For returns the logger for a component. Its level can be changed at runtime
+// with SetLevel without affecting other components.
+func For(component string) *slog.Logger {
+	mu.Lock()
+	lv, ok := levels[component]
+	if !ok {
+		lv = new(slog.LevelVar)
+		lv.Set(levelFor(component))
+		levels[component] = lv
+	}
+	mu.Unlock()
+
+	return slog.New(&componentHandler{level: lv}).With(slog.String("component", component))
+}
+
+// levelFor must be called with mu held.
+func levelFor(component string) slog.Level {
+	if l, ok := overrides[component]; ok {
+		return l
+	}
+	return defaultLevel.Level()
+}
+
+// SetLevel changes the level of one component, or of every component without
+// an explicit override when component is empty.
+func SetLevel(component, level string) error {
+	l, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if component == "" {
+		defaultLevel.Set(l)
+		for name, lv := range levels {
+			if _, ok := overrides[name]; !ok {
+				lv.Set(l)
+			}
+		}
+		return nil
+	}
+	overrides[component] = l
+	if lv, ok := levels[component]; ok {
+		lv.Set(l)
+	}
+	return nil
+}
+
+// Levels reports the current level of every known component.
+func Levels() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	out := map[string]string{"default": defaultLevel.Level().String()}
+	for name, lv := range levels {
+		out[name] = lv.Level().String()
+	}
+	return out
+}
+
+// Fatal logs at error level and exits, standing in for log.Fatal.
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+func parseLevel(value string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return l, fmt.Errorf("invalid log level %q", value)
+	}
+	return l, nil
+}
+
+// componentHandler filters on a component level and resolves the root
+// handler lazily, so loggers created before Setup still end up as JSON.
+type componentHandler struct {
+	level  *slog.LevelVar
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *componentHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	inner := *root.Load()
+	if len(h.attrs) > 0 {
+		inner = inner.WithAttrs(h.attrs)
+	}
+	for _, g := range h.groups {
+		inner = inner.WithGroup(g)
+	}
+
+	r.Message = redactString(r.Message)
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return inner.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &componentHandler{
+		level:  h.level,
+		attrs:  append(append([]slog.Attr{}, h.attrs...), attrs...),
+		groups: h.groups,
+	}
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return &componentHandler{
+		level:  h.level,
+		attrs:  h.attrs,
+		groups: append(append([]string{}, h.groups...), name),
+	}
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// accountKeys are attribute keys whose values are account numbers.
+var accountKeys = map[string]bool{
+	"account":             true,
+	"account_id":          true,
+	"account_number":      true,
+	"source_account":      true,
+	"destination_account": true,
+	"from_account":        true,
+	"to_account":          true,
+}
+
+// longNumber catches account-like digit runs that end up inside free text.
+var longNumber = regexp.MustCompile(`\b\d{8,}\b`)
+
+// Mask keeps the last four characters of an account number.
+func Mask(value string) string {
+	if len(value) <= 4 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", len(value)-4) + value[len(value)-4:]
+}
+
+func redactString(s string) string {
+	return longNumber.ReplaceAllStringFunc(s, Mask)
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if accountKeys[a.Key] {
+		return slog.String(a.Key, Mask(a.Value.Resolve().String()))
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, redactString(a.Value.String()))
+	}
+	return a
+}
@@ -2,56 +2,101 @@ package messaging
 
 import (
 	"context"
-	"os"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
 
-	"github.com/labstack/gommon/log"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// pollTimeout bounds a single poll so the loop reports progress even when the
+// topic is idle.
+const pollTimeout = 5 * time.Second
+
+// maxAttempts is how many times a record is handed to the processor before it
+// is counted as failed and copied to the dead-letter topic. Its offset is
+// only committed once the copy is stored.
+const maxAttempts = 3
+
+// deadLetterSuffix names the topic failed records are copied to, such as
+// transactions.dlq.
+const deadLetterSuffix = ".dlq"
+
 type kafkaConsumer struct {
 	client    *kgo.Client
 	topic     string
 	connected bool
-}
+	// tail marks a consumer without a group; see NewKafkaTail.
+	tail bool
+	// cursor is the position just after the last record handed out. It is
+	// only touched by the consume loop.
+	cursor Cursor
 
-func NewKafkaConsumer(brokers []string, topic string) types.ConsumerConnection {
-	groupID := "transaction-consumer-group"
+	mu       sync.RWMutex
+	lastPoll time.Time
+	lag      map[int32]int64
+}
 
+// NewKafkaConsumer joins groupID on topic. Every service that needs its own
+// copy of the stream must use a distinct group. Only committed records are
+// read, so events of an aborted batch are never seen. Offsets are committed
+// only by the consume loop, never automatically on a timer, revoke or close,
+// so a record interrupted at shutdown is delivered again.
+func NewKafkaConsumer(brokers []string, topic, groupID string) types.ConsumerConnection {
 	client, err := kgo.NewClient(
 		kgo.SeedBrokers(brokers...),
 		kgo.ConsumerGroup(groupID),
 		kgo.ConsumeTopics(topic),
-		kgo.WithLogger(kgo.BasicLogger(os.Stderr, kgo.LogLevelInfo, nil)),
+		kgo.DisableAutoCommit(),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+		kgo.AllowAutoTopicCreation(),
+		kgo.WithLogger(logging.NewKafkaLogger()),
 	)
 	if err != nil {
-		log.Errorf("Failed to create Kafka consumer client: %v", err)
+		logger.Error("failed to create Kafka consumer client", "error", err)
 		return nil
 	}
 
 	return &kafkaConsumer{
 		client: client,
 		topic:  topic,
+		cursor: Cursor{},
+		lag:    map[int32]int64{},
 	}
 }
 
-func (kc *kafkaConsumer) consume(ctx context.Context, handler func(key, value []byte) error) {
+// recordHandler processes one record; ctx carries the consumer span.
+type recordHandler func(ctx context.Context, r *kgo.Record) error
+
+func (kc *kafkaConsumer) consume(ctx context.Context, handler recordHandler) {
 	go func() {
-		log.Infof("Starting Kafka consumer for topic: %s", kc.topic)
+		logger.Info("starting Kafka consumer", "topic", kc.topic)
 
 		for {
 			select {
 			case <-ctx.Done():
-				log.Info("Kafka consumer shutting down")
+				logger.Info("Kafka consumer shutting down", "topic", kc.topic)
 				return
 			default:
-				fetches := kc.client.PollFetches(ctx)
+				pollCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+				fetches := kc.client.PollFetches(pollCtx)
+				cancel()
 				if errs := fetches.Errors(); len(errs) > 0 {
 					for _, e := range errs {
-						log.Errorf("Kafka fetch error: %v", e.Err)
+						if errors.Is(e.Err, context.DeadlineExceeded) || errors.Is(e.Err, context.Canceled) {
+							continue
+						}
+						logger.Error("Kafka fetch error", "topic", e.Topic, "partition", e.Partition, "error", e.Err)
 					}
 				}
+				kc.recordPoll(fetches)
 
 				records := fetches.Records()
 				if len(records) == 0 {
@@ -59,37 +104,169 @@ func (kc *kafkaConsumer) consume(ctx context.Context, handler func(key, value []
 					continue
 				}
 
-				log.Infof("Received %d messages", len(records))
+				logger.Debug("received records", "count", len(records))
 
 				for _, r := range records {
-					// Add additional logging for debugging
-					log.Debugf("Processing message: key=%s offset=%d partition=%d",
-						string(r.Key), r.Offset, r.Partition)
+					kc.cursor.advance(r.Topic, r.Partition, r.Offset+1)
+					if kc.tail {
+						if err := handler(ctx, r); err != nil {
+							if ctx.Err() == nil {
+								logger.Info("Kafka tail stopped by its processor", "topic", kc.topic, "error", err)
+							}
+							return
+						}
+						kc.recordProcessed(r)
+						continue
+					}
 
 					// Process the message
-					err := handler(r.Key, r.Value)
+					err := kc.process(ctx, r, handler)
+					if err != nil && ctx.Err() == nil {
+						err = kc.deadLetter(ctx, r, err)
+					}
 					if err == nil {
 						if commitErr := kc.client.CommitRecords(ctx, r); commitErr != nil {
-							log.Errorf("Failed to commit offset: %v", commitErr)
+							logger.Error("failed to commit offset", "partition", r.Partition, "offset", r.Offset, "error", commitErr)
 						} else {
-							log.Debugf("Successfully committed offset for key=%s", string(r.Key))
+							logger.Debug("committed offset", "partition", r.Partition, "offset", r.Offset)
 						}
 					}
+					kc.recordProcessed(r)
 				}
 			}
 		}
 	}()
 }
 
+// process runs handler with a linear backoff between attempts and records
+// the outcome.
+func (kc *kafkaConsumer) process(ctx context.Context, r *kgo.Record, handler recordHandler) error {
+	start := time.Now()
+	defer func() {
+		metrics.KafkaProcessDuration.WithLabelValues(r.Topic).Observe(time.Since(start).Seconds())
+	}()
+
+	spanCtx, span := tracing.StartConsume(ctx, r)
+	spanCtx = logging.WithRequestID(spanCtx, tracing.RecordCarrier{Record: r}.Get(logging.RequestIDHeader))
+	defer span.End()
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = handler(spanCtx, r); err == nil {
+			metrics.KafkaConsumed.WithLabelValues(r.Topic).Inc()
+			return nil
+		}
+		logger.ErrorContext(spanCtx, "failed to process record",
+			"partition", r.Partition, "offset", r.Offset, "attempt", attempt, "max_attempts", maxAttempts, "error", err)
+		if attempt == maxAttempts {
+			break
+		}
+		metrics.KafkaConsumeRetried.WithLabelValues(r.Topic).Inc()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+		}
+	}
+	metrics.KafkaConsumeFailed.WithLabelValues(r.Topic).Inc()
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// deadLetter copies a record that failed every attempt to the dead-letter
+// topic, with the error and its origin in headers. It retries until the copy
+// is stored or ctx is done, so no later offset of the partition is committed
+// past a record that was neither processed nor kept.
+func (kc *kafkaConsumer) deadLetter(ctx context.Context, r *kgo.Record, cause error) error {
+	dlq := &kgo.Record{
+		Topic: r.Topic + deadLetterSuffix,
+		Key:   r.Key,
+		Value: r.Value,
+		Headers: append(slices.Clone(r.Headers),
+			kgo.RecordHeader{Key: "dlq-error", Value: []byte(cause.Error())},
+			kgo.RecordHeader{Key: "dlq-topic", Value: []byte(r.Topic)},
+			kgo.RecordHeader{Key: "dlq-partition", Value: []byte(strconv.Itoa(int(r.Partition)))},
+			kgo.RecordHeader{Key: "dlq-offset", Value: []byte(strconv.FormatInt(r.Offset, 10))},
+		),
+	}
+	for attempt := 1; ; attempt++ {
+		err := kc.client.ProduceSync(ctx, dlq).FirstErr()
+		if err == nil {
+			metrics.KafkaDeadLettered.WithLabelValues(r.Topic).Inc()
+			logger.Warn("record dead-lettered", "topic", dlq.Topic, "partition", r.Partition, "offset", r.Offset, "error", cause)
+			return nil
+		}
+		logger.Error("failed to dead-letter record", "topic", dlq.Topic, "partition", r.Partition, "offset", r.Offset, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(time.Duration(attempt), 30) * time.Second):
+		}
+	}
+}
+
 func (kc *kafkaConsumer) StartConsumer(ctx context.Context, ms types.MessageProcessor) {
-	handler := func(key, value []byte) error {
-		log.Infof("Processing message: key=%s", string(key))
-		return ms.ProcessMessage(string(value))
+	handler := func(ctx context.Context, r *kgo.Record) error {
+		logger.DebugContext(ctx, "processing record", "key", string(r.Key), "partition", r.Partition, "offset", r.Offset)
+		return ms.ProcessMessage(ctx, string(r.Value))
+	}
+
+	kc.consume(ctx, handler)
+}
+
+// StartRecordConsumer also passes each record's topic and the cursor just
+// after it, in the form ParseCursor reads.
+func (kc *kafkaConsumer) StartRecordConsumer(ctx context.Context, rp types.RecordProcessor) {
+	handler := func(ctx context.Context, r *kgo.Record) error {
+		return rp.ProcessRecord(ctx, r.Topic, string(r.Value), kc.cursor.String())
 	}
 
 	kc.consume(ctx, handler)
 }
 
+// recordPoll notes the poll time and the high watermark lag of every
+// partition returned by the fetch. Tails keep the lag for Stats but leave
+// the gauge to the group consumers of the topic.
+func (kc *kafkaConsumer) recordPoll(fetches kgo.Fetches) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	kc.lastPoll = time.Now()
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		if p.Err != nil || len(p.Records) == 0 {
+			return
+		}
+		first := p.Records[0].Offset
+		kc.lag[p.Partition] = max(p.HighWatermark-first, 0)
+		if kc.tail {
+			return
+		}
+		metrics.KafkaConsumerLag.WithLabelValues(p.Topic, strconv.Itoa(int(p.Partition))).Set(float64(kc.lag[p.Partition]))
+	})
+}
+
+func (kc *kafkaConsumer) recordProcessed(r *kgo.Record) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if lag, ok := kc.lag[r.Partition]; ok && lag > 0 {
+		kc.lag[r.Partition] = lag - 1
+		if kc.tail {
+			return
+		}
+		metrics.KafkaConsumerLag.WithLabelValues(r.Topic, strconv.Itoa(int(r.Partition))).Set(float64(lag - 1))
+	}
+}
+
+func (kc *kafkaConsumer) Stats() types.ConsumerStats {
+	kc.mu.RLock()
+	defer kc.mu.RUnlock()
+	lag := make(map[int32]int64, len(kc.lag))
+	for p, l := range kc.lag {
+		lag[p] = l
+	}
+	return types.ConsumerStats{LastPoll: kc.lastPoll, Lag: lag}
+}
+
 func (kc *kafkaConsumer) IsConnected() bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
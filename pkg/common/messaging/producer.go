@@ -2,15 +2,23 @@ package messaging
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sync"
 	"time"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/metrics"
+	"txsystem/pkg/common/tracing"
 	"txsystem/pkg/common/types"
 
-	"github.com/labstack/gommon/log"
 	"github.com/twmb/franz-go/pkg/kgo"
 )
 
+var logger = logging.For("messaging")
+
 type kafkaProducer struct {
+	// mu serialises Kafka transactions, the client runs one at a time.
+	mu         sync.Mutex
 	client     *kgo.Client
 	topic      string
 	connected  bool
@@ -18,23 +26,18 @@ type kafkaProducer struct {
 }
 
 func connectProducer(brokers []string, topic string) *kafkaProducer {
-	log.Debug("kafka brokers", brokers)
 	client, err := kgo.NewClient(
 		kgo.SeedBrokers(brokers...),
 		kgo.AllowAutoTopicCreation(),
-		kgo.TransactionalID("transaction-producer-1"),
+		kgo.TransactionalID(transactionalID(topic)),
 		kgo.RetryBackoffFn(func(attempt int) time.Duration {
 			return time.Duration(attempt) * time.Second
 		}),
 		kgo.RetryTimeout(30*time.Second),
-		kgo.WithLogger(kgo.BasicLogger(
-			os.Stderr,        // log to stderr
-			kgo.LogLevelInfo, // set log level (Debug, Info, Warn, Error)
-			nil,              // use default formatter
-		)),
+		kgo.WithLogger(logging.NewKafkaLogger()),
 	)
 	if err != nil {
-		log.Errorf("Failed to create Kafka client: %v", err)
+		logger.Error("failed to create Kafka client", "error", err)
 		return nil
 	}
 
@@ -45,37 +48,77 @@ func connectProducer(brokers []string, topic string) *kafkaProducer {
 	}
 }
 
+// transactionalID must differ between live producers, or the broker fences
+// all but the newest.
+func transactionalID(topic string) string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-producer-%s-%d", topic, host, os.Getpid())
+}
+
 func GetProducerConnection(brokers []string, topic string) types.ProducerConnection {
-	log.Info("Connecting to Kafka brokers:", brokers)
-	log.Info("Using topic:", topic)
+	logger.Info("connecting to Kafka", "brokers", brokers, "topic", topic)
 	var instance *kafkaProducer = connectProducer(brokers, topic)
 	if instance.client == nil {
-		log.Error("Kafka client is nil")
+		logger.Error("Kafka client is nil")
 		return nil
 	}
 	return instance
 }
 
-func (kp *kafkaProducer) Produce(message string) error {
-	log.Info("Producing message to Kafka topic:", kp.topic)
+func (kp *kafkaProducer) Produce(ctx context.Context, message string) error {
+	return kp.ProduceBatch(ctx, []string{message})
+}
+
+func (kp *kafkaProducer) ProduceBatch(ctx context.Context, messages []string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	logger.DebugContext(ctx, "producing records", "topic", kp.topic, "count", len(messages))
+
+	records := make([]*kgo.Record, len(messages))
+	for i, message := range messages {
+		records[i] = &kgo.Record{
+			Topic: kp.topic,
+			Value: []byte(message),
+			Key:   kp.producerID,
+		}
+		if id := logging.RequestID(ctx); id != "" {
+			records[i].Headers = append(records[i].Headers, kgo.RecordHeader{Key: logging.RequestIDHeader, Value: []byte(id)})
+		}
+	}
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	start := time.Now()
 	if err := kp.client.BeginTransaction(); err != nil {
+		metrics.KafkaProduceErrors.WithLabelValues(kp.topic).Inc()
 		return err
 	}
-	record := &kgo.Record{
-		Topic: kp.topic,
-		Value: []byte(message),
-		Key:   kp.producerID,
+	ctx, span := tracing.StartProduce(ctx, records[0])
+	defer span.End()
+	for _, r := range records[1:] {
+		tracing.Inject(ctx, r)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	// Allow more time for large batches, which are flushed together.
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second+time.Duration(len(records))*time.Millisecond)
 	defer cancel()
-	if err := kp.client.ProduceSync(ctx, record); err != nil {
-		log.Errorf("Failed to produce message: %v", err)
-		if abortErr := kp.client.EndTransaction(ctx, kgo.TryCommit); abortErr != nil {
-			log.Errorf("Additionally failed to abort transaction: %v", abortErr)
+	if err := kp.client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		logger.ErrorContext(ctx, "failed to produce record", "topic", kp.topic, "error", err)
+		span.RecordError(err)
+		metrics.KafkaProduceErrors.WithLabelValues(kp.topic).Inc()
+		if abortErr := kp.client.EndTransaction(ctx, kgo.TryAbort); abortErr != nil {
+			logger.ErrorContext(ctx, "failed to abort Kafka transaction", "error", abortErr)
 		}
-		return nil
+		return err
+	}
+	if err := kp.client.EndTransaction(ctx, kgo.TryCommit); err != nil {
+		metrics.KafkaProduceErrors.WithLabelValues(kp.topic).Inc()
+		return err
 	}
 
+	metrics.KafkaProduceDuration.WithLabelValues(kp.topic).Observe(time.Since(start).Seconds())
 	return nil
 }
 
@@ -0,0 +1,195 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"txsystem/pkg/common/logging"
+	"txsystem/pkg/common/types"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// Cursor is a read position across topics: the next offset to read for each
+// topic and partition. Its string form is "topic:partition:offset,...".
+type Cursor map[string]map[int32]int64
+
+func (c Cursor) String() string {
+	var parts []string
+	for topic, partitions := range c {
+		for p, offset := range partitions {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", topic, p, offset))
+		}
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// ParseCursor reads a cursor produced by Cursor.String. The empty string is
+// an empty cursor.
+func ParseCursor(s string) (Cursor, error) {
+	c := Cursor{}
+	if s == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid cursor entry %q", part)
+		}
+		p, err := strconv.ParseInt(fields[1], 10, 32)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid partition in cursor entry %q", part)
+		}
+		offset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid offset in cursor entry %q", part)
+		}
+		c.advance(fields[0], int32(p), offset)
+	}
+	return c, nil
+}
+
+func (c Cursor) advance(topic string, partition int32, next int64) {
+	if c[topic] == nil {
+		c[topic] = map[int32]int64{}
+	}
+	c[topic][partition] = next
+}
+
+// NewKafkaTail reads topics without a consumer group. Partitions listed in
+// from resume there (or at the oldest retained record); the rest start at
+// their current end, so the cursor names every partition from the first
+// record on. Only committed records are read and nothing is committed. Each
+// record is handed to the processor once: an error stops the consumer
+// instead of being retried or dead-lettered.
+func NewKafkaTail(ctx context.Context, brokers, topics []string, from Cursor) (types.ConsumerConnection, error) {
+	meta, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.WithLogger(logging.NewKafkaLogger()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	partitions, err := topicPartitions(ctx, meta, topics)
+	if err != nil {
+		meta.Close()
+		return nil, err
+	}
+	ends, err := endOffsets(ctx, meta, partitions)
+	meta.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := Cursor{}
+	start := map[string]map[int32]kgo.Offset{}
+	for topic, ps := range partitions {
+		start[topic] = map[int32]kgo.Offset{}
+		for _, p := range ps {
+			if next, ok := from[topic][p]; ok {
+				start[topic][p] = kgo.NewOffset().At(next)
+				cursor.advance(topic, p, next)
+			} else {
+				// Start at the resolved end rather than AtEnd so the cursor
+				// names every partition, even ones that stay quiet.
+				start[topic][p] = kgo.NewOffset().At(ends[topic][p])
+				cursor.advance(topic, p, ends[topic][p])
+			}
+		}
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumePartitions(start),
+		kgo.FetchIsolationLevel(kgo.ReadCommitted()),
+		kgo.WithLogger(logging.NewKafkaLogger()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+
+	return &kafkaConsumer{
+		client: client,
+		topic:  strings.Join(topics, ","),
+		tail:   true,
+		cursor: cursor,
+		lag:    map[int32]int64{},
+	}, nil
+}
+
+// topicPartitions looks up the partitions of each topic.
+func topicPartitions(ctx context.Context, client *kgo.Client, topics []string) (map[string][]int32, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	for _, topic := range topics {
+		t := kmsg.NewMetadataRequestTopic()
+		t.Topic = kmsg.StringPtr(topic)
+		req.Topics = append(req.Topics, t)
+	}
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch topic metadata: %w", err)
+	}
+
+	partitions := map[string][]int32{}
+	for _, t := range resp.Topics {
+		if t.Topic == nil {
+			continue
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			return nil, fmt.Errorf("failed to fetch metadata for topic %s: %w", *t.Topic, err)
+		}
+		for _, p := range t.Partitions {
+			partitions[*t.Topic] = append(partitions[*t.Topic], p.Partition)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, errors.New("no partitions found for the requested topics")
+	}
+	return partitions, nil
+}
+
+// endOffsets looks up the last stable offset of each partition, the offset
+// a read-committed consumer starting at the end reads next.
+func endOffsets(ctx context.Context, client *kgo.Client, partitions map[string][]int32) (map[string]map[int32]int64, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	req.ReplicaID = -1
+	req.IsolationLevel = 1
+	for topic, ps := range partitions {
+		t := kmsg.NewListOffsetsRequestTopic()
+		t.Topic = topic
+		for _, p := range ps {
+			rp := kmsg.NewListOffsetsRequestTopicPartition()
+			rp.Partition = p
+			rp.CurrentLeaderEpoch = -1
+			rp.Timestamp = -1
+			t.Partitions = append(t.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, t)
+	}
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list end offsets: %w", err)
+	}
+
+	ends := map[string]map[int32]int64{}
+	for _, t := range resp.Topics {
+		ends[t.Topic] = map[int32]int64{}
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				return nil, fmt.Errorf("failed to list end offset of %s/%d: %w", t.Topic, p.Partition, err)
+			}
+			ends[t.Topic][p.Partition] = p.Offset
+		}
+	}
+	for topic, ps := range partitions {
+		for _, p := range ps {
+			if _, ok := ends[topic][p]; !ok {
+				return nil, fmt.Errorf("no end offset returned for %s/%d", topic, p)
+			}
+		}
+	}
+	return ends, nil
+}
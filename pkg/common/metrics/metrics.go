@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"txsystem/pkg/common/logging"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "txsystem"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	KafkaProduceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_produce_duration_seconds",
+		Help:      "Time to produce and commit a record.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	KafkaProduceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_produce_errors_total",
+		Help:      "Records that could not be produced.",
+	}, []string{"topic"})
+
+	KafkaConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumed_records_total",
+		Help:      "Records processed successfully.",
+	}, []string{"topic"})
+
+	KafkaConsumeFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_consume_failed_total",
+		Help:      "Records whose processing failed after every retry.",
+	}, []string{"topic"})
+
+	KafkaDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_dead_lettered_total",
+		Help:      "Failed records copied to the dead-letter topic.",
+	}, []string{"topic"})
+
+	KafkaConsumeRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_consume_retries_total",
+		Help:      "Processing attempts retried after an error.",
+	}, []string{"topic"})
+
+	KafkaProcessDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_process_duration_seconds",
+		Help:      "Time spent processing a single record, retries included.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Records behind the high watermark per partition.",
+	}, []string{"topic", "partition"})
+
+	TransfersSettled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_settled_total",
+		Help:      "Transfers that moved balances.",
+	}, []string{"currency"})
+
+	TransfersFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_failed_total",
+		Help:      "Transfers rejected or failed during settlement.",
+	}, []string{"currency"})
+
+	TransferVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount_total",
+		Help:      "Sum of settled transfer amounts.",
+	}, []string{"currency"})
+
+	OverdraftDrawn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "overdraft_drawn_total",
+		Help:      "Sum of the overdraft drawn by settled transfers.",
+	}, []string{"currency"})
+
+	TransferRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfer_rejections_total",
+		Help:      "Transfers failed before settlement, by reason code.",
+	}, []string{"reason"})
+
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts, by outcome (succeeded, retrying, failed).",
+	}, []string{"outcome"})
+
+	SagasFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sagas_finished_total",
+		Help:      "Transfer sagas that ended, by final status (completed, failed, compensated).",
+	}, []string{"status"})
+
+	SagaStepTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "saga_step_timeouts_total",
+		Help:      "Saga steps that got no reply in time, by step.",
+	}, []string{"step"})
+
+	AccountChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_checks_total",
+		Help:      "Synchronous account checks on transaction creation, by outcome (passed, rejected, unavailable).",
+	}, []string{"outcome"})
+
+	TransactionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_created_total",
+		Help:      "Transactions accepted by the transaction service.",
+	}, []string{"type"})
+)
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Register mounts /metrics and the request instrumentation on an Echo server.
+func Register(e *echo.Echo) {
+	e.Use(Middleware())
+	e.GET("/metrics", echo.WrapHandler(Handler()))
+}
+
+// Middleware records request counts and latency labelled by the route
+// template rather than the raw path, to keep cardinality bounded.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(c.Response().Status)
+			method := c.Request().Method
+
+			HTTPRequests.WithLabelValues(route, method, status).Inc()
+			HTTPDuration.WithLabelValues(route, method, status).Observe(time.Since(start).Seconds())
+			return nil
+		}
+	}
+}
+
+// RegisterDBStats exports connection pool statistics for db.
+func RegisterDBStats(db *gorm.DB, name string) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		logging.For("metrics").Error("failed to register DB stats collector", "error", err)
+		return
+	}
+	if err := prometheus.Register(collectors.NewDBStatsCollector(sqlDB, name)); err != nil {
+		logging.For("metrics").Error("failed to register DB stats collector", "error", err)
+	}
+}
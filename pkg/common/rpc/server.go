@@ -0,0 +1,162 @@
+// Package rpc holds the gRPC plumbing shared by the services: an
+// authenticated server, its lifecycle, and call credentials for clients.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strings"
+	"txsystem/pkg/common/auth"
+	"txsystem/pkg/common/logging"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+var logger = logging.For("grpc")
+
+// publicPrefixes are callable without a token, like the HTTP probes.
+var publicPrefixes = []string{"/grpc.health.v1.Health/", "/grpc.reflection."}
+
+// NewServer returns a server that authenticates every call with the same
+// bearer tokens as the REST API, with health checking and reflection
+// registered.
+func NewServer(v *auth.Verifier) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, authUnary(v)),
+		grpc.ChainStreamInterceptor(recoverStream, authStream(v)),
+	)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	reflection.Register(s)
+	return s
+}
+
+// Serve listens on addr and serves s until ctx is cancelled, then stops
+// gracefully.
+func Serve(ctx context.Context, s *grpc.Server, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	go func() {
+		logger.Info("starting gRPC server", "addr", addr)
+		if err := s.Serve(lis); err != nil {
+			logger.Error("gRPC server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+func authenticate(ctx context.Context, v *auth.Verifier, method string) (context.Context, error) {
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, _ = strings.CutPrefix(values[0], "Bearer ")
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, auth.ErrMissingToken.Error())
+	}
+	principal, err := v.Verify(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, auth.ErrInvalidToken.Error())
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func authUnary(v *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, v, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(v *auth.Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), v, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func recoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorContext(ctx, "panic in gRPC handler", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.ErrorContext(ss.Context(), "panic in gRPC handler", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// BearerToken returns call credentials sending token the way the server
+// expects. They are allowed over plaintext connections inside the cluster.
+func BearerToken(token string) credentials.PerRPCCredentials {
+	return bearerToken(token)
+}
+
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// Dial connects to another service's gRPC server inside the cluster,
+// authenticating every call with token.
+func Dial(addr, token string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(BearerToken(token)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", addr, err)
+	}
+	return conn, nil
+}
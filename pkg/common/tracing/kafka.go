@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordCarrier adapts Kafka record headers to the OpenTelemetry propagator.
+type RecordCarrier struct {
+	Record *kgo.Record
+}
+
+func (c RecordCarrier) Get(key string) string {
+	for _, h := range c.Record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c RecordCarrier) Set(key, value string) {
+	for i, h := range c.Record.Headers {
+		if h.Key == key {
+			c.Record.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	c.Record.Headers = append(c.Record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+}
+
+func (c RecordCarrier) Keys() []string {
+	keys := make([]string, len(c.Record.Headers))
+	for i, h := range c.Record.Headers {
+		keys[i] = h.Key
+	}
+	return keys
+}
+
+// StartProduce starts a producer span and injects its context into the record headers.
+func StartProduce(ctx context.Context, r *kgo.Record) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, r.Topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(r.Topic),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, RecordCarrier{Record: r})
+	return ctx, span
+}
+
+// Inject copies the span context in ctx into the record headers, for records
+// published under a span started by StartProduce for another record.
+func Inject(ctx context.Context, r *kgo.Record) {
+	otel.GetTextMapPropagator().Inject(ctx, RecordCarrier{Record: r})
+}
+
+// StartConsume extracts the producer's trace context from the record and
+// starts a consumer span as its child.
+func StartConsume(ctx context.Context, r *kgo.Record) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, RecordCarrier{Record: r})
+	return Tracer().Start(ctx, r.Topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(r.Topic),
+			semconv.MessagingDestinationPartitionID(strconv.Itoa(int(r.Partition))),
+			semconv.MessagingKafkaMessageOffset(int(r.Offset)),
+		),
+	)
+}
@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MongoMonitor returns a command monitor that wraps every MongoDB command in
+// a client span. Pass it to options.Client().SetMonitor.
+func MongoMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	finish := func(requestID int64, err string) {
+		value, ok := spans.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if err != "" {
+			span.SetStatus(codes.Error, err)
+		}
+		span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			attrs := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemMongoDB,
+					semconv.DBNamespace(e.DatabaseName),
+					semconv.DBOperationName(e.CommandName),
+				),
+			}
+			if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+				attrs = append(attrs, trace.WithAttributes(semconv.DBCollectionName(collection)))
+			}
+			_, span := Tracer().Start(ctx, "mongodb "+e.CommandName, attrs...)
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.Failure)
+		},
+	}
+}
@@ -1 +1,23 @@
 package types
+
+// BalanceResponse is an account's balance with what it can still spend.
+// AvailableBalance includes the overdraft; AvailableCredit is the part of
+// the overdraft limit not yet used.
+type BalanceResponse struct {
+	AccountID        uint    `json:"account_id"`
+	Currency         string  `json:"currency"`
+	Balance          float64 `json:"balance"`
+	HeldBalance      float64 `json:"held_balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	OverdraftLimit   float64 `json:"overdraft_limit"`
+	OverdraftUsed    float64 `json:"overdraft_used"`
+	AvailableCredit  float64 `json:"available_credit"`
+}
+
+// OverdraftRequest sets how far an account may go below zero and the fee
+// charged on each transfer that draws on it. A zero limit turns the
+// overdraft off.
+type OverdraftRequest struct {
+	Limit float64 `json:"limit"`
+	Fee   float64 `json:"fee"`
+}
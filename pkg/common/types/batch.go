@@ -0,0 +1,39 @@
+package types
+
+// BatchItemError reports why one item of a batch was rejected. Index is the
+// zero-based position of the item in the submission.
+type BatchItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type BatchRejectedResponse struct {
+	Error string           `json:"error"`
+	Items []BatchItemError `json:"items"`
+}
+
+type BatchResponse struct {
+	BatchID   string `json:"batch_id"`
+	ItemCount int    `json:"item_count"`
+}
+
+type BatchItemStatus struct {
+	Index         int    `json:"index"`
+	ID            uint64 `json:"id"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// BatchStatusResponse summarises a batch. Status is pending until every item
+// has settled, then completed, failed or partially_failed.
+type BatchStatusResponse struct {
+	BatchID     string            `json:"batch_id"`
+	SubmittedBy string            `json:"submitted_by"`
+	Status      string            `json:"status"`
+	CreatedAt   string            `json:"created_at"`
+	Total       int               `json:"total"`
+	Pending     int               `json:"pending"`
+	Completed   int               `json:"completed"`
+	Failed      int               `json:"failed"`
+	Items       []BatchItemStatus `json:"items"`
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"context"
+	"time"
 )
 
 type Connection interface {
@@ -10,14 +11,33 @@ type Connection interface {
 }
 type ProducerConnection interface {
 	Connection
-	Produce(message string) error
+	Produce(ctx context.Context, message string) error
+	// ProduceBatch publishes every message in one Kafka transaction, so
+	// either all of them become visible to consumers or none do.
+	ProduceBatch(ctx context.Context, messages []string) error
 }
 
 type ConsumerConnection interface {
 	Connection
 	StartConsumer(ctx context.Context, ms MessageProcessor)
+	// StartRecordConsumer is StartConsumer for processors that need to know
+	// where each message was read.
+	StartRecordConsumer(ctx context.Context, rp RecordProcessor)
+	Stats() ConsumerStats
+}
+
+// ConsumerStats is a snapshot of the consumer poll loop, lag is keyed by partition.
+type ConsumerStats struct {
+	LastPoll time.Time
+	Lag      map[int32]int64
 }
 
 type MessageProcessor interface {
-	ProcessMessage(message string) error
+	ProcessMessage(ctx context.Context, message string) error
+}
+
+// RecordProcessor receives each message with its topic and the consumer
+// position just after it, which a tailing consumer can be restarted from.
+type RecordProcessor interface {
+	ProcessRecord(ctx context.Context, topic, message, position string) error
 }
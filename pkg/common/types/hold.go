@@ -0,0 +1,15 @@
+package types
+
+type HoldRequest struct {
+	AccountID          uint    `json:"account_id"`
+	DestinationAccount uint    `json:"destination_account"`
+	Amount             float64 `json:"amount"`
+	Description        string  `json:"description"`
+	// TTL is a Go duration such as "15m" or "72h". Empty uses the default.
+	TTL string `json:"ttl"`
+}
+
+type CaptureRequest struct {
+	// Amount to capture. Zero or omitted captures the full hold.
+	Amount float64 `json:"amount"`
+}
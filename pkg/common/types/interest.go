@@ -0,0 +1,11 @@
+package types
+
+// InterestRequest sets the interest terms of an account. AnnualRate is a
+// percentage. DayCount is actual/365 (the default), actual/360,
+// actual/actual or 30/360; Compounding is daily, monthly (the default),
+// quarterly or annually.
+type InterestRequest struct {
+	AnnualRate  float64 `json:"annual_rate"`
+	DayCount    string  `json:"day_count"`
+	Compounding string  `json:"compounding"`
+}
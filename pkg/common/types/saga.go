@@ -0,0 +1,36 @@
+package types
+
+// Saga actions. Transfer and reverse are sent to the account participant,
+// record and void to the ledger participant.
+const (
+	SagaTransfer = "transfer"
+	SagaReverse  = "reverse"
+	SagaRecord   = "record"
+	SagaVoid     = "void"
+)
+
+// SagaCommand asks a participant to perform one step of a transfer saga.
+// Participants must handle a command more than once with the same result.
+type SagaCommand struct {
+	SagaID             uint64  `json:"saga_id"`
+	TransactionID      uint64  `json:"transaction_id"`
+	Action             string  `json:"action"`
+	Amount             float64 `json:"amount"`
+	Fee                float64 `json:"fee,omitempty"`
+	SourceAccount      string  `json:"source_account"`
+	DestinationAccount string  `json:"destination_account"`
+	TransactionType    string  `json:"transaction_type"`
+}
+
+// SagaReply reports the outcome of a command. OK is false only for business
+// rejections, with Reason set; failures worth retrying get no reply.
+// OverdraftFee is charged by a transfer that drew on the overdraft, on top
+// of the command's fee.
+type SagaReply struct {
+	SagaID        uint64  `json:"saga_id"`
+	TransactionID uint64  `json:"transaction_id"`
+	Action        string  `json:"action"`
+	OK            bool    `json:"ok"`
+	Reason        string  `json:"reason,omitempty"`
+	OverdraftFee  float64 `json:"overdraft_fee,omitempty"`
+}
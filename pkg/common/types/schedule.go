@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// Recurrence repeats a scheduled transfer until Until or for Count
+// occurrences, whichever comes first. Neither set repeats indefinitely.
+type Recurrence struct {
+	Frequency Frequency  `json:"frequency"`
+	Until     *time.Time `json:"until,omitempty"`
+	Count     int        `json:"count,omitempty"`
+}
+
+type ScheduleResponse struct {
+	ID                 uint64      `json:"id"`
+	Amount             float64     `json:"amount"`
+	Description        string      `json:"description"`
+	SourceAccount      string      `json:"source_account"`
+	DestinationAccount string      `json:"destination_account"`
+	TransactionType    string      `json:"transaction_type"`
+	ExecuteAt          string      `json:"execute_at"`
+	Recurrence         *Recurrence `json:"recurrence,omitempty"`
+	Status             string      `json:"status"`
+	Occurrences        int         `json:"occurrences"`
+	NextRunAt          string      `json:"next_run_at,omitempty"`
+	CreatedAt          string      `json:"created_at"`
+	UpdatedAt          string      `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+const (
+	EventTransactionCompleted = "transaction.completed"
+	EventTransactionFailed    = "transaction.failed"
+)
+
+// TransactionStatusEvent is published on the status topic when settlement
+// finishes a transaction. EventID is stable for a given outcome, so
+// republished events can be deduplicated.
+type TransactionStatusEvent struct {
+	EventID            string    `json:"event_id"`
+	Type               string    `json:"type"`
+	TransactionID      uint64    `json:"transaction_id"`
+	Reference          string    `json:"reference,omitempty"`
+	Status             string    `json:"status"`
+	FailureReason      string    `json:"failure_reason,omitempty"`
+	Amount             float64   `json:"amount"`
+	SourceAccount      string    `json:"source_account"`
+	DestinationAccount string    `json:"destination_account"`
+	TransactionType    string    `json:"transaction_type"`
+	OccurredAt         time.Time `json:"occurred_at"`
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type TransactionStatus string
 
 const (
@@ -15,16 +17,30 @@ type TransactionRequest struct {
 	SourceAccount      string  `json:"source_account"`
 	DestinationAccount string  `json:"destination_account"`
 	TransactionType    string  `json:"transaction_type"`
+	// ExecuteAt defers the transfer to a future time. With Recurrence it is
+	// the first occurrence and defaults to now.
+	ExecuteAt  *time.Time  `json:"execute_at,omitempty"`
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+}
+
+// CreateTransactionResponse answers a created transfer with its fee.
+type CreateTransactionResponse struct {
+	Message     string               `json:"message"`
+	Transaction *TransactionResponse `json:"transaction"`
 }
 
 type TransactionResponse struct {
 	ID                 uint64  `json:"id"`
 	Amount             float64 `json:"amount"`
+	Currency           string  `json:"currency,omitempty"`
+	Fee                float64 `json:"fee"`
+	FeeScheduleVersion int     `json:"fee_schedule_version,omitempty"`
 	Description        string  `json:"description"`
 	SourceAccount      string  `json:"source_account"`
 	DestinationAccount string  `json:"destination_account"`
 	TransactionType    string  `json:"transaction_type"`
 	Status             string  `json:"status"`
+	FailureReason      string  `json:"failure_reason,omitempty"`
 	CreatedAt          string  `json:"created_at"`
 	UpdatedAt          string  `json:"updated_at"`
 	TransactionID      string  `json:"transaction_id"`
@@ -0,0 +1,35 @@
+package types
+
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Events filters by event type, e.g. "transaction.failed". Empty means all.
+	Events []string `json:"events,omitempty"`
+	// Accounts filters by source or destination account. Empty means all of
+	// the caller's accounts.
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+type WebhookResponse struct {
+	ID       uint64   `json:"id"`
+	URL      string   `json:"url"`
+	Events   []string `json:"events"`
+	Accounts []string `json:"accounts"`
+	Active   bool     `json:"active"`
+	// Secret signs deliveries. It is only returned when the webhook is created.
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             uint64 `json:"id"`
+	WebhookID      uint64 `json:"webhook_id"`
+	EventID        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+	DeliveredAt    string `json:"delivered_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
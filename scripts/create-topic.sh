@@ -7,7 +7,7 @@ DEFAULT_PARTITIONS=${DEFAULT_PARTITIONS:-1}
 DEFAULT_REPLICATION=${DEFAULT_REPLICATION:-1}
 
 # Use env vars if set, else use defaults
-TOPICS=${KAFKA_TOPIC_TRANSCATIONSS:-$DEFAULT_TOPICS}
+TOPICS=${KAFKA_TOPIC_TRANSACTIONS:-$DEFAULT_TOPICS}
 PARTITIONS=${KAFKA_PARTITIONS:-$DEFAULT_PARTITIONS}
 REPLICATION=${KAFKA_REPLICATION:-$DEFAULT_REPLICATION}
 BOOTSTRAP_SERVER=${BOOTSTRAP_SERVER:-kafka:9092}